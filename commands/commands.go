@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+)
+
+// command is a CLI entry point that receives the arguments after its name
+type command func(args []string) error
+
+// registry maps command names to their entry points
+var registry = map[string]command{
+	"seed":            Seed,
+	"rates":           Rates,
+	"vapid-keys":      VAPIDKeys,
+	"storage-migrate": StorageMigrate,
+	"images-gc":       ImagesGC,
+}
+
+// Run dispatches args[0] to the matching command
+func Run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no command given, available commands: %v", names())
+	}
+
+	cmd, exists := registry[args[0]]
+	if !exists {
+		return fmt.Errorf("unknown command %q, available commands: %v", args[0], names())
+	}
+
+	return cmd(args[1:])
+}
+
+func names() []string {
+	var list []string
+	for name := range registry {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"backend-go/config"
+	"backend-go/reconcile"
+	"backend-go/storage"
+	"context"
+	"flag"
+	"log"
+)
+
+// ImagesGC reports stored files and image rows that no longer belong together, and cleans them
+// up when asked to.
+//
+//	go run main.go images-gc            # report only
+//	go run main.go images-gc -delete    # report, then clean up
+//
+// It finds files no image, variant or resumable upload owns, image rows whose file is gone and
+// images that have been outside a live trip for longer than -ttl. Anything younger than -grace
+// is left alone as it may belong to an upload in progress.
+func ImagesGC(args []string) error {
+	defaults := reconcile.OptionsFromEnv()
+	flags := flag.NewFlagSet("images-gc", flag.ContinueOnError)
+	ttl := flags.Duration("ttl", defaults.UnattachedTTL, "How long an image may stay without a live trip")
+	grace := flags.Duration("grace", defaults.Grace, "Skip files and rows younger than this")
+	remove := flags.Bool("delete", false, "Delete what the report lists")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	store := storage.Default()
+	report, err := reconcile.Scan(ctx, config.DB, store, reconcile.Options{UnattachedTTL: *ttl, Grace: *grace})
+	if err != nil {
+		return err
+	}
+	report.Log()
+
+	if report.Empty() || !*remove {
+		if !report.Empty() {
+			log.Println("Nothing deleted, run again with -delete to clean up")
+		}
+		return nil
+	}
+	if err := reconcile.Clean(ctx, config.DB, store, report); err != nil {
+		return err
+	}
+	log.Println("Image GC cleanup finished")
+	return nil
+}
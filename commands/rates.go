@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"backend-go/config"
+	"backend-go/currency"
+	"backend-go/models"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// Rates loads exchange rates from a JSON rate table.
+//
+//	go run main.go rates -file rates.json
+//
+// The file looks like {"base": "USD", "rates": {"IDR": 16250.5}}, tables quoted against
+// another base are rebased onto USD. Existing rates are overwritten, others are kept.
+func Rates(args []string) error {
+	flags := flag.NewFlagSet("rates", flag.ContinueOnError)
+	file := flags.String("file", "", "JSON rate table to load (required)")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		flags.Usage()
+		return errors.New("-file is required")
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+
+	var table currency.RateTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return fmt.Errorf("parse %s: %w", *file, err)
+	}
+
+	rates, err := table.Normalize()
+	if err != nil {
+		return err
+	}
+
+	if err := models.SaveRates(config.DB, rates); err != nil {
+		return err
+	}
+
+	log.Printf("Loaded %d exchange rates against %s", len(rates), currency.Base)
+	return nil
+}
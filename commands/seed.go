@@ -0,0 +1,336 @@
+package commands
+
+import (
+	"backend-go/config"
+	"backend-go/currency"
+	"backend-go/models"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"math/rand/v2"
+	"os"
+
+	"gorm.io/gorm"
+)
+
+// defaultBoundingBox covers Jakarta
+const defaultBoundingBox = "-6.3713,106.6486,-6.0835,106.9758"
+
+// seedCategory describes how trips are generated for one kind of OSM feature
+type seedCategory struct {
+	Preferences []string   `json:"preferences"`
+	Price       [2]float64 `json:"price"` // Range in whole IDR
+	Duration    [2]int     `json:"duration"`
+	CoverImage  string     `json:"cover_image"`
+}
+
+// defaultSeedMapping is keyed by "key=value" tag pairs, "default" is used when nothing matches
+var defaultSeedMapping = map[string]seedCategory{
+	"tourism=attraction": {
+		Preferences: []string{"Sightseeing", "Photography", "Culture", "History"},
+		Price:       [2]float64{0, 25000},
+		Duration:    [2]int{30, 120},
+		CoverImage:  "https://images.unsplash.com/photo-1539650116574-75c0c6d73f6e?w=800",
+	},
+	"tourism=museum": {
+		Preferences: []string{"Education", "Culture", "History", "Art"},
+		Price:       [2]float64{15000, 50000},
+		Duration:    [2]int{60, 180},
+		CoverImage:  "https://images.unsplash.com/photo-1566127992631-137a642a90f4?w=800",
+	},
+	"tourism=viewpoint": {
+		Preferences: []string{"Photography", "Sightseeing", "Nature"},
+		Price:       [2]float64{0, 15000},
+		Duration:    [2]int{20, 60},
+		CoverImage:  "https://images.unsplash.com/photo-1477959858617-67f85cf4f1df?w=800",
+	},
+	"tourism=gallery": {
+		Preferences: []string{"Art", "Culture", "Photography"},
+		Price:       [2]float64{10000, 40000},
+		Duration:    [2]int{45, 120},
+		CoverImage:  "https://images.unsplash.com/photo-1578662996442-48f60103fc96?w=800",
+	},
+	"tourism=theme_park": {
+		Preferences: []string{"Family", "Entertainment", "Adventure"},
+		Price:       [2]float64{100000, 300000},
+		Duration:    [2]int{240, 480},
+		CoverImage:  "https://images.unsplash.com/photo-1544552866-d3ed42536cfd?w=800",
+	},
+	"tourism=zoo": {
+		Preferences: []string{"Family", "Education", "Nature"},
+		Price:       [2]float64{30000, 80000},
+		Duration:    [2]int{120, 300},
+		CoverImage:  "https://images.unsplash.com/photo-1564760055775-d63b17a55c44?w=800",
+	},
+	"tourism=aquarium": {
+		Preferences: []string{"Family", "Education", "Marine Life"},
+		Price:       [2]float64{50000, 150000},
+		Duration:    [2]int{90, 240},
+		CoverImage:  "https://images.unsplash.com/photo-1544551763-46a013bb70d5?w=800",
+	},
+	"tourism=artwork": {
+		Preferences: []string{"Art", "Culture", "Photography"},
+		Price:       [2]float64{0, 10000},
+		Duration:    [2]int{10, 30},
+		CoverImage:  "https://images.unsplash.com/photo-1578662996442-48f60103fc96?w=800",
+	},
+	"default": {
+		Preferences: []string{"Sightseeing"},
+		Price:       [2]float64{0, 25000},
+		Duration:    [2]int{30, 120},
+		CoverImage:  "https://images.unsplash.com/photo-1539650116574-75c0c6d73f6e?w=800",
+	},
+}
+
+// Seed imports trips from an OSM extract on disk.
+//
+//	go run main.go seed -file jakarta.osm.pbf -owner 1 -seed 42
+//
+// Trips are keyed by their OSM reference (e.g. "way/123"), so running the command
+// again updates the existing trips instead of creating duplicates.
+func Seed(args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	file := flags.String("file", "", "Overpass JSON export or .osm.pbf extract to import (required)")
+	owner := flags.Uint("owner", 0, "ID of the trip_owner user the trips belong to (required)")
+	bbox := flags.String("bbox", defaultBoundingBox, "bounding box as south,west,north,east")
+	mappingFile := flags.String("mapping", "", "JSON file mapping key=value tags to preferences, price, duration and cover image")
+	seed := flags.Uint64("seed", 1, "random seed used for generated prices, durations and trip points")
+	limit := flags.Int("limit", 0, "maximum number of elements to import, 0 means no limit")
+	var filters tagFilters
+	flags.Var(&filters, "tag", "tag filter as key=value1|value2, repeatable, \"*\" matches any value (default tourism attractions)")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" || *owner == 0 {
+		flags.Usage()
+		return errors.New("-file and -owner are required")
+	}
+	if len(filters) == 0 {
+		filters = tagFilters{{
+			Key:    "tourism",
+			Values: []string{"attraction", "museum", "viewpoint", "gallery", "theme_park", "zoo", "aquarium", "artwork"},
+		}}
+	}
+
+	box, err := parseBoundingBox(*bbox)
+	if err != nil {
+		return err
+	}
+
+	mapping := defaultSeedMapping
+	if *mappingFile != "" {
+		if mapping, err = loadSeedMapping(*mappingFile); err != nil {
+			return err
+		}
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, *owner).Error; err != nil {
+		return fmt.Errorf("owner %d not found", *owner)
+	}
+	if user.Role != "trip_owner" {
+		return fmt.Errorf("user %d is not a trip_owner", *owner)
+	}
+
+	elements, err := loadOSMElements(*file, box, filters)
+	if err != nil {
+		return err
+	}
+	if *limit > 0 && len(elements) > *limit {
+		elements = elements[:*limit]
+	}
+
+	seeder := tripSeeder{
+		ownerID:     user.ID,
+		seed:        *seed,
+		filters:     filters,
+		mapping:     mapping,
+		preferences: make(map[string]models.Preference),
+	}
+
+	var created, updated, skipped int
+	for _, element := range elements {
+		result, err := seeder.upsert(element)
+		if err != nil {
+			return fmt.Errorf("failed to seed %s: %w", element.Ref(), err)
+		}
+		switch result {
+		case seedCreated:
+			created++
+		case seedUpdated:
+			updated++
+		default:
+			skipped++
+		}
+	}
+
+	log.Printf("Seeded %d elements: %d created, %d updated, %d skipped", len(elements), created, updated, skipped)
+	return nil
+}
+
+func loadSeedMapping(path string) (map[string]seedCategory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var mapping map[string]seedCategory
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse mapping file: %w", err)
+	}
+	if _, exists := mapping["default"]; !exists {
+		mapping["default"] = defaultSeedMapping["default"]
+	}
+	return mapping, nil
+}
+
+type seedResult int
+
+const (
+	seedSkipped seedResult = iota
+	seedCreated
+	seedUpdated
+)
+
+type tripSeeder struct {
+	ownerID     uint
+	seed        uint64
+	filters     tagFilters
+	mapping     map[string]seedCategory
+	preferences map[string]models.Preference // cache by name
+}
+
+// category picks the mapping entry for the first filter key the element carries
+func (s *tripSeeder) category(tags map[string]string) seedCategory {
+	for _, f := range s.filters {
+		if category, exists := s.mapping[f.Key+"="+tags[f.Key]]; exists {
+			return category
+		}
+	}
+	return s.mapping["default"]
+}
+
+// random returns a generator that only depends on the seed and the element,
+// so filtering or reordering the input does not change the generated values
+func (s *tripSeeder) random(element osmElement) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(element.Ref()))
+	return rand.New(rand.NewPCG(s.seed, h.Sum64()))
+}
+
+// upsert creates the trip for element, or refreshes the OSM-derived fields of a previous import.
+// Trips the owner deleted are left deleted.
+func (s *tripSeeder) upsert(element osmElement) (seedResult, error) {
+	category := s.category(element.Tags)
+	ref := element.Ref()
+
+	preferences, err := s.resolvePreferences(category.Preferences)
+	if err != nil {
+		return seedSkipped, err
+	}
+
+	var existing models.Trip
+	err = config.DB.Unscoped().Where("osm_id = ?", ref).First(&existing).Error
+	if err == nil {
+		if existing.DeletedAt.Valid {
+			return seedSkipped, nil
+		}
+
+		err = config.DB.Transaction(func(tx *gorm.DB) error {
+			existing.Name = element.Tags["name"]
+			existing.Description = generateDescription(element.Tags)
+			existing.StartLatitude, existing.StartLongitude = element.Lat, element.Lon
+			existing.EndLatitude, existing.EndLongitude = element.Lat, element.Lon
+			if err := tx.Save(&existing).Error; err != nil {
+				return err
+			}
+			return tx.Model(&existing).Association("Preferences").Replace(preferences)
+		})
+		if err != nil {
+			return seedSkipped, err
+		}
+		return seedUpdated, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return seedSkipped, err
+	}
+
+	rng := s.random(element)
+
+	// Create trip points (simulated popular spots)
+	var tripPoints []models.TripPoint
+	for j := 0; j < 3; j++ {
+		tripPoints = append(tripPoints, models.TripPoint{
+			Latitude:  element.Lat + (rng.Float64()-0.5)*0.001,
+			Longitude: element.Lon + (rng.Float64()-0.5)*0.001,
+		})
+	}
+
+	price := category.Price[0] + rng.Float64()*(category.Price[1]-category.Price[0])
+	duration := category.Duration[0] + int(rng.Float64()*float64(category.Duration[1]-category.Duration[0]))
+
+	trip := models.Trip{
+		Name:           element.Tags["name"],
+		Description:    generateDescription(element.Tags),
+		CoverImage:     category.CoverImage,
+		PriceAmount:    currency.ToMinor(math.Round(price), currency.Default),
+		Currency:       currency.Default,
+		Duration:       duration,
+		StartLatitude:  element.Lat,
+		StartLongitude: element.Lon,
+		EndLatitude:    element.Lat,
+		EndLongitude:   element.Lon,
+		OSMID:          &ref,
+		UserID:         s.ownerID,
+		Points:         tripPoints,
+		Preferences:    preferences,
+	}
+
+	if err := config.DB.Create(&trip).Error; err != nil {
+		return seedSkipped, err
+	}
+	return seedCreated, nil
+}
+
+func (s *tripSeeder) resolvePreferences(names []string) ([]models.Preference, error) {
+	var preferences []models.Preference
+	for _, name := range names {
+		pref, cached := s.preferences[name]
+		if !cached {
+			if err := config.DB.FirstOrCreate(&pref, models.Preference{Name: name}).Error; err != nil {
+				return nil, err
+			}
+			s.preferences[name] = pref
+		}
+		preferences = append(preferences, pref)
+	}
+	return preferences, nil
+}
+
+func generateDescription(tags map[string]string) string {
+	desc := "Explore this amazing attraction"
+	if city := tags["addr:city"]; city != "" {
+		desc += " in " + city
+	}
+	desc += ". "
+
+	if addr := tags["addr:full"]; addr != "" {
+		desc += "Located at " + addr + ". "
+	}
+
+	if website := tags["website"]; website != "" {
+		desc += "Visit their website for more information. "
+	}
+
+	if phone := tags["phone"]; phone != "" {
+		desc += "Contact: " + phone + ". "
+	}
+
+	desc += "Perfect for photography and sightseeing!"
+	return desc
+}
@@ -0,0 +1,294 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+)
+
+// osmElement is a named OSM feature reduced to a single coordinate
+type osmElement struct {
+	Type string
+	ID   int64
+	Lat  float64
+	Lon  float64
+	Tags map[string]string
+}
+
+// Ref returns the globally unique "type/id" reference of the element
+func (e osmElement) Ref() string {
+	return fmt.Sprintf("%s/%d", e.Type, e.ID)
+}
+
+// boundingBox uses the Overpass order: south, west, north, east
+type boundingBox struct {
+	South, West, North, East float64
+}
+
+func parseBoundingBox(value string) (boundingBox, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return boundingBox{}, fmt.Errorf("bbox must be south,west,north,east")
+	}
+
+	var coords [4]float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return boundingBox{}, fmt.Errorf("invalid bbox coordinate %q", part)
+		}
+		coords[i] = v
+	}
+
+	box := boundingBox{South: coords[0], West: coords[1], North: coords[2], East: coords[3]}
+	if box.South > box.North || box.West > box.East {
+		return boundingBox{}, fmt.Errorf("bbox south/west must be smaller than north/east")
+	}
+	return box, nil
+}
+
+func (b boundingBox) Contains(lat, lon float64) bool {
+	return lat >= b.South && lat <= b.North && lon >= b.West && lon <= b.East
+}
+
+// tagFilter matches elements whose Key tag has one of Values ("*" matches any value)
+type tagFilter struct {
+	Key    string
+	Values []string
+}
+
+func parseTagFilter(value string) (tagFilter, error) {
+	key, values, found := strings.Cut(value, "=")
+	if !found || key == "" || values == "" {
+		return tagFilter{}, fmt.Errorf("tag filter must look like key=value1|value2, got %q", value)
+	}
+	return tagFilter{Key: key, Values: strings.Split(values, "|")}, nil
+}
+
+func (f tagFilter) Match(tags map[string]string) bool {
+	value, exists := tags[f.Key]
+	if !exists {
+		return false
+	}
+	for _, v := range f.Values {
+		if v == "*" || v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// tagFilters is a repeatable flag.Value, an element matches if any filter matches
+type tagFilters []tagFilter
+
+func (fs *tagFilters) String() string {
+	var parts []string
+	for _, f := range *fs {
+		parts = append(parts, f.Key+"="+strings.Join(f.Values, "|"))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (fs *tagFilters) Set(value string) error {
+	f, err := parseTagFilter(value)
+	if err != nil {
+		return err
+	}
+	*fs = append(*fs, f)
+	return nil
+}
+
+func (fs tagFilters) Match(tags map[string]string) bool {
+	if tags["name"] == "" {
+		return false
+	}
+	for _, f := range fs {
+		if f.Match(tags) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadOSMElements reads matching elements from an Overpass JSON export or an .osm.pbf extract,
+// sorted by reference so that the result does not depend on the file order
+func loadOSMElements(path string, box boundingBox, filters tagFilters) ([]osmElement, error) {
+	var elements []osmElement
+	var err error
+
+	if strings.HasSuffix(path, ".pbf") {
+		elements, err = loadPBF(path, filters)
+	} else {
+		elements, err = loadOverpassJSON(path, filters)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var inside []osmElement
+	for _, element := range elements {
+		if box.Contains(element.Lat, element.Lon) {
+			inside = append(inside, element)
+		}
+	}
+
+	sort.Slice(inside, func(i, j int) bool {
+		if inside[i].Type != inside[j].Type {
+			return inside[i].Type < inside[j].Type
+		}
+		return inside[i].ID < inside[j].ID
+	})
+	return inside, nil
+}
+
+// loadOverpassJSON reads the output of an Overpass query run with "out center"
+func loadOverpassJSON(path string, filters tagFilters) ([]osmElement, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var osmData struct {
+		Elements []struct {
+			Type   string  `json:"type"`
+			ID     int64   `json:"id"`
+			Lat    float64 `json:"lat"`
+			Lon    float64 `json:"lon"`
+			Center *struct {
+				Lat float64 `json:"lat"`
+				Lon float64 `json:"lon"`
+			} `json:"center,omitempty"`
+			Tags map[string]string `json:"tags"`
+		} `json:"elements"`
+	}
+
+	if err := json.NewDecoder(file).Decode(&osmData); err != nil {
+		return nil, fmt.Errorf("failed to parse Overpass JSON: %w", err)
+	}
+
+	var elements []osmElement
+	for _, element := range osmData.Elements {
+		if !filters.Match(element.Tags) {
+			continue
+		}
+
+		lat, lon := element.Lat, element.Lon
+		if element.Center != nil {
+			lat, lon = element.Center.Lat, element.Center.Lon
+		} else if element.Type != "node" {
+			// Ways and relations only carry coordinates when exported with "out center"
+			continue
+		}
+
+		elements = append(elements, osmElement{
+			Type: element.Type,
+			ID:   element.ID,
+			Lat:  lat,
+			Lon:  lon,
+			Tags: element.Tags,
+		})
+	}
+	return elements, nil
+}
+
+// loadPBF reads nodes and ways from an .osm.pbf extract. Ways are placed at the
+// average of their nodes, which needs a second pass over the file. Relations are
+// skipped because resolving their geometry needs the full member tree, use an
+// Overpass export with "out center" for those.
+func loadPBF(path string, filters tagFilters) ([]osmElement, error) {
+	var elements []osmElement
+	wayNodes := make(map[int64][]osm.NodeID)
+	neededNodes := make(map[osm.NodeID]bool)
+
+	err := scanPBF(path, func(scanner *osmpbf.Scanner) {
+		scanner.SkipRelations = true
+	}, func(object osm.Object) {
+		switch o := object.(type) {
+		case *osm.Node:
+			tags := o.Tags.Map()
+			if filters.Match(tags) {
+				elements = append(elements, osmElement{Type: "node", ID: int64(o.ID), Lat: o.Lat, Lon: o.Lon, Tags: tags})
+			}
+		case *osm.Way:
+			tags := o.Tags.Map()
+			if filters.Match(tags) && len(o.Nodes) > 0 {
+				elements = append(elements, osmElement{Type: "way", ID: int64(o.ID), Tags: tags})
+				for _, node := range o.Nodes {
+					wayNodes[int64(o.ID)] = append(wayNodes[int64(o.ID)], node.ID)
+					neededNodes[node.ID] = true
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(wayNodes) == 0 {
+		return elements, nil
+	}
+
+	coords := make(map[osm.NodeID][2]float64, len(neededNodes))
+	err = scanPBF(path, func(scanner *osmpbf.Scanner) {
+		scanner.SkipWays = true
+		scanner.SkipRelations = true
+		scanner.FilterNode = func(n *osm.Node) bool { return neededNodes[n.ID] }
+	}, func(object osm.Object) {
+		if node, ok := object.(*osm.Node); ok {
+			coords[node.ID] = [2]float64{node.Lat, node.Lon}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var located []osmElement
+	for _, element := range elements {
+		if element.Type == "way" {
+			var latSum, lonSum float64
+			var count int
+			for _, id := range wayNodes[element.ID] {
+				if c, exists := coords[id]; exists {
+					latSum += c[0]
+					lonSum += c[1]
+					count++
+				}
+			}
+			if count == 0 {
+				continue
+			}
+			element.Lat, element.Lon = latSum/float64(count), lonSum/float64(count)
+		}
+		located = append(located, element)
+	}
+	return located, nil
+}
+
+func scanPBF(path string, configure func(*osmpbf.Scanner), visit func(osm.Object)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := osmpbf.New(context.Background(), file, runtime.GOMAXPROCS(0))
+	defer scanner.Close()
+	configure(scanner)
+
+	for scanner.Scan() {
+		visit(scanner.Object())
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read PBF extract: %w", err)
+	}
+	return nil
+}
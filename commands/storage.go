@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"backend-go/storage"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+)
+
+// StorageMigrate copies every stored upload from one storage backend to another.
+//
+//	go run main.go storage-migrate -from local -to s3
+//
+// Both backends are configured from the environment as for STORAGE_BACKEND. Objects that
+// already exist at the destination with the same size are skipped, so an interrupted run
+// can simply be started again. With -delete each object is removed from the source once
+// it is copied; switch STORAGE_BACKEND to the destination before serving traffic again.
+func StorageMigrate(args []string) error {
+	flags := flag.NewFlagSet("storage-migrate", flag.ContinueOnError)
+	from := flags.String("from", "local", "Backend to copy from: local or s3")
+	to := flags.String("to", "", "Backend to copy to: local or s3 (required)")
+	prefix := flags.String("prefix", "", "Only copy keys starting with this prefix, e.g. covers/")
+	remove := flags.Bool("delete", false, "Delete each object from the source after copying it")
+	dryRun := flags.Bool("dry-run", false, "List what would be copied without copying")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *to == "" {
+		flags.Usage()
+		return errors.New("-to is required")
+	}
+	if *from == *to {
+		return errors.New("-from and -to must name different backends")
+	}
+	if *from == "memory" || *to == "memory" {
+		return errors.New("the memory backend does not outlive this command, migrate between local and s3")
+	}
+
+	source, err := storage.New(*from)
+	if err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	destination, err := storage.New(*to)
+	if err != nil {
+		return fmt.Errorf("destination: %w", err)
+	}
+
+	ctx := context.Background()
+	var copied, skipped int
+	var bytes int64
+	err = source.List(ctx, *prefix, func(object storage.Object) error {
+		if existing, err := destination.Stat(ctx, object.Key); err == nil && existing.Size == object.Size {
+			skipped++
+		} else if *dryRun {
+			log.Printf("Would copy %s (%d bytes)", object.Key, object.Size)
+			copied++
+			bytes += object.Size
+			return nil
+		} else {
+			if err := copyObject(ctx, source, destination, object); err != nil {
+				return fmt.Errorf("copy %s: %w", object.Key, err)
+			}
+			copied++
+			bytes += object.Size
+		}
+
+		if *remove && !*dryRun {
+			if err := source.Delete(ctx, object.Key); err != nil {
+				return fmt.Errorf("delete %s from %s: %w", object.Key, source.Name(), err)
+			}
+		}
+		return nil
+	})
+
+	log.Printf("Copied %d objects (%d bytes) from %s to %s, %d already present", copied, bytes, source.Name(), destination.Name(), skipped)
+	return err
+}
+
+// copyObject streams one object between backends
+func copyObject(ctx context.Context, source, destination storage.Storage, object storage.Object) error {
+	reader, info, err := source.Get(ctx, object.Key)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	return destination.Put(ctx, object.Key, reader, info.Size, info.ContentType)
+}
@@ -0,0 +1,21 @@
+package commands
+
+import (
+	"backend-go/notify"
+	"fmt"
+)
+
+// VAPIDKeys prints a new key pair for Web Push notifications to put in .env.
+//
+//	go run main.go vapid-keys
+//
+// Rotating the keys invalidates every browser subscription made with the old ones.
+func VAPIDKeys(args []string) error {
+	publicKey, privateKey, err := notify.GenerateVAPIDKeys()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("VAPID_PUBLIC_KEY=%s\nVAPID_PRIVATE_KEY=%s\n", publicKey, privateKey)
+	return nil
+}
@@ -3,6 +3,7 @@ package auth
 import (
 	"backend-go/config"
 	"backend-go/models"
+	"backend-go/notify"
 	"backend-go/utils"
 	"net/http"
 
@@ -21,6 +22,11 @@ type RegisterRequest struct {
 	Role     string `json:"role" binding:"required,oneof=visitor trip_owner"`
 }
 
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
 type AuthResponse struct {
 	Token string      `json:"token"`
 	User  models.User `json:"user"`
@@ -235,6 +241,68 @@ func UpdateProfile(c *gin.Context) {
 	})
 }
 
+// ChangePassword replaces the current user's password after checking the current one
+func ChangePassword(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "User not found",
+			"message": "User profile could not be found",
+		})
+		return
+	}
+
+	if !utils.CheckPasswordHash(req.CurrentPassword, user.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid credentials",
+			"message": "Current password is incorrect",
+		})
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Password processing failed",
+			"message": "Failed to process password",
+		})
+		return
+	}
+
+	if err := config.DB.Model(&user).Update("password", hashedPassword).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Update failed",
+			"message": "Failed to change password",
+		})
+		return
+	}
+
+	notify.Send(user.ID, notify.EventPasswordChanged, "Your password was changed",
+		"The password of your account was just changed. If this was not you, contact support right away.", "")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password changed successfully",
+	})
+}
+
 // GetRoles returns available user roles
 func GetRoles(c *gin.Context) {
 	roles := []map[string]string{
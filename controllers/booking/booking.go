@@ -0,0 +1,464 @@
+package booking
+
+import (
+	"backend-go/config"
+	"backend-go/models"
+	"backend-go/notify"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errDepartureNotFound = errors.New("departure not found")
+	errNoOccurrence      = errors.New("the departure does not run at the requested time")
+	errBookingClosed     = errors.New("booking for this departure has closed")
+	errNotEnoughSeats    = errors.New("not enough seats left on this departure")
+	errBookingNotFound   = errors.New("booking not found")
+	errAccessDenied      = errors.New("access denied")
+	errInvalidTransition = errors.New("invalid status transition")
+	errQuoteNotFound     = errors.New("quote not found")
+	errQuoteUnavailable  = errors.New("quote has expired or was already booked")
+	errInvalidQuote      = errors.New("invalid participants")
+	errVoucherNotFound   = errors.New("voucher not found")
+)
+
+// dateFormat formats departure times in notifications
+const dateFormat = "2 Jan 2006 15:04 MST"
+
+// CreateBookingRequest represents the request structure for booking a departure,
+// either from a quote or from a departure occurrence and participant breakdown
+type CreateBookingRequest struct {
+	QuoteID      uint                        `json:"quote_id"`
+	DepartureID  uint                        `json:"departure_id" binding:"required_without=QuoteID"`
+	StartsAt     time.Time                   `json:"starts_at" binding:"required_without=QuoteID"`
+	Participants models.ParticipantBreakdown `json:"participants" binding:"required_without=QuoteID"`
+	VoucherCode  string                      `json:"voucher_code"`
+}
+
+// UpdateStatusRequest represents the request structure for changing a booking's status,
+// cancellations go through Cancel so that refunds are applied
+type UpdateStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=confirmed completed"`
+}
+
+// Create books seats on a departure occurrence (visitors only).
+// The departure row is locked for the duration of the transaction so that
+// concurrent bookings cannot oversell the same slot. Bookings made from a
+// quote pay its total, other bookings are quoted on the spot.
+func Create(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to book a trip",
+		})
+		return
+	}
+
+	var req CreateBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var booking models.Booking
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		var quote models.Quote
+		if req.QuoteID != 0 {
+			// Quotes of other visitors look like they do not exist
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("user_id = ?", userID).First(&quote, req.QuoteID).Error; err != nil {
+				return errQuoteNotFound
+			}
+			var used int64
+			if err := tx.Model(&models.Booking{}).Where("quote_id = ?", quote.ID).Count(&used).Error; err != nil {
+				return err
+			}
+			if used > 0 || quote.Expired(now) {
+				return errQuoteUnavailable
+			}
+			req.DepartureID, req.StartsAt, req.Participants = quote.DepartureID, quote.StartsAt, quote.Participants
+		}
+
+		var departure models.Departure
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&departure, req.DepartureID).Error; err != nil {
+			return errDepartureNotFound
+		}
+		if err := tx.Where("departure_id = ?", departure.ID).Find(&departure.Blackouts).Error; err != nil {
+			return err
+		}
+
+		var trip models.Trip
+		if err := tx.Preload("CancellationPolicy").First(&trip, departure.TripID).Error; err != nil {
+			return errDepartureNotFound
+		}
+
+		startsAt := req.StartsAt.UTC()
+		runs, err := departure.HasOccurrence(startsAt)
+		if err != nil || !runs {
+			return errNoOccurrence
+		}
+		if !now.Before(departure.BookingClosesAt(startsAt)) {
+			return errBookingClosed
+		}
+
+		if req.QuoteID == 0 {
+			var rules []models.PricingRule
+			if err := tx.Where("trip_id = ?", trip.ID).Order("id").Find(&rules).Error; err != nil {
+				return err
+			}
+			if quote, err = models.NewQuote(trip, rules, departure, startsAt, req.Participants, now); err != nil {
+				return fmt.Errorf("%w: %v", errInvalidQuote, err)
+			}
+			quote.UserID = userID.(uint)
+			if err := tx.Create(&quote).Error; err != nil {
+				return err
+			}
+		}
+
+		participants := quote.Participants.Total()
+		booked, err := models.BookedSeats(tx, departure.ID, startsAt)
+		if err != nil {
+			return err
+		}
+		if booked+participants > departure.Capacity {
+			return errNotEnoughSeats
+		}
+
+		// The voucher row stays locked until commit so limited codes cannot be redeemed twice
+		var voucher models.Voucher
+		var discount int64
+		if req.VoucherCode != "" {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("code = ?", models.NormalizeVoucherCode(req.VoucherCode)).First(&voucher).Error; err != nil {
+				return errVoucherNotFound
+			}
+			if discount, err = voucher.Discount(trip, quote.Total, quote.Currency, now); err != nil {
+				return err
+			}
+			if err := models.CheckVoucherUsage(tx, voucher, userID.(uint)); err != nil {
+				return err
+			}
+		}
+
+		booking = models.Booking{
+			TripID:       trip.ID,
+			DepartureID:  departure.ID,
+			StartsAt:     startsAt,
+			UserID:       userID.(uint),
+			Participants: participants,
+			Amount:       quote.Total - discount,
+			Discount:     discount,
+			Currency:     quote.Currency,
+			QuoteID:      &quote.ID,
+			Status:       models.BookingPending,
+		}
+		if voucher.ID != 0 {
+			booking.VoucherID = &voucher.ID
+		}
+		if trip.CancellationPolicy != nil {
+			booking.CancellationRules = append(models.CancellationRules{}, trip.CancellationPolicy.Rules...)
+		}
+		// Free trips have nothing to pay for and are confirmed right away, others hold their seats
+		// until they are paid or the hold runs out
+		if booking.Amount == 0 {
+			booking.Status = models.BookingConfirmed
+		} else {
+			holdExpiresAt := now.Add(models.BookingHold)
+			booking.HoldExpiresAt = &holdExpiresAt
+		}
+		if err := tx.Create(&booking).Error; err != nil {
+			return err
+		}
+
+		if voucher.ID == 0 {
+			return nil
+		}
+		if err := tx.Model(&voucher).UpdateColumn("redemptions", gorm.Expr("redemptions + 1")).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.VoucherRedemption{
+			VoucherID: voucher.ID,
+			BookingID: booking.ID,
+			UserID:    booking.UserID,
+			Amount:    discount,
+			Currency:  booking.Currency,
+		}).Error
+	})
+
+	switch {
+	case errors.Is(err, errDepartureNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Departure not found",
+			"message": "The requested departure does not exist",
+		})
+		return
+	case errors.Is(err, errQuoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Quote not found",
+			"message": "The requested quote does not exist",
+		})
+		return
+	case errors.Is(err, errQuoteUnavailable):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Quote unavailable",
+			"message": err.Error(),
+		})
+		return
+	case errors.Is(err, errInvalidQuote):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid participants",
+			"message": err.Error(),
+		})
+		return
+	case errors.Is(err, errVoucherNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Voucher not found",
+			"message": "The voucher code does not exist",
+		})
+		return
+	case errors.Is(err, models.ErrVoucherNotApplicable):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Voucher not applicable",
+			"message": err.Error(),
+		})
+		return
+	case errors.Is(err, errNoOccurrence), errors.Is(err, errBookingClosed):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Departure not bookable",
+			"message": err.Error(),
+		})
+		return
+	case errors.Is(err, errNotEnoughSeats):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Departure full",
+			"message": err.Error(),
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create booking",
+			"message": "Could not save booking to database",
+		})
+		return
+	}
+
+	config.DB.Preload("Trip").Preload("User").Preload("Quote").First(&booking, booking.ID)
+
+	notify.Send(booking.Trip.UserID, notify.EventBookingCreated, "New booking for "+booking.Trip.Name,
+		fmt.Sprintf("%s booked %d seat(s) on %s.", booking.User.Name, booking.Participants, booking.StartsAt.UTC().Format(dateFormat)),
+		fmt.Sprintf("/api/v1/bookings/%d", booking.ID))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Booking created successfully",
+		"data":    booking,
+	})
+}
+
+// GetMyBookings retrieves the bookings made by the current visitor
+func GetMyBookings(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to view your bookings",
+		})
+		return
+	}
+
+	query := config.DB.Preload("Trip").Where("user_id = ?", userID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var bookings []models.Booking
+	if err := query.Order("starts_at").Find(&bookings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve bookings",
+			"message": "Could not fetch your bookings from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Your bookings retrieved successfully",
+		"data":    bookings,
+		"count":   len(bookings),
+	})
+}
+
+// GetTripBookings retrieves the bookings made on the current owner's trips
+func GetTripBookings(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to view bookings on your trips",
+		})
+		return
+	}
+
+	query := config.DB.Preload("Trip").Preload("User").
+		Joins("JOIN trips ON trips.id = bookings.trip_id").
+		Where("trips.user_id = ?", userID)
+
+	// Optional filtering by trip, departure and status
+	if tripID := c.Query("trip_id"); tripID != "" {
+		query = query.Where("bookings.trip_id = ?", tripID)
+	}
+	if departureID := c.Query("departure_id"); departureID != "" {
+		query = query.Where("bookings.departure_id = ?", departureID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("bookings.status = ?", status)
+	}
+
+	var bookings []models.Booking
+	if err := query.Order("bookings.starts_at").Find(&bookings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve bookings",
+			"message": "Could not fetch bookings on your trips from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bookings on your trips retrieved successfully",
+		"data":    bookings,
+		"count":   len(bookings),
+	})
+}
+
+// GetByID retrieves a booking, visible to the visitor who made it and the trip owner
+func GetByID(c *gin.Context) {
+	var booking models.Booking
+	if err := config.DB.Preload("Trip").Preload("User").Preload("Quote").First(&booking, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Booking not found",
+			"message": "The requested booking does not exist",
+		})
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists || (booking.UserID != userID.(uint) && booking.Trip.UserID != userID.(uint)) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"message": "You can only view your own bookings or bookings on your trips",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Booking retrieved successfully",
+		"data":    booking,
+	})
+}
+
+// UpdateStatus lets the trip owner confirm or complete a booking. Only free bookings and bookings
+// with a captured payment can be confirmed, paid bookings are otherwise confirmed by their payment.
+func UpdateStatus(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to update a booking",
+		})
+		return
+	}
+
+	var req UpdateStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var booking models.Booking
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, c.Param("id")).Error; err != nil {
+			return errBookingNotFound
+		}
+
+		var trip models.Trip
+		if err := tx.Unscoped().First(&trip, booking.TripID).Error; err != nil {
+			return err
+		}
+
+		if trip.UserID != userID.(uint) {
+			return errAccessDenied
+		}
+
+		if !models.CanTransition(booking.Status, req.Status) {
+			return fmt.Errorf("%w: a %s booking cannot become %s", errInvalidTransition, booking.Status, req.Status)
+		}
+		if req.Status == models.BookingCompleted && time.Now().Before(booking.StartsAt) {
+			return fmt.Errorf("%w: a booking can only be completed after the departure has started", errInvalidTransition)
+		}
+		if req.Status == models.BookingConfirmed && booking.Amount > 0 {
+			var captured int64
+			if err := tx.Model(&models.Payment{}).
+				Where("booking_id = ? AND status IN ?", booking.ID, refundablePaymentStatuses).
+				Count(&captured).Error; err != nil {
+				return err
+			}
+			if captured == 0 {
+				return fmt.Errorf("%w: a booking with an amount due can only be confirmed once it is paid", errInvalidTransition)
+			}
+		}
+
+		booking.Status = req.Status
+		return tx.Save(&booking).Error
+	})
+
+	switch {
+	case errors.Is(err, errBookingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Booking not found",
+			"message": "The requested booking does not exist",
+		})
+		return
+	case errors.Is(err, errAccessDenied):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"message": "Only the trip owner can confirm or complete bookings",
+		})
+		return
+	case errors.Is(err, errInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Invalid status transition",
+			"message": err.Error(),
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update booking",
+			"message": "Could not save changes to database",
+		})
+		return
+	}
+
+	config.DB.Preload("Trip").Preload("User").First(&booking, booking.ID)
+
+	notify.Send(booking.UserID, notify.EventBookingUpdated, "Booking "+booking.Status,
+		fmt.Sprintf("Your booking #%d for %s on %s is now %s.", booking.ID, booking.Trip.Name, booking.StartsAt.UTC().Format(dateFormat), booking.Status),
+		fmt.Sprintf("/api/v1/bookings/%d", booking.ID))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Booking updated successfully",
+		"data":    booking,
+	})
+}
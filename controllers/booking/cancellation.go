@@ -0,0 +1,288 @@
+package booking
+
+import (
+	"backend-go/config"
+	"backend-go/models"
+	"backend-go/notify"
+	"backend-go/payments"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CancelRequest represents the request structure for cancelling a booking
+type CancelRequest struct {
+	Reason string `json:"reason"`
+}
+
+// refundablePaymentStatuses are the statuses of payments that still hold captured money
+var refundablePaymentStatuses = []string{models.PaymentCaptured, models.PaymentPartiallyRefunded}
+
+// CancellationQuote previews the refund the current user would get by cancelling now
+func CancellationQuote(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to cancel a booking",
+		})
+		return
+	}
+
+	var booking models.Booking
+	if err := config.DB.Preload("Trip", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).First(&booking, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Booking not found",
+			"message": "The requested booking does not exist",
+		})
+		return
+	}
+
+	initiator, ok := cancellationInitiator(booking, booking.Trip, userID.(uint))
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"message": "You can only cancel your own bookings or bookings on your trips",
+		})
+		return
+	}
+
+	var paid []models.Payment
+	if err := config.DB.Where("booking_id = ? AND status IN ?", booking.ID, refundablePaymentStatuses).Find(&paid).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to compute refund",
+			"message": "Could not fetch payments from database",
+		})
+		return
+	}
+
+	decision := decideRefund(booking, initiator, paid, time.Now())
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cancellation quote computed successfully",
+		"data":    decision,
+	})
+}
+
+// Cancel cancels a booking and refunds according to the cancellation policy captured
+// when it was made. Owner-initiated cancellations are always refunded in full. Refunds are
+// recorded as pending with the cancellation and only sent to the gateway once it is committed.
+func Cancel(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to cancel a booking",
+		})
+		return
+	}
+
+	var req CancelRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var decision models.RefundDecision
+	var booking models.Booking
+	var trip models.Trip
+	var refunds []models.PaymentRefund
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// The booking is locked before its payments, like payment webhooks do
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, c.Param("id")).Error; err != nil {
+			return errBookingNotFound
+		}
+
+		if err := tx.Unscoped().First(&trip, booking.TripID).Error; err != nil {
+			return err
+		}
+
+		initiator, ok := cancellationInitiator(booking, trip, userID.(uint))
+		if !ok {
+			return errAccessDenied
+		}
+		if !models.CanTransition(booking.Status, models.BookingCancelled) {
+			return fmt.Errorf("%w: a %s booking cannot be cancelled", errInvalidTransition, booking.Status)
+		}
+
+		var paid []models.Payment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("booking_id = ? AND status IN ?", booking.ID, refundablePaymentStatuses).
+			Order("id").Find(&paid).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		decision = decideRefund(booking, initiator, paid, now)
+		decision.CancelledBy = userID.(uint)
+		decision.Reason = req.Reason
+
+		// Spread the refund over the captured payments, oldest first
+		remaining := decision.RefundAmount
+		for i := range paid {
+			if remaining == 0 {
+				break
+			}
+			amount := min(remaining, paid[i].Refundable())
+			if amount == 0 {
+				continue
+			}
+			refund, err := payments.ReserveRefund(tx, &paid[i], amount, "booking cancelled")
+			if err != nil {
+				return err
+			}
+			refunds = append(refunds, refund)
+			remaining -= amount
+		}
+
+		// The voucher is locked last, after the booking and its payments
+		if err := models.ReleaseVoucher(tx, booking); err != nil {
+			return err
+		}
+
+		booking.Status = models.BookingCancelled
+		booking.CancelledAt = &now
+		if err := tx.Save(&booking).Error; err != nil {
+			return err
+		}
+		return tx.Create(&decision).Error
+	})
+
+	switch {
+	case errors.Is(err, errBookingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Booking not found",
+			"message": "The requested booking does not exist",
+		})
+		return
+	case errors.Is(err, errAccessDenied):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"message": "You can only cancel your own bookings or bookings on your trips",
+		})
+		return
+	case errors.Is(err, errInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Invalid status transition",
+			"message": err.Error(),
+		})
+		return
+	case err != nil:
+		log.Printf("Failed to cancel booking %s: %v", c.Param("id"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to cancel booking",
+			"message": "The booking could not be cancelled and no refund was issued",
+		})
+		return
+	}
+
+	for i := range refunds {
+		if err := payments.IssueRefund(c.Request.Context(), &refunds[i]); err != nil {
+			log.Printf("Failed to refund payment %d for cancelled booking %d: %v", refunds[i].PaymentID, booking.ID, err)
+		}
+	}
+
+	// Tell the other side, the owner when the visitor cancels and the visitor otherwise
+	recipient := booking.UserID
+	if userID.(uint) == booking.UserID {
+		recipient = trip.UserID
+	}
+	notify.Send(recipient, notify.EventBookingCancelled, "Booking cancelled",
+		fmt.Sprintf("Booking #%d for %s on %s was cancelled.", booking.ID, trip.Name, booking.StartsAt.UTC().Format(dateFormat)),
+		fmt.Sprintf("/api/v1/bookings/%d", booking.ID))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Booking cancelled successfully",
+		"data":    decision,
+		"refunds": refunds,
+	})
+}
+
+// GetRefundDecisions lists the refund decisions recorded for a booking
+func GetRefundDecisions(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to view refund decisions",
+		})
+		return
+	}
+
+	var booking models.Booking
+	if err := config.DB.Preload("Trip", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).First(&booking, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Booking not found",
+			"message": "The requested booking does not exist",
+		})
+		return
+	}
+
+	if _, ok := cancellationInitiator(booking, booking.Trip, userID.(uint)); !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"message": "You can only view your own bookings or bookings on your trips",
+		})
+		return
+	}
+
+	var decisions []models.RefundDecision
+	if err := config.DB.Where("booking_id = ?", booking.ID).Order("created_at").Find(&decisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve refund decisions",
+			"message": "Could not fetch refund decisions from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Refund decisions retrieved successfully",
+		"data":    decisions,
+		"count":   len(decisions),
+	})
+}
+
+// cancellationInitiator tells whether userID may cancel the booking and in which role
+func cancellationInitiator(booking models.Booking, trip models.Trip, userID uint) (string, bool) {
+	switch userID {
+	case trip.UserID:
+		return models.CancelledByOwner, true
+	case booking.UserID:
+		return models.CancelledByVisitor, true
+	}
+	return "", false
+}
+
+// decideRefund computes how much of the captured payments is refunded when the booking is cancelled at now
+func decideRefund(booking models.Booking, initiator string, paid []models.Payment, now time.Time) models.RefundDecision {
+	var paidAmount int64
+	for _, payment := range paid {
+		paidAmount += payment.Refundable()
+	}
+
+	hoursBefore := booking.StartsAt.Sub(now).Hours()
+	percent := 100
+	if initiator == models.CancelledByVisitor {
+		percent = booking.CancellationRules.RefundPercent(hoursBefore)
+	}
+
+	return models.RefundDecision{
+		BookingID:            booking.ID,
+		Initiator:            initiator,
+		Rules:                booking.CancellationRules,
+		HoursBeforeDeparture: hoursBefore,
+		RefundPercent:        percent,
+		PaidAmount:           paidAmount,
+		RefundAmount:         paidAmount * int64(percent) / 100,
+		Currency:             booking.Currency,
+	}
+}
@@ -0,0 +1,104 @@
+package booking
+
+import (
+	"backend-go/config"
+	"backend-go/models"
+	"backend-go/notify"
+	"backend-go/payments"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// holdCheckInterval is how often ExpireHolds looks for bookings whose hold ran out
+const holdCheckInterval = time.Minute
+
+// ExpireHolds cancels pending bookings whose hold ran out before a payment went through, giving
+// back their seats and voucher redemptions and cancelling their payment intents. It runs every
+// minute in the background.
+func ExpireHolds() {
+	ticker := time.NewTicker(holdCheckInterval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		now := time.Now()
+		var ids []uint
+		if err := config.DB.Model(&models.Booking{}).
+			Where("status = ? AND hold_expires_at <= ?", models.BookingPending, now).
+			Order("hold_expires_at").Pluck("id", &ids).Error; err != nil {
+			log.Printf("Failed to find expired booking holds: %v", err)
+			continue
+		}
+		for _, id := range ids {
+			if err := expireHold(context.Background(), id, now); err != nil {
+				log.Printf("Failed to expire the hold of booking %d: %v", id, err)
+			}
+		}
+	}
+}
+
+// expireHold cancels a booking whose hold ran out at now, unless a payment was authorized or captured
+// meanwhile and is about to confirm it. Intents nobody paid are cancelled with the gateway once the
+// booking is cancelled, a payment authorized later is cancelled by its webhook.
+func expireHold(ctx context.Context, id uint, now time.Time) error {
+	var booking models.Booking
+	var unpaid []models.Payment
+	expired := false
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// The booking is locked before its payments, like payment webhooks do
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
+			return err
+		}
+		if !booking.HoldExpired(now) {
+			return nil
+		}
+
+		var list []models.Payment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("booking_id = ?", booking.ID).Order("id").Find(&list).Error; err != nil {
+			return err
+		}
+		for _, payment := range list {
+			if payment.Status == models.PaymentAuthorized || payment.CapturedAmount > 0 {
+				return nil
+			}
+		}
+		for _, payment := range list {
+			if payment.Status != models.PaymentRequiresPayment && payment.Status != models.PaymentFailed {
+				continue
+			}
+			payment.Status = models.PaymentCancelled
+			payment.PendingAction = models.PaymentActionCancel
+			if err := tx.Save(&payment).Error; err != nil {
+				return err
+			}
+			unpaid = append(unpaid, payment)
+		}
+
+		booking.Status = models.BookingCancelled
+		booking.CancelledAt = &now
+		if err := tx.Save(&booking).Error; err != nil {
+			return err
+		}
+		expired = true
+		return models.ReleaseVoucher(tx, booking)
+	})
+	if err != nil || !expired {
+		return err
+	}
+
+	for _, payment := range unpaid {
+		if err := payments.CompleteAction(ctx, payment); err != nil {
+			log.Printf("Failed to cancel payment %d of expired booking %d: %v", payment.ID, booking.ID, err)
+		}
+	}
+
+	notify.Send(booking.UserID, notify.EventBookingCancelled, "Booking expired",
+		fmt.Sprintf("Booking #%d on %s was not paid in time and has been cancelled.", booking.ID, booking.StartsAt.UTC().Format(dateFormat)),
+		fmt.Sprintf("/api/v1/bookings/%d", booking.ID))
+	return nil
+}
@@ -0,0 +1,259 @@
+package calendar
+
+import (
+	"backend-go/config"
+	"backend-go/controllers/shared"
+	"backend-go/ical"
+	"backend-go/models"
+	"backend-go/utils"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Feed windows: calendar apps keep past events, so recent history is included
+const (
+	feedHistory = 30 * 24 * time.Hour
+	feedHorizon = 180 * 24 * time.Hour
+)
+
+// GetFeedURL returns the current user's secret feed URL, creating it on first use
+func GetFeedURL(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to get your calendar feed",
+		})
+		return
+	}
+
+	feed, err := models.EnsureCalendarFeed(config.DB, userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create calendar feed",
+			"message": "Could not save calendar feed to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Calendar feed retrieved successfully",
+		"data":    gin.H{"url": feedURL(c, feed)},
+	})
+}
+
+// RotateFeedURL replaces the feed token, the previous URL stops working
+func RotateFeedURL(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to manage your calendar feed",
+		})
+		return
+	}
+
+	feed, err := models.EnsureCalendarFeed(config.DB, userID.(uint))
+	if err == nil {
+		feed.Token = utils.RandomToken()
+		err = config.DB.Model(&feed).Update("token", feed.Token).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to rotate calendar feed",
+			"message": "Could not save calendar feed to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Calendar feed rotated successfully",
+		"data":    gin.H{"url": feedURL(c, feed)},
+	})
+}
+
+// Feed serves the iCalendar feed behind a secret token, no login required so calendar apps
+// can subscribe. Trip owners get their departures, visitors their confirmed bookings.
+func Feed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	var feed models.CalendarFeed
+	if token == "" || config.DB.Where("token = ?", token).First(&feed).Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Calendar feed not found",
+			"message": "The feed URL is invalid or has been rotated",
+		})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, feed.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Calendar feed not found",
+			"message": "The feed URL is invalid or has been rotated",
+		})
+		return
+	}
+
+	now := time.Now()
+	var calendar ical.Calendar
+	var err error
+	if user.Role == "trip_owner" {
+		calendar, err = ownerCalendar(c, user, now)
+	} else {
+		calendar, err = visitorCalendar(c, user, now)
+	}
+	if err != nil {
+		log.Printf("Failed to build calendar feed of user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to build calendar feed",
+			"message": "Could not fetch events from database",
+		})
+		return
+	}
+
+	shared.WriteCalendar(c, calendar, "")
+}
+
+// BookingICS downloads a single booking as an iCalendar file (the visitor who booked or the trip owner)
+func BookingICS(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to download a booking",
+		})
+		return
+	}
+
+	var booking models.Booking
+	if err := config.DB.Preload("Trip", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).First(&booking, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Booking not found",
+			"message": "The requested booking does not exist",
+		})
+		return
+	}
+
+	if booking.UserID != userID.(uint) && booking.Trip.UserID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"message": "You can only download your own bookings or bookings on your trips",
+		})
+		return
+	}
+
+	calendar := ical.Calendar{Name: booking.Trip.Name, Events: []ical.Event{bookingEvent(c, booking)}}
+	shared.WriteCalendar(c, calendar, fmt.Sprintf("booking-%d.ics", booking.ID))
+}
+
+// ownerCalendar lists the occurrences of the owner's departures with their booking counts
+func ownerCalendar(c *gin.Context, owner models.User, now time.Time) (ical.Calendar, error) {
+	calendar := ical.Calendar{Name: "My departures"}
+	from, to := now.Add(-feedHistory), now.Add(feedHorizon)
+
+	var trips []models.Trip
+	if err := config.DB.Preload("Departures").Preload("Departures.Blackouts").
+		Where("user_id = ?", owner.ID).Find(&trips).Error; err != nil {
+		return calendar, err
+	}
+
+	var rows []struct {
+		DepartureID  uint
+		StartsAt     time.Time
+		Bookings     int
+		Participants int
+	}
+	err := config.DB.Model(&models.Booking{}).
+		Select("bookings.departure_id, bookings.starts_at, COUNT(*) AS bookings, SUM(bookings.participants) AS participants").
+		Joins("JOIN trips ON trips.id = bookings.trip_id").
+		Where("trips.user_id = ? AND bookings.starts_at >= ? AND bookings.starts_at < ? AND bookings.status IN ?",
+			owner.ID, from, to, models.ActiveBookingStatuses).
+		Group("bookings.departure_id, bookings.starts_at").
+		Scan(&rows).Error
+	if err != nil {
+		return calendar, err
+	}
+
+	type slot struct {
+		departureID uint
+		start       int64
+	}
+	booked := make(map[slot][2]int, len(rows))
+	for _, row := range rows {
+		booked[slot{row.DepartureID, row.StartsAt.Unix()}] = [2]int{row.Bookings, row.Participants}
+	}
+
+	baseURL := utils.GetBaseURL(c)
+	for _, trip := range trips {
+		for _, departure := range trip.Departures {
+			starts, err := departure.Occurrences(from, to)
+			if err != nil {
+				continue // Rules are validated on save, skip anything that no longer parses
+			}
+			for _, start := range starts {
+				counts := booked[slot{departure.ID, start.Unix()}]
+				uid := fmt.Sprintf("departure-%d-%d@%s", departure.ID, start.Unix(), c.Request.Host)
+				event := ical.TripEvent(uid, trip, start, fmt.Sprintf("%s/api/v1/trips/%d", baseURL, trip.ID))
+				event.Summary = fmt.Sprintf("%s (%d/%d booked)", trip.Name, counts[1], departure.Capacity)
+				event.Description = fmt.Sprintf("%d bookings, %d of %d seats taken.\n\n%s", counts[0], counts[1], departure.Capacity, trip.Description)
+				event.Status = "CONFIRMED"
+				if departure.UpdatedAt.After(event.Updated) {
+					event.Updated = departure.UpdatedAt
+				}
+				calendar.Events = append(calendar.Events, event)
+			}
+		}
+	}
+	return calendar, nil
+}
+
+// visitorCalendar lists the visitor's confirmed and completed bookings
+func visitorCalendar(c *gin.Context, visitor models.User, now time.Time) (ical.Calendar, error) {
+	calendar := ical.Calendar{Name: "My trips"}
+
+	var bookings []models.Booking
+	err := config.DB.Preload("Trip", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("user_id = ? AND status IN ? AND starts_at >= ?", visitor.ID,
+			[]string{models.BookingConfirmed, models.BookingCompleted}, now.Add(-feedHistory)).
+		Order("starts_at").Find(&bookings).Error
+	if err != nil {
+		return calendar, err
+	}
+
+	for _, booking := range bookings {
+		calendar.Events = append(calendar.Events, bookingEvent(c, booking))
+	}
+	return calendar, nil
+}
+
+// bookingEvent builds the event of a booking, its Trip must be loaded
+func bookingEvent(c *gin.Context, booking models.Booking) ical.Event {
+	uid := fmt.Sprintf("booking-%d@%s", booking.ID, c.Request.Host)
+	event := ical.TripEvent(uid, booking.Trip, booking.StartsAt, fmt.Sprintf("%s/api/v1/bookings/%d", utils.GetBaseURL(c), booking.ID))
+	event.Description = fmt.Sprintf("Booking #%d for %d participant(s).\n\n%s", booking.ID, booking.Participants, booking.Trip.Description)
+	if booking.UpdatedAt.After(event.Updated) {
+		event.Updated = booking.UpdatedAt
+	}
+
+	switch booking.Status {
+	case models.BookingPending:
+		event.Status = "TENTATIVE"
+	case models.BookingCancelled:
+		event.Status = "CANCELLED"
+	default:
+		event.Status = "CONFIRMED"
+	}
+	return event
+}
+
+// feedURL is the subscribe URL of a feed
+func feedURL(c *gin.Context, feed models.CalendarFeed) string {
+	return fmt.Sprintf("%s/api/v1/calendar/feeds/%s.ics", utils.GetBaseURL(c), feed.Token)
+}
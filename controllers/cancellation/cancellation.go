@@ -0,0 +1,184 @@
+package cancellation
+
+import (
+	"backend-go/config"
+	"backend-go/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PolicyRequest represents the request structure for creating or replacing a cancellation policy
+type PolicyRequest struct {
+	Name  string                    `json:"name" binding:"required"`
+	Rules []models.CancellationRule `json:"rules" binding:"dive"`
+}
+
+// GetMine lists the cancellation policies of the current trip owner
+func GetMine(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to view your cancellation policies",
+		})
+		return
+	}
+
+	var policies []models.CancellationPolicy
+	if err := config.DB.Where("user_id = ?", userID).Find(&policies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve cancellation policies",
+			"message": "Could not fetch cancellation policies from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cancellation policies retrieved successfully",
+		"data":    policies,
+		"count":   len(policies),
+	})
+}
+
+// GetByID retrieves a cancellation policy, policies are public so visitors can read them before booking
+func GetByID(c *gin.Context) {
+	var policy models.CancellationPolicy
+	if err := config.DB.First(&policy, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Cancellation policy not found",
+			"message": "The requested cancellation policy does not exist",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cancellation policy retrieved successfully",
+		"data":    policy,
+	})
+}
+
+// Create creates a cancellation policy template for the current trip owner
+func Create(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to create a cancellation policy",
+		})
+		return
+	}
+
+	var req PolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	policy := models.CancellationPolicy{
+		UserID: userID.(uint),
+		Name:   req.Name,
+		Rules:  append(models.CancellationRules{}, req.Rules...),
+	}
+
+	if err := config.DB.Create(&policy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create cancellation policy",
+			"message": "Could not save cancellation policy to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Cancellation policy created successfully",
+		"data":    policy,
+	})
+}
+
+// Update replaces the name and rules of a policy (only its owner).
+// Existing bookings keep the rules they were made under.
+func Update(c *gin.Context) {
+	policy, ok := findOwnedPolicy(c)
+	if !ok {
+		return
+	}
+
+	var req PolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	policy.Name = req.Name
+	policy.Rules = append(models.CancellationRules{}, req.Rules...)
+
+	if err := config.DB.Save(&policy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update cancellation policy",
+			"message": "Could not save changes to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cancellation policy updated successfully",
+		"data":    policy,
+	})
+}
+
+// Delete removes a policy (only its owner). Trips using it fall back to full refunds.
+func Delete(c *gin.Context) {
+	policy, ok := findOwnedPolicy(c)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Model(&models.Trip{}).Where("cancellation_policy_id = ?", policy.ID).Update("cancellation_policy_id", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete cancellation policy",
+			"message": "Could not detach the policy from your trips",
+		})
+		return
+	}
+
+	if err := config.DB.Delete(&policy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete cancellation policy",
+			"message": "Could not remove cancellation policy from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cancellation policy deleted successfully",
+	})
+}
+
+// findOwnedPolicy loads the policy from the id parameter and verifies the current user owns it
+func findOwnedPolicy(c *gin.Context) (models.CancellationPolicy, bool) {
+	var policy models.CancellationPolicy
+	if err := config.DB.First(&policy, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Cancellation policy not found",
+			"message": "The requested cancellation policy does not exist",
+		})
+		return policy, false
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists || policy.UserID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"message": "You can only manage your own cancellation policies",
+		})
+		return policy, false
+	}
+
+	return policy, true
+}
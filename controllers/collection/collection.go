@@ -0,0 +1,418 @@
+package collection
+
+import (
+	"backend-go/config"
+	"backend-go/models"
+	"backend-go/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CollectionRequest represents the request structure for creating or updating a collection
+type CollectionRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description"`
+	Public      bool   `json:"public"`
+}
+
+// AddItemRequest represents the request structure for adding a trip to a collection
+type AddItemRequest struct {
+	TripID uint   `json:"trip_id" binding:"required"`
+	Note   string `json:"note"`
+}
+
+// ReorderRequest represents the request structure for reordering a collection,
+// TripIDs must list every trip in the collection in the new order
+type ReorderRequest struct {
+	TripIDs []uint `json:"trip_ids" binding:"required"`
+}
+
+// GetMine lists the current user's collections
+func GetMine(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to view your collections",
+		})
+		return
+	}
+
+	var collections []models.Collection
+	if err := config.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&collections).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve collections",
+			"message": "Could not fetch collections from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Collections retrieved successfully",
+		"data":    collections,
+		"count":   len(collections),
+	})
+}
+
+// GetByID retrieves a collection with its trips, private collections only for their owner
+func GetByID(c *gin.Context) {
+	var collection models.Collection
+	if err := loadCollection(c, config.DB.Where("id = ?", c.Param("id")), &collection); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Collection not found",
+			"message": "The requested collection does not exist",
+		})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	isOwner := userID != nil && collection.UserID == userID.(uint)
+	if !collection.Public && !isOwner {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Collection not found",
+			"message": "The requested collection does not exist",
+		})
+		return
+	}
+	if !isOwner {
+		collection.ShareToken = ""
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Collection retrieved successfully",
+		"data":    collection,
+	})
+}
+
+// GetShared retrieves a collection through its share link, no login required
+func GetShared(c *gin.Context) {
+	var collection models.Collection
+	if err := loadCollection(c, config.DB.Where("share_token = ?", c.Param("token")), &collection); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Collection not found",
+			"message": "The share link is invalid or has been revoked",
+		})
+		return
+	}
+	collection.ShareToken = ""
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Collection retrieved successfully",
+		"data":    collection,
+	})
+}
+
+// Create creates a collection for the current user
+func Create(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to create a collection",
+		})
+		return
+	}
+
+	var req CollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	collection := models.Collection{
+		UserID:      userID.(uint),
+		Name:        req.Name,
+		Description: req.Description,
+		Public:      req.Public,
+		ShareToken:  utils.RandomToken(),
+	}
+	if err := config.DB.Create(&collection).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create collection",
+			"message": "Could not save collection to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Collection created successfully",
+		"data":    collection,
+	})
+}
+
+// Update renames a collection or changes its visibility (only its owner)
+func Update(c *gin.Context) {
+	collection, ok := findOwnedCollection(c)
+	if !ok {
+		return
+	}
+
+	var req CollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	collection.Name = req.Name
+	collection.Description = req.Description
+	collection.Public = req.Public
+	if err := config.DB.Save(&collection).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update collection",
+			"message": "Could not save changes to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Collection updated successfully",
+		"data":    collection,
+	})
+}
+
+// Delete removes a collection and its items (only its owner)
+func Delete(c *gin.Context) {
+	collection, ok := findOwnedCollection(c)
+	if !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("collection_id = ?", collection.ID).Delete(&models.CollectionItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&collection).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete collection",
+			"message": "Could not remove collection from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Collection deleted successfully",
+	})
+}
+
+// RegenerateShareLink replaces the share token, revoking the previous link (only its owner)
+func RegenerateShareLink(c *gin.Context) {
+	collection, ok := findOwnedCollection(c)
+	if !ok {
+		return
+	}
+
+	collection.ShareToken = utils.RandomToken()
+	if err := config.DB.Model(&collection).Update("share_token", collection.ShareToken).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to regenerate share link",
+			"message": "Could not save share token to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Share link regenerated successfully",
+		"data":    gin.H{"share_token": collection.ShareToken},
+	})
+}
+
+// AddItem appends a trip to a collection (only its owner)
+func AddItem(c *gin.Context) {
+	collection, ok := findOwnedCollection(c)
+	if !ok {
+		return
+	}
+
+	var req AddItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var trip models.Trip
+	if err := config.DB.First(&trip, req.TripID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Trip not found",
+			"message": "The requested trip does not exist",
+		})
+		return
+	}
+
+	var count int64
+	config.DB.Model(&models.CollectionItem{}).Where("collection_id = ? AND trip_id = ?", collection.ID, trip.ID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Trip already in collection",
+			"message": "This trip is already in the collection",
+		})
+		return
+	}
+
+	var last int
+	config.DB.Model(&models.CollectionItem{}).Where("collection_id = ?", collection.ID).
+		Select("COALESCE(MAX(position), 0)").Scan(&last)
+
+	item := models.CollectionItem{
+		CollectionID: collection.ID,
+		TripID:       trip.ID,
+		Position:     last + 1,
+		Note:         req.Note,
+	}
+	if err := config.DB.Create(&item).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to add trip",
+			"message": "Could not save collection item to database",
+		})
+		return
+	}
+
+	item.Trip = trip
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Trip added to collection",
+		"data":    item,
+	})
+}
+
+// RemoveItem removes a trip from a collection (only its owner)
+func RemoveItem(c *gin.Context) {
+	collection, ok := findOwnedCollection(c)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Unscoped().Where("collection_id = ? AND trip_id = ?", collection.ID, c.Param("tripId")).Delete(&models.CollectionItem{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to remove trip",
+			"message": "Could not remove collection item from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Trip removed from collection",
+	})
+}
+
+// Reorder sets the order of the trips in a collection (only its owner)
+func Reorder(c *gin.Context) {
+	collection, ok := findOwnedCollection(c)
+	if !ok {
+		return
+	}
+
+	var req ReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Items of deleted trips are not shown, so they are not part of the order either
+	var items []models.CollectionItem
+	if err := liveItems(config.DB).Where("collection_items.collection_id = ?", collection.ID).Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to reorder collection",
+			"message": "Could not fetch collection items from database",
+		})
+		return
+	}
+
+	positions := make(map[uint]int, len(req.TripIDs))
+	for i, tripID := range req.TripIDs {
+		positions[tripID] = i + 1
+	}
+	valid := len(positions) == len(items) && len(req.TripIDs) == len(items)
+	for _, item := range items {
+		if _, listed := positions[item.TripID]; !listed {
+			valid = false
+		}
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid order",
+			"message": "trip_ids must list every trip in the collection exactly once",
+		})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for _, item := range items {
+			if err := tx.Model(&item).Update("position", positions[item.TripID]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to reorder collection",
+			"message": "Could not save the new order to database",
+		})
+		return
+	}
+
+	loadCollection(c, config.DB.Where("id = ?", collection.ID), &collection)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Collection reordered successfully",
+		"data":    collection,
+	})
+}
+
+// loadCollection loads the collection matched by query with its trips in order,
+// trips that were deleted since they were added are left out
+func loadCollection(c *gin.Context, query *gorm.DB, collection *models.Collection) error {
+	err := query.
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return liveItems(db).Order("collection_items.position, collection_items.id")
+		}).
+		Preload("Items.Trip").
+		Preload("Items.Trip.Images").
+		First(collection).Error
+	for i := range collection.Items {
+		collection.Items[i].Trip.PrepareGallery(utils.GetBaseURL(c))
+	}
+	return err
+}
+
+// liveItems limits a query of collection items to those whose trip has not been deleted
+func liveItems(db *gorm.DB) *gorm.DB {
+	return db.Joins("JOIN trips ON trips.id = collection_items.trip_id AND trips.deleted_at IS NULL")
+}
+
+// findOwnedCollection loads the collection from the id parameter and verifies the current user owns it
+func findOwnedCollection(c *gin.Context) (models.Collection, bool) {
+	var collection models.Collection
+	if err := config.DB.First(&collection, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Collection not found",
+			"message": "The requested collection does not exist",
+		})
+		return collection, false
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists || collection.UserID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"message": "You can only manage your own collections",
+		})
+		return collection, false
+	}
+
+	return collection, true
+}
@@ -0,0 +1,421 @@
+package conversation
+
+import (
+	"backend-go/config"
+	"backend-go/models"
+	"backend-go/notify"
+	"backend-go/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// StartRequest represents the request structure for opening a conversation about a trip or a booking
+type StartRequest struct {
+	TripID    uint   `json:"trip_id" binding:"required_without=BookingID"`
+	BookingID uint   `json:"booking_id" binding:"required_without=TripID"`
+	Body      string `json:"body" binding:"required,max=5000"`
+}
+
+// MessageRequest represents the request structure for sending a message
+type MessageRequest struct {
+	Body string `json:"body" binding:"required,max=5000"`
+}
+
+// GetMine lists the conversations the current user takes part in, most recent activity first.
+// unread=true keeps only conversations with unread messages.
+func GetMine(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to view your conversations",
+		})
+		return
+	}
+
+	query := config.DB.Model(&models.Conversation{}).Where("visitor_id = ? OR owner_id = ?", userID, userID)
+	listConversations(c, userID.(uint), query)
+}
+
+// GetInbox lists the conversations with visitors about the current owner's trips, optionally
+// for one trip_id. unread=true keeps only conversations with unread messages.
+func GetInbox(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to view your inbox",
+		})
+		return
+	}
+
+	query := config.DB.Model(&models.Conversation{}).Where("owner_id = ?", userID)
+	if tripID := c.Query("trip_id"); tripID != "" {
+		query = query.Where("trip_id = ?", tripID)
+	}
+	listConversations(c, userID.(uint), query)
+}
+
+// GetByID retrieves a conversation with a page of its messages, newest first
+func GetByID(c *gin.Context) {
+	conv, ok := findConversation(c)
+	if !ok {
+		return
+	}
+
+	page := utils.GetPage(c)
+	query := config.DB.Model(&models.Message{}).Where("conversation_id = ?", conv.ID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve messages",
+			"message": "Could not fetch messages from database",
+		})
+		return
+	}
+
+	var messages []models.Message
+	if err := page.Apply(query.Order("created_at DESC, id DESC")).Find(&messages).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve messages",
+			"message": "Could not fetch messages from database",
+		})
+		return
+	}
+
+	conversations := []models.Conversation{conv}
+	if err := decorate(c.GetUint("userID"), conversations); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve conversation",
+			"message": "Could not fetch conversation details from database",
+		})
+		return
+	}
+	conv = conversations[0]
+
+	if !conv.ContactsVisible {
+		for i := range messages {
+			messages[i].MaskContacts()
+		}
+	}
+	conv.Messages = messages
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Conversation retrieved successfully",
+		"data":       conv,
+		"count":      len(messages),
+		"total":      total,
+		"pagination": page,
+	})
+}
+
+// Start opens a conversation, or continues the existing one, and sends its first message.
+// With trip_id a visitor asks the trip owner before booking; with booking_id either the
+// visitor who booked or the trip owner writes about that booking.
+func Start(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to send messages",
+		})
+		return
+	}
+
+	var req StartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var conv models.Conversation
+	if req.BookingID != 0 {
+		var booking models.Booking
+		if err := config.DB.Preload("Trip", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).First(&booking, req.BookingID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Booking not found",
+				"message": "The requested booking does not exist",
+			})
+			return
+		}
+		if booking.UserID != userID.(uint) && booking.Trip.UserID != userID.(uint) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Access denied",
+				"message": "You can only write about your own bookings or bookings on your trips",
+			})
+			return
+		}
+		conv = models.Conversation{TripID: booking.TripID, BookingID: &booking.ID, VisitorID: booking.UserID, OwnerID: booking.Trip.UserID}
+	} else {
+		var trip models.Trip
+		if err := config.DB.First(&trip, req.TripID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Trip not found",
+				"message": "The requested trip does not exist",
+			})
+			return
+		}
+		if trip.UserID == userID.(uint) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid conversation",
+				"message": "You cannot start a conversation about your own trip",
+			})
+			return
+		}
+		conv = models.Conversation{TripID: trip.ID, VisitorID: userID.(uint), OwnerID: trip.UserID}
+	}
+
+	var message models.Message
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		lookup := tx.Where("trip_id = ? AND visitor_id = ? AND booking_id IS NULL", conv.TripID, conv.VisitorID)
+		if conv.BookingID != nil {
+			lookup = tx.Where("booking_id = ?", *conv.BookingID)
+		}
+		conv.LastMessageAt = time.Now()
+		if err := lookup.FirstOrCreate(&conv).Error; err != nil {
+			return err
+		}
+
+		var err error
+		message, err = postMessage(tx, conv, userID.(uint), req.Body)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to send message",
+			"message": "Could not save conversation to database",
+		})
+		return
+	}
+
+	config.DB.Preload("Trip", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Visitor").Preload("Owner").First(&conv, conv.ID)
+	// Contact details stay hidden unless they are known to be visible
+	conversations := []models.Conversation{conv}
+	if err := decorate(userID.(uint), conversations); err != nil || !conversations[0].ContactsVisible {
+		message.MaskContacts()
+	}
+	conv = conversations[0]
+	conv.LastMessage = nil
+	conv.Messages = []models.Message{message}
+	notifyRecipient(conv, message)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Message sent successfully",
+		"data":    conv,
+	})
+}
+
+// Send posts a message to a conversation (only its visitor or owner)
+func Send(c *gin.Context) {
+	conv, ok := findConversation(c)
+	if !ok {
+		return
+	}
+
+	var req MessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var message models.Message
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		message, err = postMessage(tx, conv, c.GetUint("userID"), req.Body)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to send message",
+			"message": "Could not save message to database",
+		})
+		return
+	}
+
+	// The sender sees their own message masked too, so they know what the other side will see.
+	// Contact details stay hidden unless they are known to be visible.
+	if visible, err := models.ContactsVisibleIn(config.DB, []models.Conversation{conv}); err != nil || !visible[conv.ID] {
+		message.MaskContacts()
+	}
+	notifyRecipient(conv, message)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Message sent successfully",
+		"data":    message,
+	})
+}
+
+// MarkRead marks every message the other participant sent in a conversation as read
+func MarkRead(c *gin.Context) {
+	conv, ok := findConversation(c)
+	if !ok {
+		return
+	}
+
+	result := config.DB.Model(&models.Message{}).
+		Where("conversation_id = ? AND sender_id <> ? AND read_at IS NULL", conv.ID, c.GetUint("userID")).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to mark conversation as read",
+			"message": "Could not save read receipts to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Conversation marked as read",
+		"data":    gin.H{"marked": result.RowsAffected},
+	})
+}
+
+// listConversations responds with a page of the conversations matched by query, with unread counts,
+// the latest message and the total unread count across all of them
+func listConversations(c *gin.Context, userID uint, query *gorm.DB) {
+	if c.Query("unread") == "true" {
+		query = query.Where("EXISTS (SELECT 1 FROM messages WHERE messages.conversation_id = conversations.id "+
+			"AND messages.sender_id <> ? AND messages.read_at IS NULL AND messages.deleted_at IS NULL)", userID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve conversations",
+			"message": "Could not fetch conversations from database",
+		})
+		return
+	}
+
+	var unread int64
+	if err := config.DB.Model(&models.Message{}).
+		Where("messages.conversation_id IN (?) AND messages.sender_id <> ? AND messages.read_at IS NULL", query.Session(&gorm.Session{}).Select("conversations.id"), userID).
+		Count(&unread).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve conversations",
+			"message": "Could not fetch unread messages from database",
+		})
+		return
+	}
+
+	page := utils.GetPage(c)
+	var conversations []models.Conversation
+	if err := page.Apply(query.Preload("Trip", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Visitor").Preload("Owner").Order("last_message_at DESC")).
+		Find(&conversations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve conversations",
+			"message": "Could not fetch conversations from database",
+		})
+		return
+	}
+
+	if err := decorate(userID, conversations); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve conversations",
+			"message": "Could not fetch conversation details from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Conversations retrieved successfully",
+		"data":         conversations,
+		"count":        len(conversations),
+		"total":        total,
+		"unread_total": unread,
+		"pagination":   page,
+	})
+}
+
+// decorate fills in the unread count, the latest message and whether contact details are visible
+// for userID, masking the latest message when they are not
+func decorate(userID uint, conversations []models.Conversation) error {
+	ids := make([]uint, len(conversations))
+	for i, conv := range conversations {
+		ids[i] = conv.ID
+	}
+
+	visible, err := models.ContactsVisibleIn(config.DB, conversations)
+	if err != nil {
+		return err
+	}
+	unread, err := models.UnreadMessageCounts(config.DB, userID, ids)
+	if err != nil {
+		return err
+	}
+	latest, err := models.LatestMessages(config.DB, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range conversations {
+		conv := &conversations[i]
+		conv.ContactsVisible = visible[conv.ID]
+		conv.UnreadCount = unread[conv.ID]
+		if message, exists := latest[conv.ID]; exists {
+			if !conv.ContactsVisible {
+				message.MaskContacts()
+			}
+			conv.LastMessage = &message
+		}
+	}
+	return nil
+}
+
+// postMessage saves a message and moves the conversation's last activity to it
+func postMessage(tx *gorm.DB, conv models.Conversation, senderID uint, body string) (models.Message, error) {
+	message := models.Message{ConversationID: conv.ID, SenderID: senderID, Body: body}
+	if err := tx.Create(&message).Error; err != nil {
+		return message, err
+	}
+	err := tx.Model(&models.Conversation{}).Where("id = ?", conv.ID).Update("last_message_at", message.CreatedAt).Error
+	return message, err
+}
+
+// notifyRecipient tells the other participant about a message, which must already be masked
+// when contact details are hidden. The conversation's participants must be loaded.
+func notifyRecipient(conv models.Conversation, message models.Message) {
+	recipient, sender := conv.OwnerID, conv.Visitor.Name
+	if message.SenderID == conv.OwnerID {
+		recipient, sender = conv.VisitorID, conv.Owner.Name
+	}
+	notify.Send(recipient, notify.EventMessageReceived, "New message from "+sender+" about "+conv.Trip.Name,
+		message.Body, fmt.Sprintf("/api/v1/conversations/%d", conv.ID))
+}
+
+// findConversation loads the conversation from the id parameter with its trip and participants,
+// and verifies the current user takes part in it
+func findConversation(c *gin.Context) (models.Conversation, bool) {
+	var conv models.Conversation
+	if err := config.DB.Preload("Trip", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Visitor").Preload("Owner").First(&conv, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Conversation not found",
+			"message": "The requested conversation does not exist",
+		})
+		return conv, false
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists || !conv.HasParticipant(userID.(uint)) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"message": "You can only read conversations you take part in",
+		})
+		return conv, false
+	}
+
+	return conv, true
+}
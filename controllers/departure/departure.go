@@ -0,0 +1,478 @@
+package departure
+
+import (
+	"backend-go/config"
+	"backend-go/controllers/shared"
+	"backend-go/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// errScheduleBooked is returned when an update would move or remove occurrences visitors hold seats on
+	errScheduleBooked = errors.New("cancel the upcoming bookings on this departure before changing its schedule")
+
+	// errCapacityBooked is returned when an update would leave fewer seats than are booked
+	errCapacityBooked = errors.New("capacity cannot be lower than the seats already booked")
+)
+
+// maxAvailabilityDays limits how far a single availability request expands recurring departures
+const maxAvailabilityDays = 366
+
+// CreateDepartureRequest represents the request structure for scheduling a departure
+type CreateDepartureRequest struct {
+	StartsAt      time.Time `json:"starts_at" binding:"required"`
+	RRule         string    `json:"rrule"`
+	Timezone      string    `json:"timezone"`
+	Capacity      int       `json:"capacity" binding:"required,min=1"`
+	CutoffMinutes int       `json:"cutoff_minutes" binding:"min=0"`
+	BlackoutDates []string  `json:"blackout_dates"`
+}
+
+// UpdateDepartureRequest represents the request structure for updating a departure
+type UpdateDepartureRequest struct {
+	StartsAt      *time.Time `json:"starts_at"`
+	RRule         *string    `json:"rrule"`
+	Timezone      *string    `json:"timezone"`
+	Capacity      *int       `json:"capacity" binding:"omitempty,min=1"`
+	CutoffMinutes *int       `json:"cutoff_minutes" binding:"omitempty,min=0"`
+	BlackoutDates []string   `json:"blackout_dates"`
+}
+
+// Slot is a single bookable occurrence of a departure
+type Slot struct {
+	DepartureID     uint      `json:"departure_id"`
+	StartsAt        time.Time `json:"starts_at"`
+	EndsAt          time.Time `json:"ends_at"`
+	BookingClosesAt time.Time `json:"booking_closes_at"`
+	Capacity        int       `json:"capacity"`
+	Booked          int       `json:"booked"`
+	Remaining       int       `json:"remaining"`
+}
+
+// GetByTrip lists the departures scheduled for a trip
+func GetByTrip(c *gin.Context) {
+	var departures []models.Departure
+	if err := config.DB.Preload("Blackouts").Where("trip_id = ?", c.Param("id")).Order("starts_at").Find(&departures).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve departures",
+			"message": "Could not fetch departures from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Departures retrieved successfully",
+		"data":    departures,
+		"count":   len(departures),
+	})
+}
+
+// Create schedules a departure for a trip (only the trip owner)
+func Create(c *gin.Context) {
+	trip, ok := shared.FindOwnedTrip(c, c.Param("id"), "departures")
+	if !ok {
+		return
+	}
+
+	var req CreateDepartureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+
+	departure := models.Departure{
+		TripID:        trip.ID,
+		StartsAt:      req.StartsAt.UTC(),
+		RRule:         req.RRule,
+		Timezone:      timezone,
+		Capacity:      req.Capacity,
+		CutoffMinutes: req.CutoffMinutes,
+	}
+	for _, date := range req.BlackoutDates {
+		departure.Blackouts = append(departure.Blackouts, models.DepartureBlackout{Date: date})
+	}
+
+	if !validateDeparture(c, departure) {
+		return
+	}
+
+	if err := config.DB.Create(&departure).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create departure",
+			"message": "Could not save departure to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Departure created successfully",
+		"data":    departure,
+	})
+}
+
+// Update updates a departure (only the trip owner)
+func Update(c *gin.Context) {
+	var departure models.Departure
+	if err := config.DB.Preload("Blackouts").First(&departure, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Departure not found",
+			"message": "The requested departure does not exist",
+		})
+		return
+	}
+
+	if _, ok := shared.FindOwnedTrip(c, departure.TripID, "departures"); !ok {
+		return
+	}
+
+	var req UpdateDepartureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Update only provided fields
+	if req.StartsAt != nil {
+		departure.StartsAt = req.StartsAt.UTC()
+	}
+	if req.RRule != nil {
+		departure.RRule = *req.RRule
+	}
+	if req.Timezone != nil {
+		departure.Timezone = *req.Timezone
+	}
+	if req.Capacity != nil {
+		departure.Capacity = *req.Capacity
+	}
+	if req.CutoffMinutes != nil {
+		departure.CutoffMinutes = *req.CutoffMinutes
+	}
+
+	var blackouts []models.DepartureBlackout
+	if req.BlackoutDates != nil {
+		for _, date := range req.BlackoutDates {
+			blackouts = append(blackouts, models.DepartureBlackout{Date: date})
+		}
+		departure.Blackouts = blackouts
+	}
+
+	if !validateDeparture(c, departure) {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Bookings lock the departure too, so none can be made while the change is checked
+		var current models.Departure
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, departure.ID).Error; err != nil {
+			return err
+		}
+		if err := checkBookedSlots(tx, current, departure, time.Now()); err != nil {
+			return err
+		}
+
+		if err := tx.Omit("Blackouts").Save(&departure).Error; err != nil {
+			return err
+		}
+		if req.BlackoutDates == nil {
+			return nil
+		}
+		if err := tx.Where("departure_id = ?", departure.ID).Delete(&models.DepartureBlackout{}).Error; err != nil {
+			return err
+		}
+		if len(blackouts) == 0 {
+			return nil
+		}
+		for i := range blackouts {
+			blackouts[i].DepartureID = departure.ID
+		}
+		return tx.Create(&blackouts).Error
+	})
+	if errors.Is(err, errScheduleBooked) || errors.Is(err, errCapacityBooked) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Departure has bookings",
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update departure",
+			"message": "Could not save changes to database",
+		})
+		return
+	}
+
+	config.DB.Preload("Blackouts").First(&departure, departure.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Departure updated successfully",
+		"data":    departure,
+	})
+}
+
+// checkBookedSlots verifies that updating a departure from current to updated keeps every upcoming
+// occurrence visitors hold seats on, with room for those seats
+func checkBookedSlots(tx *gorm.DB, current, updated models.Departure, now time.Time) error {
+	var slots []struct {
+		StartsAt time.Time
+		Seats    int
+	}
+	if err := tx.Model(&models.Booking{}).
+		Select("starts_at, SUM(participants) AS seats").
+		Where("departure_id = ? AND starts_at > ? AND status IN ?", current.ID, now, models.ActiveBookingStatuses).
+		Group("starts_at").Order("starts_at").
+		Scan(&slots).Error; err != nil {
+		return err
+	}
+	if len(slots) == 0 {
+		return nil
+	}
+
+	if !updated.StartsAt.Equal(current.StartsAt) || updated.RRule != current.RRule || updated.Timezone != current.Timezone {
+		return errScheduleBooked
+	}
+	for _, slot := range slots {
+		start := slot.StartsAt.UTC()
+		// New blackout dates must not fall on a booked occurrence
+		if runs, err := updated.HasOccurrence(start); err != nil || !runs {
+			return fmt.Errorf("%w: %s is booked", errScheduleBooked, start.Format(time.RFC3339))
+		}
+		if slot.Seats > updated.Capacity {
+			return fmt.Errorf("%w: %d seats are booked on %s", errCapacityBooked, slot.Seats, start.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// Delete removes a departure (only the trip owner)
+func Delete(c *gin.Context) {
+	var departure models.Departure
+	if err := config.DB.First(&departure, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Departure not found",
+			"message": "The requested departure does not exist",
+		})
+		return
+	}
+
+	if _, ok := shared.FindOwnedTrip(c, departure.TripID, "departures"); !ok {
+		return
+	}
+
+	// Refuse to drop a departure visitors still hold seats on
+	var active int64
+	config.DB.Model(&models.Booking{}).
+		Where("departure_id = ? AND starts_at > ? AND status IN ?", departure.ID, time.Now(), models.ActiveBookingStatuses).
+		Count(&active)
+	if active > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Departure has bookings",
+			"message": "Cancel the upcoming bookings on this departure before deleting it",
+		})
+		return
+	}
+
+	if err := config.DB.Delete(&departure).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete departure",
+			"message": "Could not remove departure from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Departure deleted successfully",
+	})
+}
+
+// Availability returns the open slots of a trip between the from and to dates (YYYY-MM-DD, inclusive).
+// Slots past their cut-off time or without remaining capacity are left out.
+func Availability(c *gin.Context) {
+	var trip models.Trip
+	if err := config.DB.First(&trip, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Trip not found",
+			"message": "The requested trip does not exist",
+		})
+		return
+	}
+
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return
+	}
+
+	var departures []models.Departure
+	if err := config.DB.Preload("Blackouts").Where("trip_id = ?", trip.ID).Find(&departures).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve availability",
+			"message": "Could not fetch departures from database",
+		})
+		return
+	}
+
+	booked, err := bookedSeats(departures, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve availability",
+			"message": "Could not fetch bookings from database",
+		})
+		return
+	}
+
+	now := time.Now()
+	slots := []Slot{}
+	for _, departure := range departures {
+		starts, err := departure.Occurrences(from, to)
+		if err != nil {
+			continue
+		}
+		for _, start := range starts {
+			closesAt := departure.BookingClosesAt(start)
+			seats := booked[slotKey{departure.ID, start.Unix()}]
+			if !now.Before(closesAt) || seats >= departure.Capacity {
+				continue
+			}
+			slots = append(slots, Slot{
+				DepartureID:     departure.ID,
+				StartsAt:        start,
+				EndsAt:          trip.EndsAt(start),
+				BookingClosesAt: closesAt,
+				Capacity:        departure.Capacity,
+				Booked:          seats,
+				Remaining:       departure.Capacity - seats,
+			})
+		}
+	}
+
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].StartsAt.Before(slots[j].StartsAt)
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Availability retrieved successfully",
+		"data":    slots,
+		"count":   len(slots),
+	})
+}
+
+// slotKey identifies one occurrence of a departure
+type slotKey struct {
+	DepartureID uint
+	StartsAt    int64
+}
+
+// bookedSeats sums the seats held by active bookings per slot of the given departures
+func bookedSeats(departures []models.Departure, from, to time.Time) (map[slotKey]int, error) {
+	booked := make(map[slotKey]int)
+	if len(departures) == 0 {
+		return booked, nil
+	}
+
+	var ids []uint
+	for _, departure := range departures {
+		ids = append(ids, departure.ID)
+	}
+
+	var rows []struct {
+		DepartureID uint
+		StartsAt    time.Time
+		Seats       int
+	}
+	err := config.DB.Model(&models.Booking{}).
+		Select("departure_id, starts_at, SUM(participants) AS seats").
+		Where("departure_id IN ? AND starts_at >= ? AND starts_at < ? AND status IN ?", ids, from, to, models.ActiveBookingStatuses).
+		Group("departure_id, starts_at").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		booked[slotKey{row.DepartureID, row.StartsAt.Unix()}] = row.Seats
+	}
+	return booked, nil
+}
+
+// validateDeparture checks the timezone, recurrence rule and blackout dates, writing the error response on failure
+func validateDeparture(c *gin.Context, departure models.Departure) bool {
+	if _, err := time.LoadLocation(departure.Timezone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid timezone",
+			"message": "Timezone must be an IANA name such as Asia/Jakarta",
+		})
+		return false
+	}
+
+	if _, err := departure.Rule(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid recurrence rule",
+			"details": err.Error(),
+		})
+		return false
+	}
+
+	for _, blackout := range departure.Blackouts {
+		if _, err := time.Parse("2006-01-02", blackout.Date); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid blackout date",
+				"message": "Blackout dates must use the YYYY-MM-DD format",
+			})
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseDateRange reads the from/to query dates, defaulting to the next 30 days
+func parseDateRange(c *gin.Context) (time.Time, time.Time, bool) {
+	from := time.Now().UTC().Truncate(24 * time.Hour)
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, use YYYY-MM-DD"})
+			return from, from, false
+		}
+		from = parsed
+	}
+
+	to := from.AddDate(0, 0, 30)
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, use YYYY-MM-DD"})
+			return from, from, false
+		}
+		to = parsed.AddDate(0, 0, 1) // inclusive
+	}
+
+	if !to.After(from) || to.Sub(from) > maxAvailabilityDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid date range",
+			"message": "The to date must be after from and at most a year later",
+		})
+		return from, from, false
+	}
+
+	return from, to, true
+}
@@ -0,0 +1,110 @@
+package exchangerate
+
+import (
+	"backend-go/config"
+	"backend-go/currency"
+	"backend-go/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateRateRequest represents the request structure for setting a single exchange rate
+type UpdateRateRequest struct {
+	Rate float64 `json:"rate" binding:"required,gt=0"`
+}
+
+// GetAll lists the exchange rates, quoted as units per one unit of the base currency
+func GetAll(c *gin.Context) {
+	var rates []models.ExchangeRate
+	if err := config.DB.Order("currency").Find(&rates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve exchange rates",
+			"message": "Could not fetch exchange rates from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Exchange rates retrieved successfully",
+		"base":    currency.Base,
+		"data":    rates,
+		"count":   len(rates),
+	})
+}
+
+// Update sets the rate of one currency (only admin)
+func Update(c *gin.Context) {
+	code, err := currency.Normalize(c.Param("currency"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency", "details": err.Error()})
+		return
+	}
+	if code == currency.Base {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid currency",
+			"message": "The rate of the base currency is always 1",
+		})
+		return
+	}
+
+	var req UpdateRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := models.SaveRates(config.DB, currency.Rates{code: req.Rate}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update exchange rate",
+			"message": "Could not save exchange rate to database",
+		})
+		return
+	}
+
+	var rate models.ExchangeRate
+	config.DB.Where("currency = ?", code).First(&rate)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Exchange rate updated successfully",
+		"data":    rate,
+	})
+}
+
+// Import sets the rates of a rate table in the body, leaving currencies it does not list unchanged (only admin).
+// The body has the same format the rates command loads.
+func Import(c *gin.Context) {
+	var table currency.RateTable
+	if err := c.ShouldBindJSON(&table); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	rates, err := table.Normalize()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid rate table",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := models.SaveRates(config.DB, rates); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to import exchange rates",
+			"message": "Could not save exchange rates to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Exchange rates imported successfully",
+		"count":   len(rates),
+	})
+}
@@ -0,0 +1,105 @@
+package favorite
+
+import (
+	"backend-go/config"
+	"backend-go/models"
+	"backend-go/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// GetMine lists the trips the current user saved, most recent first
+func GetMine(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to view your favorites",
+		})
+		return
+	}
+
+	var favorites []models.Favorite
+	if err := config.DB.Preload("Trip").Preload("Trip.Images").
+		Joins("JOIN trips ON trips.id = favorites.trip_id AND trips.deleted_at IS NULL").
+		Where("favorites.user_id = ?", userID).
+		Order("favorites.created_at DESC").
+		Find(&favorites).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve favorites",
+			"message": "Could not fetch favorites from database",
+		})
+		return
+	}
+	for i := range favorites {
+		favorites[i].Trip.PrepareGallery(utils.GetBaseURL(c))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Favorites retrieved successfully",
+		"data":    favorites,
+		"count":   len(favorites),
+	})
+}
+
+// Add saves a trip to the current user's favorites, saving it twice has no effect
+func Add(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to save trips",
+		})
+		return
+	}
+
+	var trip models.Trip
+	if err := config.DB.First(&trip, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Trip not found",
+			"message": "The requested trip does not exist",
+		})
+		return
+	}
+
+	favorite := models.Favorite{UserID: userID.(uint), TripID: trip.ID}
+	if err := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&favorite).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save trip",
+			"message": "Could not save favorite to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Trip added to favorites",
+		"data":    gin.H{"trip_id": trip.ID, "is_favorited": true},
+	})
+}
+
+// Remove removes a trip from the current user's favorites
+func Remove(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to manage your favorites",
+		})
+		return
+	}
+
+	if err := config.DB.Unscoped().Where("user_id = ? AND trip_id = ?", userID, c.Param("id")).Delete(&models.Favorite{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to remove favorite",
+			"message": "Could not remove favorite from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Trip removed from favorites",
+		"data":    gin.H{"trip_id": c.Param("id"), "is_favorited": false},
+	})
+}
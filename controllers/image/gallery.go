@@ -0,0 +1,308 @@
+package image
+
+import (
+	"backend-go/config"
+	"backend-go/controllers/shared"
+	"backend-go/media"
+	"backend-go/models"
+	"backend-go/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReorderRequest lists every image of a trip's gallery in the new order
+type ReorderRequest struct {
+	ImageIDs []uint `json:"image_ids" binding:"required"`
+}
+
+// CoverRequest picks the gallery image used as the trip's cover, null or 0 clears it
+type CoverRequest struct {
+	ImageID *uint `json:"image_id"`
+}
+
+// UpdateRequest changes the details of an image, only provided fields are updated
+type UpdateRequest struct {
+	Caption *string `json:"caption" binding:"omitempty,max=500"`
+	AltText *string `json:"alt_text" binding:"omitempty,max=250"`
+}
+
+// MoveRequest moves an image to another of the owner's trips, null detaches it from any trip
+type MoveRequest struct {
+	TripID *uint `json:"trip_id"`
+}
+
+// Duplicate pairs a gallery image with the earlier one it repeats
+type Duplicate struct {
+	ImageID     uint `json:"image_id"`
+	DuplicateOf uint `json:"duplicate_of"`
+	Distance    int  `json:"distance"` // Bits the fingerprints differ in, 0 for identical content
+	Exact       bool `json:"exact"`    // Both images show the same file
+}
+
+// ReorderGallery sets the order of a trip's images
+func ReorderGallery(c *gin.Context) {
+	trip, ok := shared.FindOwnedTrip(c, c.Param("id"), "images")
+	if !ok {
+		return
+	}
+
+	var req ReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	var imageIDs []uint
+	if err := config.DB.Model(&models.Image{}).Where("trip_id = ?", trip.ID).Pluck("id", &imageIDs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch images"})
+		return
+	}
+
+	// The new order must name each image of the gallery exactly once
+	inGallery := make(map[uint]bool, len(imageIDs))
+	for _, id := range imageIDs {
+		inGallery[id] = true
+	}
+	seen := make(map[uint]bool, len(req.ImageIDs))
+	for _, id := range req.ImageIDs {
+		if !inGallery[id] || seen[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "image_ids must list every image of the trip exactly once"})
+			return
+		}
+		seen[id] = true
+	}
+	if len(seen) != len(inGallery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "image_ids must list every image of the trip exactly once"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for position, id := range req.ImageIDs {
+			if err := tx.Model(&models.Image{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder images"})
+		return
+	}
+
+	respondWithGallery(c, trip.ID, "Gallery reordered successfully")
+}
+
+// SetCover makes one of a trip's images its cover, Trip.CoverImage then follows that image
+func SetCover(c *gin.Context) {
+	trip, ok := shared.FindOwnedTrip(c, c.Param("id"), "images")
+	if !ok {
+		return
+	}
+
+	var req CoverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{"cover_image_id": nil, "cover_image": ""}
+	if req.ImageID != nil && *req.ImageID != 0 {
+		var image models.Image
+		if err := config.DB.Where("id = ? AND trip_id = ?", *req.ImageID, trip.ID).First(&image).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The cover must be one of the trip's images"})
+			return
+		}
+		// The stored path serves responses that do not load the gallery
+		updates = map[string]interface{}{"cover_image_id": image.ID, "cover_image": media.RoutePath(image.StorageKey)}
+	}
+
+	if err := config.DB.Model(&trip).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cover image"})
+		return
+	}
+
+	respondWithGallery(c, trip.ID, "Cover image updated successfully")
+}
+
+// UpdateImage changes the caption and alt text of one of the user's images
+func UpdateImage(c *gin.Context) {
+	image, ok := findOwnImage(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	if req.Caption != nil {
+		image.Caption = *req.Caption
+	}
+	if req.AltText != nil {
+		image.AltText = *req.AltText
+	}
+	if err := config.DB.Model(&image).Select("caption", "alt_text").Updates(&image).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update image"})
+		return
+	}
+	image.ResolveURLs(utils.GetBaseURL(c))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Image updated successfully",
+		"image":   image,
+	})
+}
+
+// MoveImage moves one of the user's images to the end of another of their trips' galleries
+func MoveImage(c *gin.Context) {
+	image, ok := findOwnImage(c)
+	if !ok {
+		return
+	}
+
+	var req MoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+	if req.TripID != nil && *req.TripID == 0 {
+		req.TripID = nil
+	}
+	if req.TripID != nil {
+		if _, ok := shared.FindOwnedTrip(c, *req.TripID, "images"); !ok {
+			return
+		}
+	}
+	if sameTrip(image.TripID, req.TripID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Image is already in that trip"})
+		return
+	}
+	if !checkTripImages(c, req.TripID, 1) {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := models.ClearTripCover(tx, image.ID); err != nil {
+			return err
+		}
+		position := 0
+		if req.TripID != nil {
+			next, err := models.NextImagePosition(tx, *req.TripID)
+			if err != nil {
+				return err
+			}
+			position = next
+		}
+		image.TripID = req.TripID
+		image.Position = position
+		return tx.Model(&image).Select("trip_id", "position").Updates(&image).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move image"})
+		return
+	}
+	image.ResolveURLs(utils.GetBaseURL(c))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Image moved successfully",
+		"image":   image,
+	})
+}
+
+// GetDuplicates flags images of a trip's gallery that repeat an earlier image in gallery order, either
+// the same file or a near-duplicate such as a resized or recompressed copy of the same photo
+func GetDuplicates(c *gin.Context) {
+	trip, ok := shared.FindOwnedTrip(c, c.Param("id"), "images")
+	if !ok {
+		return
+	}
+
+	var images []models.Image
+	if err := config.DB.Where("trip_id = ?", trip.ID).Order("position, id").Find(&images).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch images"})
+		return
+	}
+
+	duplicates := []Duplicate{}
+	for i, image := range images {
+		for _, earlier := range images[:i] {
+			duplicate := Duplicate{ImageID: image.ID, DuplicateOf: earlier.ID}
+			switch {
+			case image.ContentHash != "" && image.ContentHash == earlier.ContentHash:
+				duplicate.Exact = true
+			case image.Fingerprint != nil && earlier.Fingerprint != nil:
+				duplicate.Distance = media.Distance(uint64(*image.Fingerprint), uint64(*earlier.Fingerprint))
+				if duplicate.Distance > media.NearDuplicateDistance {
+					continue
+				}
+			default:
+				continue
+			}
+			duplicates = append(duplicates, duplicate)
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"duplicates": duplicates,
+		"count":      len(duplicates),
+	})
+}
+
+// uploadTripID reads the trip an upload is attached to, which must be one of the uploader's trips
+func uploadTripID(c *gin.Context, raw string) (*uint, bool) {
+	if raw == "" {
+		return nil, true
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+		return nil, false
+	}
+	trip, ok := shared.FindOwnedTrip(c, uint(id), "images")
+	if !ok {
+		return nil, false
+	}
+	return &trip.ID, true
+}
+
+// findOwnImage loads the image from the id parameter and verifies the current user uploaded it
+func findOwnImage(c *gin.Context) (models.Image, bool) {
+	var image models.Image
+	imageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image ID"})
+		return image, false
+	}
+
+	if err := config.DB.Where("id = ? AND uploaded_by = ?", uint(imageID), c.GetUint("userID")).First(&image).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found or unauthorized"})
+		return image, false
+	}
+	return image, true
+}
+
+func sameTrip(a, b *uint) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// respondWithGallery answers with a trip's images in gallery order
+func respondWithGallery(c *gin.Context, tripID uint, message string) {
+	var trip models.Trip
+	if err := config.DB.Preload("Images").First(&trip, tripID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch images"})
+		return
+	}
+	trip.PrepareGallery(utils.GetBaseURL(c))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        message,
+		"images":         trip.Images,
+		"cover_image_id": trip.CoverImageID,
+	})
+}
@@ -2,18 +2,15 @@ package image
 
 import (
 	"backend-go/config"
+	"backend-go/media"
 	"backend-go/models"
-	"fmt"
-	"io"
+	"backend-go/utils"
+	"errors"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
-	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // Upload handles regular image uploads
@@ -26,9 +23,10 @@ func Upload(c *gin.Context) {
 	}
 
 	// Parse multipart form
+	limitRequest(c)
 	form, err := c.MultipartForm()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form"})
+		formError(c, err)
 		return
 	}
 
@@ -38,63 +36,43 @@ func Upload(c *gin.Context) {
 		return
 	}
 
-	// Get trip ID if provided
-	var tripID *uint
-	if tripIDStr := c.PostForm("trip_id"); tripIDStr != "" {
-		if id, err := strconv.ParseUint(tripIDStr, 10, 32); err == nil {
-			tripIDUint := uint(id)
-			tripID = &tripIDUint
-		}
+	// Get trip ID if provided, images can only be attached to the uploader's own trips
+	tripID, ok := uploadTripID(c, c.PostForm("trip_id"))
+	if !ok {
+		return
 	}
 
 	var uploadedImages []models.Image
-	uploadDir := "uploads/images"
-
-	// Create upload directory if it doesn't exist
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload directory"})
-		return
-	}
 
-	for _, file := range files {
-		// Validate file type
-		if !isValidImageType(file.Header.Get("Content-Type")) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid file type for %s", file.Filename)})
+	// Validate every file before saving any, so a bad file does not leave half an upload behind
+	processed := make([]media.Processed, len(files))
+	for i, file := range files {
+		if processed[i], err = processUpload(file); err != nil {
+			uploadError(c, file.Filename, err)
 			return
 		}
+	}
 
-		// Generate unique filename
-		ext := filepath.Ext(file.Filename)
-		fileName := fmt.Sprintf("image_%s_%s%s",
-			time.Now().Format("20060102_150405"),
-			uuid.New().String(),
-			ext)
-
-		filePath := filepath.Join(uploadDir, fileName)
+	// Check the quotas once the stored sizes are known, the upload rate last so a rejected upload does not count
+	var total int64
+	for _, p := range processed {
+		total += int64(len(p.Data))
+	}
+	if !checkStorage(c, userID.(uint), total, 0) || !checkTripImages(c, tripID, len(files)) || !checkUploadRate(c, userID.(uint), len(files)) {
+		return
+	}
 
-		// Save file
-		if err := c.SaveUploadedFile(file, filePath); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+	for i, file := range files {
+		image, err := storeImage(c, imagesFolder, processed[i], file.Filename, tripID, userID.(uint), recheckStorage(c, userID.(uint)))
+		var quotaErr QuotaError
+		if errors.As(err, &quotaErr) {
+			quotaExceeded(c, quotaErr)
 			return
 		}
-
-		// Create image record
-		userIDUint := userID.(uint)
-		image := models.Image{
-			TripID:       tripID,
-			URL:          fmt.Sprintf("http://localhost:8080/api/v1/images/%s", fileName),
-			FileName:     fileName,
-			OriginalName: file.Filename,
-			FileSize:     file.Size,
-			MimeType:     file.Header.Get("Content-Type"),
-			UploadedBy:   &userIDUint,
-		}
-
-		if err := config.DB.Create(&image).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image record"})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
 			return
 		}
-
 		uploadedImages = append(uploadedImages, image)
 	}
 
@@ -113,64 +91,43 @@ func UploadCoverImage(c *gin.Context) {
 		return
 	}
 
+	limitRequest(c)
 	file, err := c.FormFile("cover_image")
 	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			formError(c, err)
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No cover image provided"})
 		return
 	}
 
-	// Validate file type
-	if !isValidImageType(file.Header.Get("Content-Type")) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file type"})
+	// Validate the content and strip its metadata
+	processed, err := processUpload(file)
+	if err != nil {
+		uploadError(c, file.Filename, err)
 		return
 	}
 
-	// Get trip ID if provided
-	var tripID *uint
-	if tripIDStr := c.PostForm("trip_id"); tripIDStr != "" {
-		if id, err := strconv.ParseUint(tripIDStr, 10, 32); err == nil {
-			tripIDUint := uint(id)
-			tripID = &tripIDUint
-		}
-	}
-
-	uploadDir := "uploads/covers"
-
-	// Create upload directory if it doesn't exist
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload directory"})
+	// Get trip ID if provided, images can only be attached to the uploader's own trips
+	tripID, ok := uploadTripID(c, c.PostForm("trip_id"))
+	if !ok {
 		return
 	}
 
-	// Generate unique filename
-	ext := filepath.Ext(file.Filename)
-	fileName := fmt.Sprintf("cover_%s_%s%s",
-		time.Now().Format("20060102_150405"),
-		uuid.New().String(),
-		ext)
-
-	filePath := filepath.Join(uploadDir, fileName)
-
-	// Save file
-	if err := c.SaveUploadedFile(file, filePath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+	if !checkStorage(c, userID.(uint), int64(len(processed.Data)), 0) || !checkTripImages(c, tripID, 1) || !checkUploadRate(c, userID.(uint), 1) {
 		return
 	}
 
-	// Create image record
-	userIDUint := userID.(uint)
-	image := models.Image{
-		TripID:       tripID,
-		URL:          fmt.Sprintf("http://localhost:8080/api/v1/covers/%s", fileName),
-		FileName:     fileName,
-		OriginalName: file.Filename,
-		FileSize:     file.Size,
-		MimeType:     file.Header.Get("Content-Type"),
-		UploadedBy:   &userIDUint,
+	image, err := storeImage(c, coversFolder, processed, file.Filename, tripID, userID.(uint), recheckStorage(c, userID.(uint)))
+	var quotaErr QuotaError
+	if errors.As(err, &quotaErr) {
+		quotaExceeded(c, quotaErr)
+		return
 	}
-
-	if err := config.DB.Create(&image).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image record"})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
 		return
 	}
 
@@ -180,112 +137,6 @@ func UploadCoverImage(c *gin.Context) {
 	})
 }
 
-// GetImage serves regular images
-func GetImage(c *gin.Context) {
-	filename := c.Param("filename")
-	if filename == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Filename is required"})
-		return
-	}
-
-	filePath := filepath.Join("uploads/images", filename)
-
-	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
-		return
-	}
-
-	// Open and serve the file
-	file, err := os.Open(filePath)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open image"})
-		return
-	}
-	defer file.Close()
-
-	// Get file info for content type
-	var image models.Image
-	if err := config.DB.Where("file_name = ?", filename).First(&image).Error; err == nil {
-		c.Header("Content-Type", image.MimeType)
-	} else {
-		// Fallback to detecting content type from file extension
-		ext := strings.ToLower(filepath.Ext(filename))
-		switch ext {
-		case ".jpg", ".jpeg":
-			c.Header("Content-Type", "image/jpeg")
-		case ".png":
-			c.Header("Content-Type", "image/png")
-		case ".gif":
-			c.Header("Content-Type", "image/gif")
-		case ".webp":
-			c.Header("Content-Type", "image/webp")
-		default:
-			c.Header("Content-Type", "application/octet-stream")
-		}
-	}
-
-	// Copy file content to response
-	_, err = io.Copy(c.Writer, file)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serve image"})
-		return
-	}
-}
-
-// GetCoverImage serves cover images
-func GetCoverImage(c *gin.Context) {
-	filename := c.Param("filename")
-	if filename == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Filename is required"})
-		return
-	}
-
-	filePath := filepath.Join("uploads/covers", filename)
-
-	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Cover image not found"})
-		return
-	}
-
-	// Open and serve the file
-	file, err := os.Open(filePath)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open cover image"})
-		return
-	}
-	defer file.Close()
-
-	// Get file info for content type
-	var image models.Image
-	if err := config.DB.Where("file_name = ?", filename).First(&image).Error; err == nil {
-		c.Header("Content-Type", image.MimeType)
-	} else {
-		// Fallback to detecting content type from file extension
-		ext := strings.ToLower(filepath.Ext(filename))
-		switch ext {
-		case ".jpg", ".jpeg":
-			c.Header("Content-Type", "image/jpeg")
-		case ".png":
-			c.Header("Content-Type", "image/png")
-		case ".gif":
-			c.Header("Content-Type", "image/gif")
-		case ".webp":
-			c.Header("Content-Type", "image/webp")
-		default:
-			c.Header("Content-Type", "application/octet-stream")
-		}
-	}
-
-	// Copy file content to response
-	_, err = io.Copy(c.Writer, file)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serve cover image"})
-		return
-	}
-}
-
 // GetMyImages returns images uploaded by the authenticated user
 func GetMyImages(c *gin.Context) {
 	userID, exists := c.Get("userID")
@@ -299,6 +150,7 @@ func GetMyImages(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch images"})
 		return
 	}
+	models.ResolveImageURLs(images, utils.GetBaseURL(c))
 
 	c.JSON(http.StatusOK, gin.H{
 		"images": images,
@@ -315,10 +167,11 @@ func GetImagesByTrip(c *gin.Context) {
 	}
 
 	var images []models.Image
-	if err := config.DB.Where("trip_id = ?", uint(tripID)).Find(&images).Error; err != nil {
+	if err := config.DB.Where("trip_id = ?", uint(tripID)).Order("position, id").Find(&images).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch images"})
 		return
 	}
+	models.ResolveImageURLs(images, utils.GetBaseURL(c))
 
 	c.JSON(http.StatusOK, gin.H{
 		"images": images,
@@ -347,22 +200,27 @@ func DeleteImage(c *gin.Context) {
 		return
 	}
 
-	// Determine file path based on URL
-	var filePath string
-	if strings.Contains(image.URL, "/covers/") {
-		filePath = filepath.Join("uploads/covers", image.FileName)
-	} else {
-		filePath = filepath.Join("uploads/images", image.FileName)
-	}
-
-	// Delete the file from filesystem
-	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete file"})
+	// Delete the database record, a trip using it as cover falls back to no cover. The file and its
+	// variants go with the last image showing them.
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := models.ClearTripCover(tx, image.ID); err != nil {
+			return err
+		}
+		result := tx.Delete(&image)
+		if result.Error != nil {
+			return result.Error
+		}
+		// A concurrent delete already released the blob
+		if result.RowsAffected != 1 {
+			return gorm.ErrRecordNotFound
+		}
+		return releaseBlob(c.Request.Context(), tx, image)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found or unauthorized"})
 		return
 	}
-
-	// Delete the database record
-	if err := config.DB.Delete(&image).Error; err != nil {
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete image record"})
 		return
 	}
@@ -371,21 +229,3 @@ func DeleteImage(c *gin.Context) {
 		"message": "Image deleted successfully",
 	})
 }
-
-// isValidImageType checks if the MIME type is a valid image type
-func isValidImageType(mimeType string) bool {
-	validTypes := []string{
-		"image/jpeg",
-		"image/jpg",
-		"image/png",
-		"image/gif",
-		"image/webp",
-	}
-
-	for _, validType := range validTypes {
-		if mimeType == validType {
-			return true
-		}
-	}
-	return false
-}
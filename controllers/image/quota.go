@@ -0,0 +1,319 @@
+package image
+
+import (
+	"backend-go/config"
+	"backend-go/models"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Quota is what one user of a role may keep in storage and how many images they may upload per hour
+type Quota struct {
+	StorageBytes   int64 `json:"storage_bytes"`
+	UploadsPerHour int   `json:"uploads_per_hour"`
+}
+
+// Quotas per role, configured with QUOTA_<ROLE>_STORAGE_MB and QUOTA_<ROLE>_UPLOADS_PER_HOUR.
+// Unknown roles get the visitor quota.
+var quotas = map[string]Quota{
+	"visitor": {
+		StorageBytes:   megabytesFromEnv("QUOTA_VISITOR_STORAGE_MB", 100),
+		UploadsPerHour: countFromEnv("QUOTA_VISITOR_UPLOADS_PER_HOUR", 30),
+	},
+	"trip_owner": {
+		StorageBytes:   megabytesFromEnv("QUOTA_TRIP_OWNER_STORAGE_MB", 2048),
+		UploadsPerHour: countFromEnv("QUOTA_TRIP_OWNER_UPLOADS_PER_HOUR", 300),
+	},
+}
+
+// maxImagesPerTrip caps a trip's gallery, QUOTA_IMAGES_PER_TRIP overrides the default
+var maxImagesPerTrip = countFromEnv("QUOTA_IMAGES_PER_TRIP", 100)
+
+// Quota names used in quota errors
+const (
+	quotaStorage       = "storage"
+	quotaImagesPerTrip = "images_per_trip"
+	quotaUploadRate    = "upload_rate"
+)
+
+// QuotaError tells the client which limit an upload would exceed and by how much
+type QuotaError struct {
+	Quota      string `json:"quota"`
+	Limit      int64  `json:"limit"`
+	Used       int64  `json:"used"`
+	Requested  int64  `json:"requested"`
+	TripID     *uint  `json:"trip_id,omitempty"`
+	RetryAfter int64  `json:"retry_after,omitempty"` // Seconds until the upload rate allows the upload again
+}
+
+// Error lets a QuotaError roll back the transaction that found the quota exceeded
+func (e QuotaError) Error() string {
+	return fmt.Sprintf("%s quota exceeded", e.Quota)
+}
+
+// countFromEnv reads a positive count from the environment
+func countFromEnv(key string, fallback int) int {
+	if value := os.Getenv(key); value != "" {
+		if count, err := strconv.Atoi(value); err == nil && count > 0 {
+			return count
+		}
+		log.Printf("Warning: ignoring invalid %s=%q", key, value)
+	}
+	return fallback
+}
+
+// quotaFor returns the quota of the current user's role
+func quotaFor(c *gin.Context) Quota {
+	if quota, exists := quotas[c.GetString("role")]; exists {
+		return quota
+	}
+	return quotas["visitor"]
+}
+
+// quotaExceeded responds to an upload that would go over a quota. Storage and gallery limits answer
+// 403 as retrying will not help, the upload rate answers 429 with Retry-After, or 413 when the request
+// alone holds more images than the hourly limit.
+func quotaExceeded(c *gin.Context, quotaErr QuotaError) {
+	status := http.StatusForbidden
+	message := fmt.Sprintf("This upload needs %d bytes but only %d of your %d bytes are left", quotaErr.Requested, max(quotaErr.Limit-quotaErr.Used, 0), quotaErr.Limit)
+	switch {
+	case quotaErr.Quota == quotaImagesPerTrip:
+		message = fmt.Sprintf("A trip can have at most %d images, this one has %d", quotaErr.Limit, quotaErr.Used)
+	case quotaErr.Quota == quotaUploadRate && quotaErr.Requested > quotaErr.Limit:
+		status = http.StatusRequestEntityTooLarge
+		message = fmt.Sprintf("You can upload %d images per hour, this request has %d", quotaErr.Limit, quotaErr.Requested)
+	case quotaErr.Quota == quotaUploadRate:
+		status = http.StatusTooManyRequests
+		message = fmt.Sprintf("You can upload %d images per hour, try again in %d seconds", quotaErr.Limit, quotaErr.RetryAfter)
+		c.Header("Retry-After", strconv.FormatInt(quotaErr.RetryAfter, 10))
+	}
+
+	c.JSON(status, gin.H{
+		"error":   "Quota exceeded",
+		"message": message,
+		"quota":   quotaErr,
+	})
+}
+
+// checkUploadRate counts count uploads against the user's hourly upload rate
+func checkUploadRate(c *gin.Context, userID uint, count int) bool {
+	limit := quotaFor(c).UploadsPerHour
+	if count > limit {
+		// No amount of waiting lets this request through
+		used, _ := uploadRate.used(userID)
+		quotaExceeded(c, QuotaError{Quota: quotaUploadRate, Limit: int64(limit), Used: int64(used), Requested: int64(count)})
+		return false
+	}
+	used, retryAfter, ok := uploadRate.take(userID, count, limit)
+	if !ok {
+		quotaExceeded(c, QuotaError{
+			Quota:      quotaUploadRate,
+			Limit:      int64(limit),
+			Used:       int64(used),
+			Requested:  int64(count),
+			RetryAfter: int64(math.Ceil(retryAfter.Seconds())),
+		})
+	}
+	return ok
+}
+
+// checkStorage verifies the user's storage quota has room for bytes more. Bytes reserved by
+// unfinished resumable uploads count as used, except the released bytes of the upload being finished.
+func checkStorage(c *gin.Context, userID uint, bytes, released int64) bool {
+	used, err := models.StorageUsed(config.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check storage quota"})
+		return false
+	}
+	reserved, err := models.StorageReserved(config.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check storage quota"})
+		return false
+	}
+
+	limit := quotaFor(c).StorageBytes
+	used += reserved - released
+	if used+bytes > limit {
+		quotaExceeded(c, QuotaError{Quota: quotaStorage, Limit: limit, Used: used, Requested: bytes})
+		return false
+	}
+	return true
+}
+
+// recheckStorage returns a storeImage hook checking the storage quota again once the image is inserted,
+// so concurrent uploads that each passed checkStorage cannot together go over it. Locking the uploader
+// makes their uploads take turns, the hook fails with a QuotaError when the image does not fit.
+func recheckStorage(c *gin.Context, userID uint) func(tx *gorm.DB, image models.Image) error {
+	limit := quotaFor(c).StorageBytes
+	return func(tx *gorm.DB, image models.Image) error {
+		// NO KEY UPDATE does not wait on the key share locks taken by inserting images
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "NO KEY UPDATE"}).Select("id").First(&user, userID).Error; err != nil {
+			return err
+		}
+		used, err := models.StorageUsed(tx, userID)
+		if err != nil {
+			return err
+		}
+		reserved, err := models.StorageReserved(tx, userID)
+		if err != nil {
+			return err
+		}
+		if used+reserved > limit {
+			return QuotaError{Quota: quotaStorage, Limit: limit, Used: used + reserved - image.FileSize, Requested: image.FileSize}
+		}
+		return nil
+	}
+}
+
+// checkTripImages verifies a trip's gallery has room for count more images
+func checkTripImages(c *gin.Context, tripID *uint, count int) bool {
+	if tripID == nil {
+		return true
+	}
+	images, err := models.CountTripImages(config.DB, *tripID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check image quota"})
+		return false
+	}
+	if images+int64(count) > int64(maxImagesPerTrip) {
+		quotaExceeded(c, QuotaError{
+			Quota:     quotaImagesPerTrip,
+			Limit:     int64(maxImagesPerTrip),
+			Used:      images,
+			Requested: int64(count),
+			TripID:    tripID,
+		})
+		return false
+	}
+	return true
+}
+
+// GetUsage returns the current user's storage, upload rate and gallery consumption against their quotas
+func GetUsage(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	used, err := models.StorageUsed(config.DB, userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch usage"})
+		return
+	}
+	reserved, err := models.StorageReserved(config.DB, userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch usage"})
+		return
+	}
+
+	// Gallery sizes of the user's own trips
+	var trips []struct {
+		TripID uint   `json:"trip_id"`
+		Name   string `json:"name"`
+		Images int64  `json:"images"`
+	}
+	if err := config.DB.Model(&models.Trip{}).
+		Select("trips.id AS trip_id, trips.name, COUNT(images.id) AS images").
+		Joins("LEFT JOIN images ON images.trip_id = trips.id AND images.deleted_at IS NULL").
+		Where("trips.user_id = ?", userID).
+		Group("trips.id, trips.name").
+		Order("trips.id").
+		Scan(&trips).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch usage"})
+		return
+	}
+
+	quota := quotaFor(c)
+	uploads, resetsIn := uploadRate.used(userID.(uint))
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"role": c.GetString("role"),
+			"storage": gin.H{
+				"used_bytes":      used,
+				"reserved_bytes":  reserved,
+				"limit_bytes":     quota.StorageBytes,
+				"remaining_bytes": max(quota.StorageBytes-used-reserved, 0),
+			},
+			"uploads": gin.H{
+				"used":              uploads,
+				"limit":             quota.UploadsPerHour,
+				"remaining":         max(quota.UploadsPerHour-uploads, 0),
+				"resets_in_seconds": int64(math.Ceil(resetsIn.Seconds())),
+			},
+			"images_per_trip": maxImagesPerTrip,
+			"trips":           trips,
+		},
+	})
+}
+
+// uploadRate counts each user's uploads over the last hour. It lives in memory, so every instance of
+// the API enforces the limit on its own and a restart forgets it.
+var uploadRate = &rateLimiter{window: time.Hour, uploads: make(map[uint][]time.Time)}
+
+// rateLimiter is a sliding window of upload times per user
+type rateLimiter struct {
+	mu      sync.Mutex
+	window  time.Duration
+	uploads map[uint][]time.Time
+}
+
+// take records count uploads when they fit within limit. Otherwise it returns how many uploads the
+// window holds and how long until enough of them expire. count must not exceed limit.
+func (l *rateLimiter) take(userID uint, count, limit int) (int, time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	recent := l.prune(userID, now)
+	if len(recent)+count > limit {
+		excess := len(recent) + count - limit
+		return len(recent), recent[excess-1].Add(l.window).Sub(now), false
+	}
+
+	for i := 0; i < count; i++ {
+		recent = append(recent, now)
+	}
+	l.uploads[userID] = recent
+	return len(recent), 0, true
+}
+
+// used returns how many uploads the window holds for a user and how long until the oldest expires
+func (l *rateLimiter) used(userID uint) (int, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	recent := l.prune(userID, now)
+	if len(recent) == 0 {
+		return 0, 0
+	}
+	return len(recent), recent[0].Add(l.window).Sub(now)
+}
+
+// prune drops a user's uploads that left the window, forgetting users with none left
+func (l *rateLimiter) prune(userID uint, now time.Time) []time.Time {
+	recent := l.uploads[userID]
+	cutoff := now.Add(-l.window)
+	for len(recent) > 0 && !recent[0].After(cutoff) {
+		recent = recent[1:]
+	}
+	if len(recent) == 0 {
+		delete(l.uploads, userID)
+		return nil
+	}
+	l.uploads[userID] = recent
+	return recent
+}
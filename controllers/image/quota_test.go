@@ -0,0 +1,69 @@
+package image
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimiterTake(t *testing.T) {
+	l := &rateLimiter{window: time.Hour, uploads: make(map[uint][]time.Time)}
+
+	if used, _, ok := l.take(1, 3, 5); !ok || used != 3 {
+		t.Fatalf("take(3) = %d, %v, want 3, true", used, ok)
+	}
+	if used, retryAfter, ok := l.take(1, 3, 5); ok || used != 3 || retryAfter <= 59*time.Minute || retryAfter > time.Hour {
+		t.Errorf("take(3) over the limit = %d, %v, %v, want 3, about an hour, false", used, retryAfter, ok)
+	}
+	if used, _, ok := l.take(1, 2, 5); !ok || used != 5 {
+		t.Errorf("take(2) = %d, %v, want 5, true", used, ok)
+	}
+	if used, _, ok := l.take(2, 5, 5); !ok || used != 5 {
+		t.Errorf("take(5) for another user = %d, %v, want 5, true", used, ok)
+	}
+}
+
+func TestRateLimiterWindow(t *testing.T) {
+	l := &rateLimiter{window: time.Hour, uploads: make(map[uint][]time.Time)}
+	now := time.Now()
+	l.uploads[1] = []time.Time{now.Add(-90 * time.Minute), now.Add(-30 * time.Minute), now.Add(-10 * time.Minute)}
+
+	used, resetsIn := l.used(1)
+	if used != 2 || resetsIn <= 29*time.Minute || resetsIn > 30*time.Minute {
+		t.Errorf("used = %d, %v, want 2, about 30 minutes", used, resetsIn)
+	}
+
+	// Two more need both remaining uploads to expire, the later one goes in 50 minutes
+	if _, retryAfter, ok := l.take(1, 2, 2); ok || retryAfter <= 49*time.Minute || retryAfter > 50*time.Minute {
+		t.Errorf("take(2) = %v, %v, want about 50 minutes, false", retryAfter, ok)
+	}
+
+	l.uploads[1] = []time.Time{now.Add(-2 * time.Hour)}
+	if used, _ := l.used(1); used != 0 {
+		t.Errorf("used after the window = %d, want 0", used)
+	}
+	if _, exists := l.uploads[1]; exists {
+		t.Error("a user without recent uploads is still tracked")
+	}
+}
+
+func TestCheckUploadRateOverLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Set("role", "visitor")
+
+	limit := quotas["visitor"].UploadsPerHour
+	if checkUploadRate(c, 1, limit+1) {
+		t.Fatal("checkUploadRate allowed more images than the hourly limit")
+	}
+	if recorder.Code != http.StatusRequestEntityTooLarge || recorder.Header().Get("Retry-After") != "" {
+		t.Errorf("response = %d with Retry-After %q, want %d without", recorder.Code, recorder.Header().Get("Retry-After"), http.StatusRequestEntityTooLarge)
+	}
+	if used, _ := uploadRate.used(1); used != 0 {
+		t.Errorf("the refused request counted %d uploads", used)
+	}
+}
@@ -0,0 +1,445 @@
+package image
+
+import (
+	"backend-go/config"
+	"backend-go/media"
+	"backend-go/models"
+	"backend-go/storage"
+	"backend-go/utils"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Resumable uploads follow tus 1.0 (https://tus.io/protocols/resumable-upload) with the creation,
+// expiration and termination extensions
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,expiration,termination"
+
+	// uploadTTL is how long a session lives after its last chunk before it is abandoned
+	uploadTTL = 24 * time.Hour
+
+	// uploadLockLease bounds how long a PATCH holds a session, so a crashed one cannot block it forever
+	uploadLockLease = 10 * time.Minute
+
+	// partsFolder keeps received chunks, one object per PATCH named by its zero-padded offset
+	partsFolder = "tus"
+)
+
+// errUploadFinished rolls back an image whose session another request already finished
+var errUploadFinished = errors.New("upload already finished")
+
+// TusOptions answers tus discovery with the protocol version, extensions and size limit
+func TusOptions(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	c.Header("Tus-Max-Size", strconv.FormatInt(maxFileSize, 10))
+	c.Status(http.StatusNoContent)
+}
+
+// CreateUpload starts a resumable upload. Upload-Length is required and Upload-Metadata may carry
+// filename, kind (image or cover) and trip_id. The Location header is where chunks are sent.
+func CreateUpload(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	if !tusRequest(c) {
+		return
+	}
+
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length < 1 {
+		message := "Upload-Length must be a positive number of bytes"
+		if c.GetHeader("Upload-Defer-Length") != "" {
+			message = "Upload-Defer-Length is not supported, send Upload-Length"
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+	if length > maxFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":     "Upload is too large",
+			"max_bytes": maxFileSize,
+		})
+		return
+	}
+
+	metadata, err := parseUploadMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Upload-Metadata", "details": err.Error()})
+		return
+	}
+
+	session := models.UploadSession{
+		Token:        utils.RandomToken(),
+		UserID:       userID.(uint),
+		Kind:         models.UploadKindImage,
+		FileName:     metadata["filename"],
+		UploadLength: length,
+		ExpiresAt:    time.Now().Add(uploadTTL),
+	}
+	if kind := metadata["kind"]; kind != "" {
+		if kind != models.UploadKindImage && kind != models.UploadKindCover {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be image or cover"})
+			return
+		}
+		session.Kind = kind
+	}
+
+	// Get trip ID if provided, images can only be attached to the uploader's own trips
+	tripID, ok := uploadTripID(c, metadata["trip_id"])
+	if !ok {
+		return
+	}
+	session.TripID = tripID
+
+	// The declared length is reserved against the storage quota until the upload completes or expires
+	if !checkStorage(c, session.UserID, length, 0) || !checkTripImages(c, tripID, 1) || !checkUploadRate(c, session.UserID, 1) {
+		return
+	}
+
+	if err := config.DB.Create(&session).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload"})
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("%s/api/v1/uploads/%s", utils.GetBaseURL(c), session.Token))
+	c.Header("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Upload created successfully",
+		"data":    session,
+	})
+}
+
+// HeadUpload reports how many bytes of an upload have been received, clients resume from there
+func HeadUpload(c *gin.Context) {
+	if !tusRequest(c) {
+		return
+	}
+	session, ok := findUpload(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	setUploadHeaders(c, session)
+	c.Status(http.StatusOK)
+}
+
+// GetUpload returns an upload's progress, and its image once complete
+func GetUpload(c *gin.Context) {
+	session, ok := findUpload(c)
+	if !ok {
+		return
+	}
+	if session.Image != nil {
+		session.Image.ResolveURLs(utils.GetBaseURL(c))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Upload retrieved successfully",
+		"data":    session,
+	})
+}
+
+// PatchUpload appends a chunk at Upload-Offset. What arrives is kept even when the connection drops
+// mid-chunk, so the client resumes from the offset a HEAD reports. The chunk that completes the
+// upload runs the normal validation pipeline and creates the image.
+func PatchUpload(c *gin.Context) {
+	if !tusRequest(c) {
+		return
+	}
+	if c.ContentType() != "application/offset+octet-stream" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/offset+octet-stream"})
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Offset must be a non-negative number"})
+		return
+	}
+
+	session, ok := findUpload(c)
+	if !ok {
+		return
+	}
+	if session.Complete() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Upload is already complete"})
+		return
+	}
+	if offset != session.UploadOffset {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         "Upload-Offset does not match the received bytes",
+			"upload_offset": session.UploadOffset,
+		})
+		return
+	}
+
+	// Claim the session, a concurrent PATCH or one at a stale offset loses
+	now := time.Now()
+	claim := config.DB.Model(&models.UploadSession{}).
+		Where("id = ? AND upload_offset = ? AND (locked_until IS NULL OR locked_until < ?)", session.ID, offset, now).
+		Update("locked_until", now.Add(uploadLockLease))
+	if claim.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update upload"})
+		return
+	}
+	if claim.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload is being written by another request, retry from HEAD"})
+		return
+	}
+
+	// Keep whatever arrived, even if the body ends early
+	ctx := context.WithoutCancel(c.Request.Context())
+	data, readErr := io.ReadAll(io.LimitReader(c.Request.Body, session.UploadLength-offset))
+	if len(data) > 0 {
+		key := fmt.Sprintf("%s/%s/%020d", partsFolder, session.Token, offset)
+		if err := storage.Default().Put(ctx, key, bytes.NewReader(data), int64(len(data)), "application/octet-stream"); err != nil {
+			releaseUpload(session.ID, offset)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store chunk"})
+			return
+		}
+	}
+
+	// The last chunk keeps the session claimed until its image exists, so a second PATCH cannot
+	// finish it again
+	session.UploadOffset = offset + int64(len(data))
+	session.ExpiresAt = time.Now().Add(uploadTTL)
+	finished := session.UploadOffset == session.UploadLength && readErr == nil
+	updates := map[string]interface{}{
+		"upload_offset": session.UploadOffset,
+		"expires_at":    session.ExpiresAt,
+	}
+	if !finished {
+		updates["locked_until"] = nil
+	}
+	if err := config.DB.Model(&session).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update upload"})
+		return
+	}
+	if readErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Chunk was cut short", "upload_offset": session.UploadOffset})
+		return
+	}
+
+	if finished && !finishUpload(c, &session) {
+		releaseUpload(session.ID, session.UploadOffset)
+		return
+	}
+
+	setUploadHeaders(c, session)
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteUpload abandons an unfinished upload and discards its chunks
+func DeleteUpload(c *gin.Context) {
+	if !tusRequest(c) {
+		return
+	}
+	session, ok := findUpload(c)
+	if !ok {
+		return
+	}
+
+	if err := discardUpload(c.Request.Context(), session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete upload"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// finishUpload assembles the chunks, validates the file like a regular upload and creates the image.
+// A file that fails validation can never become valid, so its session is discarded. The image is
+// only created while the session has none, should a PATCH whose claim expired get this far too.
+func finishUpload(c *gin.Context, session *models.UploadSession) bool {
+	ctx := context.WithoutCancel(c.Request.Context())
+
+	var file bytes.Buffer
+	err := storage.Default().List(ctx, partsPrefix(session.Token), func(object storage.Object) error {
+		part, _, err := storage.Default().Get(ctx, object.Key)
+		if err != nil {
+			return err
+		}
+		defer part.Close()
+		_, err = io.Copy(&file, part)
+		return err
+	})
+	if err != nil || int64(file.Len()) != session.UploadLength {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assemble upload"})
+		return false
+	}
+
+	processed, err := media.Process(&file, maxFileSize)
+	if err != nil {
+		discardUpload(ctx, *session)
+		uploadError(c, session.FileName, err)
+		return false
+	}
+
+	// Check again with the stored size, the trip may have filled up while the chunks arrived
+	if !checkStorage(c, session.UserID, int64(len(processed.Data)), session.UploadLength) || !checkTripImages(c, session.TripID, 1) {
+		return false
+	}
+
+	folder := imagesFolder
+	if session.Kind == models.UploadKindCover {
+		folder = coversFolder
+	}
+	recheck := recheckStorage(c, session.UserID)
+	image, err := storeImage(c, folder, processed, session.FileName, session.TripID, session.UserID, func(tx *gorm.DB, image models.Image) error {
+		result := tx.Model(&models.UploadSession{}).Where("id = ? AND image_id IS NULL", session.ID).
+			Updates(map[string]interface{}{"image_id": image.ID, "locked_until": nil})
+		if result.Error == nil && result.RowsAffected == 0 {
+			return errUploadFinished
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		// The upload no longer reserves its length once it has its image
+		return recheck(tx, image)
+	})
+	var quotaErr QuotaError
+	if errors.As(err, &quotaErr) {
+		quotaExceeded(c, quotaErr)
+		return false
+	}
+	if errors.Is(err, errUploadFinished) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Upload is already complete"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
+		return false
+	}
+
+	session.ImageID = &image.ID
+	deleteParts(ctx, session.Token)
+
+	c.Header("Upload-Image-Id", strconv.FormatUint(uint64(image.ID), 10))
+	return true
+}
+
+// tusRequest checks the client speaks our tus version, every response says which one we speak
+func tusRequest(c *gin.Context) bool {
+	c.Header("Tus-Resumable", tusVersion)
+	if version := c.GetHeader("Tus-Resumable"); version != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Unsupported Tus-Resumable version, use " + tusVersion})
+		return false
+	}
+	return true
+}
+
+// findUpload loads the current user's upload from the token parameter, expired uploads are gone
+func findUpload(c *gin.Context) (models.UploadSession, bool) {
+	var session models.UploadSession
+	if err := config.DB.Preload("Image").
+		Where("token = ? AND user_id = ?", c.Param("token"), c.GetUint("userID")).
+		First(&session).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return session, false
+	}
+	if !session.Complete() && session.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusGone, gin.H{"error": "Upload expired"})
+		return session, false
+	}
+	return session, true
+}
+
+func setUploadHeaders(c *gin.Context, session models.UploadSession) {
+	c.Header("Upload-Offset", strconv.FormatInt(session.UploadOffset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(session.UploadLength, 10))
+	if !session.Complete() {
+		c.Header("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// releaseUpload drops the claim of a PATCH that stored nothing
+func releaseUpload(id uint, offset int64) {
+	config.DB.Model(&models.UploadSession{}).
+		Where("id = ? AND upload_offset = ?", id, offset).
+		Update("locked_until", nil)
+}
+
+// parseUploadMetadata decodes Upload-Metadata: comma separated keys, each followed by a space and
+// its base64 value, or alone for an empty value
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("value of %s is not base64", key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+func partsPrefix(token string) string {
+	return partsFolder + "/" + token + "/"
+}
+
+// deleteParts removes the stored chunks of an upload
+func deleteParts(ctx context.Context, token string) error {
+	var keys []string
+	if err := storage.Default().List(ctx, partsPrefix(token), func(object storage.Object) error {
+		keys = append(keys, object.Key)
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := storage.Default().Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// discardUpload deletes an upload's chunks and its session
+func discardUpload(ctx context.Context, session models.UploadSession) error {
+	if err := deleteParts(ctx, session.Token); err != nil {
+		return err
+	}
+	return config.DB.Unscoped().Delete(&session).Error
+}
+
+// ExpireUploads discards abandoned uploads and forgets completed ones once their session expires,
+// it runs hourly in the background
+func ExpireUploads() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		var sessions []models.UploadSession
+		if err := config.DB.Where("expires_at < ?", time.Now()).Find(&sessions).Error; err != nil {
+			log.Printf("Failed to find expired uploads: %v", err)
+			continue
+		}
+		for _, session := range sessions {
+			if err := discardUpload(context.Background(), session); err != nil {
+				log.Printf("Failed to discard expired upload %s: %v", session.Token, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package image
+
+import (
+	"backend-go/config"
+	"backend-go/models"
+	"backend-go/storage"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+)
+
+// immutable is sent with every served image. Stored names are unique per upload and a name never
+// gets new content, so clients and CDNs may keep a copy for a year without revalidating.
+const immutable = "public, max-age=31536000, immutable"
+
+// GetImage serves regular images and GetCoverImage cover images, ?size=thumb|medium|large or ?w=
+// serve a resized variant
+var (
+	GetImage      = serve(imagesFolder, "Image not found")
+	GetCoverImage = serve(coversFolder, "Cover image not found")
+)
+
+// serve returns the handler for the images stored in folder. Responses carry a strong ETag of the
+// content's SHA-256 and Last-Modified, and support conditional requests (304) and byte ranges.
+func serve(folder, notFoundMessage string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filename := c.Param("filename")
+		if filename == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Filename is required"})
+			return
+		}
+
+		ctx := c.Request.Context()
+		key := path.Join(folder, filename)
+
+		// The image record names the blob holding its file, files without a record are served as stored
+		fileKey := key
+		var image models.Image
+		if err := config.DB.Where("storage_key = ?", key).First(&image).Error; err == nil {
+			if fileKey, err = models.FileKey(config.DB, image); err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": notFoundMessage})
+				return
+			}
+		}
+
+		// Check if file exists
+		object, err := storage.Default().Stat(ctx, fileKey)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": notFoundMessage})
+			return
+		}
+
+		// Content type and hash come from the image record, files without one are hashed on the fly
+		if image.ID != 0 {
+			object.ContentType = image.MimeType
+		}
+		hash := image.ContentHash
+		if hash == "" {
+			if hash, err = contentHash(ctx, fileKey); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open image"})
+				return
+			}
+			if image.ID != 0 {
+				config.DB.Model(&image).UpdateColumn("content_hash", hash)
+			}
+		}
+
+		// Serve a resized variant when size or w is given
+		if serveVariant(c, fileKey, hash) {
+			return
+		}
+
+		sendObject(c, object, `"`+hash+`"`)
+	}
+}
+
+// sendObject answers with a stored object, letting http.ServeContent handle If-None-Match,
+// If-Modified-Since, Range and If-Range
+func sendObject(c *gin.Context, object storage.Object, etag string) {
+	reader := storage.NewReader(c.Request.Context(), storage.Default(), object)
+	defer reader.Close()
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", immutable)
+	c.Header("Content-Type", object.ContentType)
+	http.ServeContent(c.Writer, c.Request, object.Key, object.ModTime, reader)
+}
+
+// contentHash computes the hex SHA-256 of a stored object
+func contentHash(ctx context.Context, key string) (string, error) {
+	file, _, err := storage.Default().Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,191 @@
+package image
+
+import (
+	"backend-go/config"
+	"backend-go/media"
+	"backend-go/models"
+	"backend-go/storage"
+	"backend-go/utils"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Storage folders of regular and cover images
+const (
+	imagesFolder = "images"
+	coversFolder = "covers"
+)
+
+// Upload limits in bytes, UPLOAD_MAX_FILE_MB and UPLOAD_MAX_REQUEST_MB override the defaults
+var (
+	maxFileSize    = megabytesFromEnv("UPLOAD_MAX_FILE_MB", 10)
+	maxRequestSize = megabytesFromEnv("UPLOAD_MAX_REQUEST_MB", 50)
+)
+
+// megabytesFromEnv reads a size in megabytes from the environment
+func megabytesFromEnv(key string, fallback int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if megabytes, err := strconv.ParseInt(value, 10, 64); err == nil && megabytes > 0 {
+			return megabytes << 20
+		}
+		log.Printf("Warning: ignoring invalid %s=%q", key, value)
+	}
+	return fallback << 20
+}
+
+// limitRequest caps the size of the request body, it must run before the multipart form is parsed
+func limitRequest(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxRequestSize)
+}
+
+// formError responds to a multipart form that could not be parsed
+func formError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":     "Request too large",
+			"max_bytes": maxRequestSize,
+		})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form"})
+}
+
+// processUpload validates an uploaded file from its content and strips its metadata
+func processUpload(file *multipart.FileHeader) (media.Processed, error) {
+	if file.Size > maxFileSize {
+		return media.Processed{}, media.ErrTooLarge
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return media.Processed{}, err
+	}
+	defer src.Close()
+
+	return media.Process(src, maxFileSize)
+}
+
+// uploadError responds to a file that failed processUpload
+func uploadError(c *gin.Context, filename string, err error) {
+	switch {
+	case errors.Is(err, media.ErrTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":     fmt.Sprintf("%s is too large", filename),
+			"max_bytes": maxFileSize,
+		})
+	case errors.Is(err, media.ErrUnsupportedType), errors.Is(err, media.ErrInvalidImage):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   fmt.Sprintf("Invalid file type for %s", filename),
+			"details": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file"})
+	}
+}
+
+// storeImage creates the image record of a processed upload under a unique public name in folder,
+// with URLs resolved for the response. The file itself is stored once per content as a blob, an
+// upload of content already stored only takes a reference to it. tripID must already be checked to
+// belong to the uploader. within, when set, runs in the transaction creating the image and rolls it
+// back by returning an error.
+func storeImage(c *gin.Context, folder string, processed media.Processed, originalName string, tripID *uint, uploadedBy uint, within func(tx *gorm.DB, image models.Image) error) (models.Image, error) {
+	// Generate unique filename, the extension comes from the detected type
+	prefix := "image"
+	if folder == coversFolder {
+		prefix = "cover"
+	}
+	fileName := fmt.Sprintf("%s_%s_%s%s",
+		prefix,
+		time.Now().Format("20060102_150405"),
+		uuid.New().String(),
+		processed.Ext)
+	key := path.Join(folder, fileName)
+	fingerprint := int64(processed.Fingerprint)
+
+	// Create image record
+	image := models.Image{
+		TripID:       tripID,
+		StorageKey:   key,
+		FileName:     fileName,
+		OriginalName: originalName,
+		FileSize:     int64(len(processed.Data)),
+		MimeType:     processed.MimeType,
+		ContentHash:  processed.SHA256,
+		Fingerprint:  &fingerprint,
+		Width:        processed.Width,
+		Height:       processed.Height,
+		UploadedBy:   &uploadedBy,
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		blob, err := models.AcquireBlob(tx, processed.SHA256, processed.Ext, image.FileSize, processed.MimeType)
+		if err != nil {
+			return err
+		}
+		// The first reference stores the file. A file left behind by a rollback is collected by
+		// images-gc, deleting it here could remove the file of a concurrent upload of the same content.
+		if blob.RefCount == 1 {
+			if err := storage.Default().Put(c.Request.Context(), blob.StorageKey, bytes.NewReader(processed.Data), image.FileSize, processed.MimeType); err != nil {
+				return err
+			}
+		}
+		image.BlobID = &blob.ID
+
+		// New images go to the end of the trip's gallery, a cover upload becomes the trip's cover
+		if tripID != nil {
+			position, err := models.NextImagePosition(tx, *tripID)
+			if err != nil {
+				return err
+			}
+			image.Position = position
+		}
+		if err := tx.Create(&image).Error; err != nil {
+			return err
+		}
+		if within != nil {
+			if err := within(tx, image); err != nil {
+				return err
+			}
+		}
+		if tripID != nil && folder == coversFolder {
+			return tx.Model(&models.Trip{}).Where("id = ?", *tripID).
+				Updates(map[string]interface{}{"cover_image_id": image.ID, "cover_image": media.RoutePath(key)}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Image{}, err
+	}
+
+	image.ResolveURLs(utils.GetBaseURL(c))
+	return image, nil
+}
+
+// releaseBlob drops an image's reference to its file, inside the transaction deleting the image. The
+// last reference deletes the file and its variants before the transaction commits, a failed delete is
+// logged and left for images-gc.
+func releaseBlob(ctx context.Context, tx *gorm.DB, image models.Image) error {
+	keys, err := models.ReleaseBlob(tx, image)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := storage.Default().Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete %s, left for images-gc: %v", key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package image
+
+import (
+	"backend-go/media"
+	"backend-go/storage"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/singleflight"
+)
+
+// variantGroup makes concurrent requests for the same missing variant render it once
+var variantGroup singleflight.Group
+
+// requestedVariant reads the size or w query parameter, it returns nil for the original
+func requestedVariant(c *gin.Context) (*media.Variant, bool) {
+	if name := c.Query("size"); name != "" && name != "original" {
+		variant, exists := media.LookupVariant(name)
+		if !exists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid size, use thumb, medium, large or original"})
+			return nil, false
+		}
+		return &variant, true
+	}
+
+	if raw := c.Query("w"); raw != "" {
+		width, err := strconv.Atoi(raw)
+		if err != nil || width < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid width"})
+			return nil, false
+		}
+		variant := media.VariantForWidth(width)
+		return &variant, true
+	}
+
+	return nil, true
+}
+
+// serveVariant serves the requested variant of the stored file source, rendering and caching it on
+// first use. Variants are rendered deterministically, so their ETag derives from the original's hash.
+// It reports whether it wrote the response; without size or w the caller serves the original.
+func serveVariant(c *gin.Context, source, hash string) bool {
+	variant, ok := requestedVariant(c)
+	if !ok {
+		return true
+	}
+	if variant == nil {
+		return false
+	}
+
+	ctx := c.Request.Context()
+	etag := `"` + hash + "-" + variant.Name + `"`
+	cached := media.VariantKeys(source, *variant)
+	for _, key := range cached {
+		if object, err := storage.Default().Stat(ctx, key); err == nil {
+			sendObject(c, object, etag)
+			return true
+		}
+	}
+
+	key, err, _ := variantGroup.Do(cached[0], func() (interface{}, error) {
+		return renderVariant(context.WithoutCancel(ctx), source, *variant)
+	})
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Failed to resize image"})
+		return true
+	}
+
+	object, err := storage.Default().Stat(ctx, key.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open image"})
+		return true
+	}
+	sendObject(c, object, etag)
+	return true
+}
+
+// renderVariant resizes the source object into the variant cache and returns the cached key
+func renderVariant(ctx context.Context, source string, variant media.Variant) (string, error) {
+	file, _, err := storage.Default().Get(ctx, source)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	data, mimeType, err := media.Resize(file, variant.Width)
+	if err != nil {
+		return "", err
+	}
+
+	key := media.VariantKey(source, variant, media.Extension(mimeType))
+	if err := storage.Default().Put(ctx, key, bytes.NewReader(data), int64(len(data)), mimeType); err != nil {
+		return "", fmt.Errorf("store variant: %w", err)
+	}
+	return key, nil
+}
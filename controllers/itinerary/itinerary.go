@@ -0,0 +1,343 @@
+package itinerary
+
+import (
+	"backend-go/config"
+	"backend-go/controllers/shared"
+	"backend-go/ical"
+	"backend-go/models"
+	"backend-go/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ItineraryRequest represents the request structure for creating or renaming an itinerary
+type ItineraryRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description"`
+}
+
+// AddItemRequest represents the request structure for adding a trip to an itinerary,
+// either from a booking, a departure occurrence, or a trip and a start time
+type AddItemRequest struct {
+	BookingID   uint      `json:"booking_id"`
+	TripID      uint      `json:"trip_id"`
+	DepartureID uint      `json:"departure_id"`
+	StartsAt    time.Time `json:"starts_at"`
+	Note        string    `json:"note"`
+}
+
+// GetMine lists the current user's itineraries
+func GetMine(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to view your itineraries",
+		})
+		return
+	}
+
+	var itineraries []models.Itinerary
+	if err := config.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&itineraries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve itineraries",
+			"message": "Could not fetch itineraries from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Itineraries retrieved successfully",
+		"data":    itineraries,
+		"count":   len(itineraries),
+	})
+}
+
+// GetByID retrieves an itinerary in time order with travel legs and schedule overlaps
+func GetByID(c *gin.Context) {
+	itinerary, ok := findOwnedItinerary(c, true)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Itinerary retrieved successfully",
+		"data":    itinerary.Plan(),
+	})
+}
+
+// Create creates an itinerary for the current user
+func Create(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to create an itinerary",
+		})
+		return
+	}
+
+	var req ItineraryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	itinerary := models.Itinerary{UserID: userID.(uint), Name: req.Name, Description: req.Description}
+	if err := config.DB.Create(&itinerary).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create itinerary",
+			"message": "Could not save itinerary to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Itinerary created successfully",
+		"data":    itinerary,
+	})
+}
+
+// Update renames an itinerary (only its owner)
+func Update(c *gin.Context) {
+	itinerary, ok := findOwnedItinerary(c, false)
+	if !ok {
+		return
+	}
+
+	var req ItineraryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	itinerary.Name = req.Name
+	itinerary.Description = req.Description
+	if err := config.DB.Save(&itinerary).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update itinerary",
+			"message": "Could not save changes to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Itinerary updated successfully",
+		"data":    itinerary,
+	})
+}
+
+// Delete removes an itinerary and its items (only its owner)
+func Delete(c *gin.Context) {
+	itinerary, ok := findOwnedItinerary(c, false)
+	if !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("itinerary_id = ?", itinerary.ID).Delete(&models.ItineraryItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&itinerary).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete itinerary",
+			"message": "Could not remove itinerary from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Itinerary deleted successfully",
+	})
+}
+
+// AddItem schedules a trip in an itinerary (only its owner). With booking_id the
+// booking's trip and time are used, with departure_id the time must be one of its
+// occurrences, otherwise trip_id and starts_at plan a trip at any time.
+func AddItem(c *gin.Context) {
+	itinerary, ok := findOwnedItinerary(c, false)
+	if !ok {
+		return
+	}
+
+	var req AddItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	item := models.ItineraryItem{ItineraryID: itinerary.ID, Note: req.Note}
+	switch {
+	case req.BookingID != 0:
+		var booking models.Booking
+		if err := config.DB.Where("user_id = ?", itinerary.UserID).First(&booking, req.BookingID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Booking not found",
+				"message": "The requested booking does not exist",
+			})
+			return
+		}
+		item.TripID = booking.TripID
+		item.DepartureID = &booking.DepartureID
+		item.BookingID = &booking.ID
+		item.StartsAt = booking.StartsAt
+
+	case req.DepartureID != 0:
+		var departure models.Departure
+		if err := config.DB.Preload("Blackouts").First(&departure, req.DepartureID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Departure not found",
+				"message": "The requested departure does not exist",
+			})
+			return
+		}
+		startsAt := req.StartsAt.UTC()
+		if runs, err := departure.HasOccurrence(startsAt); err != nil || !runs {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid start time",
+				"message": "the departure does not run at the requested time",
+			})
+			return
+		}
+		item.TripID = departure.TripID
+		item.DepartureID = &departure.ID
+		item.StartsAt = startsAt
+
+	case req.TripID != 0 && !req.StartsAt.IsZero():
+		item.TripID = req.TripID
+		item.StartsAt = req.StartsAt.UTC()
+
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"message": "Provide booking_id, departure_id and starts_at, or trip_id and starts_at",
+		})
+		return
+	}
+
+	var trip models.Trip
+	if err := config.DB.First(&trip, item.TripID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Trip not found",
+			"message": "The requested trip does not exist",
+		})
+		return
+	}
+
+	if err := config.DB.Create(&item).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to add trip",
+			"message": "Could not save itinerary item to database",
+		})
+		return
+	}
+
+	// Respond with the whole plan so the client sees any new overlap right away
+	itinerary, ok = findOwnedItinerary(c, true)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Trip added to itinerary",
+		"data":    itinerary.Plan(),
+	})
+}
+
+// RemoveItem removes an item from an itinerary (only its owner)
+func RemoveItem(c *gin.Context) {
+	itinerary, ok := findOwnedItinerary(c, false)
+	if !ok {
+		return
+	}
+
+	result := config.DB.Where("itinerary_id = ?", itinerary.ID).Delete(&models.ItineraryItem{}, c.Param("itemId"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to remove trip",
+			"message": "Could not remove itinerary item from database",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Item not found",
+			"message": "The requested item is not in this itinerary",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Trip removed from itinerary",
+	})
+}
+
+// ExportICS downloads an itinerary as an iCalendar file
+func ExportICS(c *gin.Context) {
+	itinerary, ok := findOwnedItinerary(c, true)
+	if !ok {
+		return
+	}
+
+	baseURL := utils.GetBaseURL(c)
+	calendar := ical.Calendar{Name: itinerary.Name}
+	for _, item := range itinerary.Plan().Items {
+		uid := fmt.Sprintf("itinerary-item-%d@%s", item.ID, c.Request.Host)
+		event := ical.TripEvent(uid, item.Trip, item.StartsAt, fmt.Sprintf("%s/api/v1/trips/%d", baseURL, item.TripID))
+		if item.Note != "" {
+			event.Description = item.Note + "\n\n" + event.Description
+		}
+		event.Status = "TENTATIVE"
+		if item.BookingID != nil {
+			event.Status = "CONFIRMED"
+		}
+		calendar.Events = append(calendar.Events, event)
+	}
+
+	shared.WriteCalendar(c, calendar, fmt.Sprintf("itinerary-%d.ics", itinerary.ID))
+}
+
+// findOwnedItinerary loads the itinerary from the id parameter, with its items and their
+// trips when withItems is set, and verifies the current user owns it
+func findOwnedItinerary(c *gin.Context, withItems bool) (models.Itinerary, bool) {
+	query := config.DB
+	if withItems {
+		// Deleted trips stay visible in plans made before they were removed
+		query = query.Preload("Items").Preload("Items.Trip", func(db *gorm.DB) *gorm.DB { return db.Unscoped() })
+	}
+
+	var itinerary models.Itinerary
+	if err := query.First(&itinerary, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Itinerary not found",
+			"message": "The requested itinerary does not exist",
+		})
+		return itinerary, false
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists || itinerary.UserID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"message": "You can only manage your own itineraries",
+		})
+		return itinerary, false
+	}
+
+	return itinerary, true
+}
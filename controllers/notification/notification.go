@@ -0,0 +1,374 @@
+package notification
+
+import (
+	"backend-go/config"
+	"backend-go/models"
+	"backend-go/notify"
+	"backend-go/utils"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PreferenceRequest represents the request structure for turning channels on or off per event
+type PreferenceRequest struct {
+	Preferences []struct {
+		Event   string `json:"event" binding:"required"`
+		Channel string `json:"channel" binding:"required"`
+		Enabled *bool  `json:"enabled" binding:"required"`
+	} `json:"preferences" binding:"required,dive"`
+}
+
+// SubscriptionRequest represents a browser's PushSubscription.toJSON()
+type SubscriptionRequest struct {
+	Endpoint string `json:"endpoint" binding:"required,url"`
+	Keys     struct {
+		P256dh string `json:"p256dh" binding:"required"`
+		Auth   string `json:"auth" binding:"required"`
+	} `json:"keys" binding:"required"`
+}
+
+// GetMine lists the current user's notifications, newest first. unread=true keeps unread ones.
+func GetMine(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to view your notifications",
+		})
+		return
+	}
+
+	query := config.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+
+	var unread int64
+	if err := query.Session(&gorm.Session{}).Where("read_at IS NULL").Count(&unread).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve notifications",
+			"message": "Could not fetch notifications from database",
+		})
+		return
+	}
+
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve notifications",
+			"message": "Could not fetch notifications from database",
+		})
+		return
+	}
+
+	page := utils.GetPage(c)
+	var notifications []models.Notification
+	if err := page.Apply(query.Order("created_at DESC, id DESC")).Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve notifications",
+			"message": "Could not fetch notifications from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Notifications retrieved successfully",
+		"data":         notifications,
+		"count":        len(notifications),
+		"total":        total,
+		"unread_count": unread,
+		"pagination":   page,
+	})
+}
+
+// MarkRead marks one of the current user's notifications as read
+func MarkRead(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to manage your notifications",
+		})
+		return
+	}
+
+	var notification models.Notification
+	if err := config.DB.Where("user_id = ?", userID).First(&notification, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Notification not found",
+			"message": "The requested notification does not exist",
+		})
+		return
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		notification.ReadAt = &now
+		if err := config.DB.Model(&notification).Update("read_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to update notification",
+				"message": "Could not save changes to database",
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Notification marked as read",
+		"data":    notification,
+	})
+}
+
+// MarkAllRead marks every unread notification of the current user as read
+func MarkAllRead(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to manage your notifications",
+		})
+		return
+	}
+
+	result := config.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update notifications",
+			"message": "Could not save changes to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Notifications marked as read",
+		"data":    gin.H{"marked": result.RowsAffected},
+	})
+}
+
+// GetPreferences lists every event with whether each configured channel is on for the current user
+func GetPreferences(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to view your notification preferences",
+		})
+		return
+	}
+
+	preferences, err := loadPreferences(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve notification preferences",
+			"message": "Could not fetch preferences from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Notification preferences retrieved successfully",
+		"data":    preferences,
+	})
+}
+
+// UpdatePreferences turns channels on or off per event, events and channels not listed are kept
+func UpdatePreferences(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to update your notification preferences",
+		})
+		return
+	}
+
+	var req PreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	channels := notify.Channels()
+	rows := make([]models.NotificationPreference, 0, len(req.Preferences))
+	for _, preference := range req.Preferences {
+		if !notify.IsEvent(preference.Event) || !slices.Contains(channels, preference.Channel) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid preference",
+				"message": "Unknown event " + preference.Event + " or channel " + preference.Channel,
+			})
+			return
+		}
+		rows = append(rows, models.NotificationPreference{
+			UserID:  userID.(uint),
+			Event:   preference.Event,
+			Channel: preference.Channel,
+			Enabled: *preference.Enabled,
+		})
+	}
+
+	if len(rows) > 0 {
+		if err := config.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "event"}, {Name: "channel"}},
+			DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+		}).Create(&rows).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to update notification preferences",
+				"message": "Could not save preferences to database",
+			})
+			return
+		}
+	}
+
+	preferences, err := loadPreferences(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve notification preferences",
+			"message": "Could not fetch preferences from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Notification preferences updated successfully",
+		"data":    preferences,
+	})
+}
+
+// GetPushKey returns the VAPID public key browsers subscribe to Web Push with
+func GetPushKey(c *gin.Context) {
+	channel, exists := notify.Lookup("webpush")
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Web Push not configured",
+			"message": "Push notifications are not enabled on this server",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Push key retrieved successfully",
+		"data":    gin.H{"public_key": channel.(*notify.WebPushChannel).PublicKey()},
+	})
+}
+
+// Subscribe registers the browser's Web Push subscription for the current user, a browser that
+// was subscribed by another account moves to this one
+func Subscribe(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to subscribe to push notifications",
+		})
+		return
+	}
+
+	if _, exists := notify.Lookup("webpush"); !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Web Push not configured",
+			"message": "Push notifications are not enabled on this server",
+		})
+		return
+	}
+
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	subscription := models.PushSubscription{
+		UserID:   userID.(uint),
+		Endpoint: req.Endpoint,
+		P256dh:   req.Keys.P256dh,
+		Auth:     req.Keys.Auth,
+	}
+	if err := config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "endpoint"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "p256dh", "auth", "updated_at"}),
+	}).Create(&subscription).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to subscribe",
+			"message": "Could not save push subscription to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Subscribed to push notifications",
+		"data":    subscription,
+	})
+}
+
+// Unsubscribe removes one of the current user's Web Push subscriptions
+func Unsubscribe(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to unsubscribe from push notifications",
+		})
+		return
+	}
+
+	result := config.DB.Unscoped().Where("user_id = ?", userID).Delete(&models.PushSubscription{}, c.Param("id"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to unsubscribe",
+			"message": "Could not remove push subscription from database",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Subscription not found",
+			"message": "The requested push subscription does not exist",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Unsubscribed from push notifications",
+	})
+}
+
+// loadPreferences builds the event by channel matrix of a user's preferences, channels default to on
+func loadPreferences(userID uint) (gin.H, error) {
+	var stored []models.NotificationPreference
+	if err := config.DB.Where("user_id = ?", userID).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	channels := notify.Channels()
+	events := make([]gin.H, len(notify.Events))
+	for i, info := range notify.Events {
+		enabled := make(map[string]bool, len(channels))
+		for _, channel := range channels {
+			enabled[channel] = true
+		}
+		for _, preference := range stored {
+			if preference.Event == info.Event {
+				if _, configured := enabled[preference.Channel]; configured {
+					enabled[preference.Channel] = preference.Enabled
+				}
+			}
+		}
+		events[i] = gin.H{"event": info.Event, "description": info.Description, "channels": enabled}
+	}
+
+	return gin.H{"channels": channels, "events": events}, nil
+}
@@ -0,0 +1,271 @@
+package payment
+
+import (
+	"backend-go/config"
+	"backend-go/models"
+	"backend-go/payments"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxWebhookBody caps the size of webhook payloads read into memory
+const maxWebhookBody = 1 << 20
+
+var errAccessDenied = errors.New("access denied")
+
+// RefundRequest represents the request structure for refunding a payment
+type RefundRequest struct {
+	Amount *int64 `json:"amount" binding:"omitempty,min=1"` // Minor units, defaults to everything refundable
+	Reason string `json:"reason"`
+}
+
+// Create starts a payment for a pending booking (only the visitor who booked)
+func Create(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to pay for a booking",
+		})
+		return
+	}
+
+	var booking models.Booking
+	if err := config.DB.First(&booking, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Booking not found",
+			"message": "The requested booking does not exist",
+		})
+		return
+	}
+
+	if booking.UserID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"message": "You can only pay for your own bookings",
+		})
+		return
+	}
+
+	if booking.Status != models.BookingPending || booking.Amount == 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Booking not payable",
+			"message": "Only pending bookings with an amount due can be paid",
+		})
+		return
+	}
+
+	if booking.HoldExpired(time.Now()) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Booking expired",
+			"message": "The booking was not paid in time and its seats have been released",
+		})
+		return
+	}
+
+	var inFlight int64
+	config.DB.Model(&models.Payment{}).
+		Where("booking_id = ? AND status IN ?", booking.ID, []string{models.PaymentAuthorized, models.PaymentCaptured}).
+		Count(&inFlight)
+	if inFlight > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Payment in progress",
+			"message": "This booking already has a successful payment",
+		})
+		return
+	}
+
+	payment, clientSecret, err := payments.Start(c.Request.Context(), booking)
+	if err != nil {
+		log.Printf("Failed to start payment for booking %d: %v", booking.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Failed to start payment",
+			"message": "The payment provider could not create the payment",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":       "Payment started successfully",
+		"data":          payment,
+		"client_secret": clientSecret,
+	})
+}
+
+// GetByBooking lists the payments of a booking (the visitor who booked or the trip owner)
+func GetByBooking(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to view payments",
+		})
+		return
+	}
+
+	var booking models.Booking
+	if err := config.DB.Preload("Trip").First(&booking, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Booking not found",
+			"message": "The requested booking does not exist",
+		})
+		return
+	}
+
+	if booking.UserID != userID.(uint) && booking.Trip.UserID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"message": "You can only view payments of your own bookings or bookings on your trips",
+		})
+		return
+	}
+
+	var list []models.Payment
+	if err := config.DB.Preload("Refunds").Where("booking_id = ?", booking.ID).Order("created_at").Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve payments",
+			"message": "Could not fetch payments from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payments retrieved successfully",
+		"data":    list,
+		"count":   len(list),
+	})
+}
+
+// Refund refunds a captured payment, fully or partially (only the trip owner)
+func Refund(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to refund a payment",
+		})
+		return
+	}
+
+	var req RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var payment models.Payment
+	var refund models.PaymentRefund
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, c.Param("id")).Error; err != nil {
+			return err
+		}
+
+		var booking models.Booking
+		if err := tx.Preload("Trip", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).First(&booking, payment.BookingID).Error; err != nil {
+			return err
+		}
+		if booking.Trip.UserID != userID.(uint) {
+			return errAccessDenied
+		}
+
+		amount := payment.Refundable()
+		if req.Amount != nil {
+			amount = *req.Amount
+		}
+
+		var err error
+		refund, err = payments.ReserveRefund(tx, &payment, amount, req.Reason)
+		return err
+	})
+	if err == nil {
+		// The gateway is only called once the refund is recorded
+		err = payments.IssueRefund(c.Request.Context(), &refund)
+	}
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Payment not found",
+			"message": "The requested payment does not exist",
+		})
+		return
+	case errors.Is(err, errAccessDenied):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"message": "You can only refund payments on your own trips",
+		})
+		return
+	case errors.Is(err, payments.ErrNotRefundable):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Invalid refund amount",
+			"message": "The amount exceeds what is left to refund on this payment",
+		})
+		return
+	case err != nil && refund.Status == models.RefundPending:
+		// Recorded but not confirmed by the gateway, the refund is retried in the background
+		log.Printf("Refund %d of payment %s is pending: %v", refund.ID, c.Param("id"), err)
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "Refund is pending, it will be retried until the payment provider confirms it",
+			"data":    refund,
+		})
+		return
+	case err != nil:
+		log.Printf("Failed to refund payment %s: %v", c.Param("id"), err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Failed to refund payment",
+			"message": "The payment provider could not process the refund",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payment refunded successfully",
+		"data":    refund,
+	})
+}
+
+// Webhook receives payment events from a gateway. Deliveries are verified by
+// signature and processed at most once, retries of processed events are acknowledged.
+func Webhook(c *gin.Context) {
+	gateway, exists := payments.Lookup(c.Param("gateway"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown payment gateway"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read webhook body"})
+		return
+	}
+
+	err = payments.HandleWebhook(c.Request.Context(), gateway, c.Request.Header, body)
+	switch {
+	case errors.Is(err, payments.ErrInvalidSignature):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook signature"})
+		return
+	case errors.Is(err, payments.ErrInvalidPayload):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook payload"})
+		return
+	case errors.Is(err, payments.ErrDuplicateEvent):
+		c.JSON(http.StatusOK, gin.H{"message": "Event already processed"})
+		return
+	case err != nil:
+		// A non-2xx response makes the gateway retry the delivery later
+		log.Printf("Failed to process %s webhook: %v", gateway.Name(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Event processed"})
+}
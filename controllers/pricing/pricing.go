@@ -0,0 +1,207 @@
+package pricing
+
+import (
+	"backend-go/config"
+	"backend-go/controllers/shared"
+	"backend-go/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReplaceRulesRequest represents the request structure for replacing a trip's pricing rules
+type ReplaceRulesRequest struct {
+	Rules []models.PricingRule `json:"rules" binding:"dive"`
+}
+
+// QuoteRequest represents the request structure for pricing a departure occurrence
+type QuoteRequest struct {
+	DepartureID  uint                        `json:"departure_id" binding:"required"`
+	StartsAt     time.Time                   `json:"starts_at" binding:"required"`
+	Participants models.ParticipantBreakdown `json:"participants" binding:"required"`
+}
+
+// GetRules lists the pricing rules of a trip (public)
+func GetRules(c *gin.Context) {
+	var trip models.Trip
+	if err := config.DB.First(&trip, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Trip not found",
+			"message": "The requested trip does not exist",
+		})
+		return
+	}
+
+	var rules []models.PricingRule
+	if err := config.DB.Where("trip_id = ?", trip.ID).Order("id").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve pricing rules",
+			"message": "Could not fetch pricing rules from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Pricing rules retrieved successfully",
+		"data":     rules,
+		"count":    len(rules),
+		"currency": trip.Currency,
+	})
+}
+
+// ReplaceRules replaces all pricing rules of a trip (only the trip owner).
+// Existing bookings keep the quote they were made against.
+func ReplaceRules(c *gin.Context) {
+	trip, ok := shared.FindOwnedTrip(c, c.Param("id"), "pricing")
+	if !ok {
+		return
+	}
+
+	var req ReplaceRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	tiers := make(map[string]bool)
+	for i := range req.Rules {
+		rule := &req.Rules[i]
+		if err := rule.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid pricing rule",
+				"message": err.Error(),
+			})
+			return
+		}
+		if rule.Kind == models.PricingTier {
+			if tiers[rule.Tier] {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Invalid pricing rule",
+					"message": "Each tier can only be priced once",
+				})
+				return
+			}
+			tiers[rule.Tier] = true
+		}
+		rule.ID = 0
+		rule.TripID = trip.ID
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("trip_id = ?", trip.ID).Delete(&models.PricingRule{}).Error; err != nil {
+			return err
+		}
+		if len(req.Rules) == 0 {
+			return nil
+		}
+		return tx.Create(&req.Rules).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update pricing rules",
+			"message": "Could not save pricing rules to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pricing rules updated successfully",
+		"data":    req.Rules,
+		"count":   len(req.Rules),
+	})
+}
+
+// Quote prices a participant breakdown on one occurrence of a departure. For logged in users the
+// quote is stored and its ID can be passed as quote_id when booking to pay exactly the quoted total,
+// anonymous callers only get the prices.
+func Quote(c *gin.Context) {
+	var req QuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var trip models.Trip
+	if err := config.DB.First(&trip, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Trip not found",
+			"message": "The requested trip does not exist",
+		})
+		return
+	}
+
+	var departure models.Departure
+	if err := config.DB.Preload("Blackouts").Where("trip_id = ?", trip.ID).First(&departure, req.DepartureID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Departure not found",
+			"message": "The requested departure does not exist on this trip",
+		})
+		return
+	}
+
+	now := time.Now()
+	startsAt := req.StartsAt.UTC()
+	if runs, err := departure.HasOccurrence(startsAt); err != nil || !runs {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Departure not bookable",
+			"message": "the departure does not run at the requested time",
+		})
+		return
+	}
+	if !now.Before(departure.BookingClosesAt(startsAt)) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Departure not bookable",
+			"message": "booking for this departure has closed",
+		})
+		return
+	}
+
+	var rules []models.PricingRule
+	if err := config.DB.Where("trip_id = ?", trip.ID).Order("id").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to compute quote",
+			"message": "Could not fetch pricing rules from database",
+		})
+		return
+	}
+
+	quote, err := models.NewQuote(trip, rules, departure, startsAt, req.Participants, now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid participants",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Quote computed successfully",
+			"data":    quote,
+		})
+		return
+	}
+
+	quote.UserID = userID.(uint)
+	if err := config.DB.Create(&quote).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to compute quote",
+			"message": "Could not save quote to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Quote computed successfully",
+		"data":    quote,
+	})
+}
@@ -0,0 +1,404 @@
+package review
+
+import (
+	"backend-go/config"
+	"backend-go/models"
+	"backend-go/notify"
+	"backend-go/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// errOwnReview is returned when a user votes on their own review
+	errOwnReview = errors.New("you cannot vote on your own review")
+
+	// errReviewExists is returned when the user already reviewed the trip
+	errReviewExists = errors.New("review already exists")
+)
+
+// ReviewRequest represents the request structure for writing or editing a review
+type ReviewRequest struct {
+	Rating int    `json:"rating" binding:"required,min=1,max=5"`
+	Body   string `json:"body" binding:"max=5000"`
+}
+
+// ReplyRequest represents the request structure for the trip owner's reply
+type ReplyRequest struct {
+	Reply string `json:"reply" binding:"required,max=5000"`
+}
+
+// reviewOrders maps the sort parameter to the ORDER BY of review listings
+var reviewOrders = map[string]string{
+	"recent":  "created_at DESC",
+	"helpful": "helpful_count DESC, created_at DESC",
+	"highest": "rating DESC, created_at DESC",
+	"lowest":  "rating ASC, created_at DESC",
+}
+
+// GetByTrip lists a trip's reviews with pagination, sort is recent (default), helpful, highest or lowest
+func GetByTrip(c *gin.Context) {
+	var trip models.Trip
+	if err := config.DB.First(&trip, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Trip not found",
+			"message": "The requested trip does not exist",
+		})
+		return
+	}
+
+	order, exists := reviewOrders[c.DefaultQuery("sort", "recent")]
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid sort",
+			"message": "sort must be one of recent, helpful, highest or lowest",
+		})
+		return
+	}
+
+	page := utils.GetPage(c)
+	query := config.DB.Model(&models.Review{}).Where("trip_id = ?", trip.ID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve reviews",
+			"message": "Could not fetch reviews from database",
+		})
+		return
+	}
+
+	var reviews []models.Review
+	if err := page.Apply(query.Preload("User").Order(order)).Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve reviews",
+			"message": "Could not fetch reviews from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Reviews retrieved successfully",
+		"data":           reviews,
+		"count":          len(reviews),
+		"total":          total,
+		"page":           page.Number,
+		"per_page":       page.Size,
+		"rating_average": trip.RatingAverage,
+		"review_count":   trip.ReviewCount,
+	})
+}
+
+// Create reviews a trip (visitors only), the visitor needs a completed booking
+// or a confirmed booking whose departure has started, and reviews a trip once
+func Create(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to review a trip",
+		})
+		return
+	}
+
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var trip models.Trip
+	if err := config.DB.First(&trip, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Trip not found",
+			"message": "The requested trip does not exist",
+		})
+		return
+	}
+
+	booking, err := models.ReviewEligibleBooking(config.DB, trip.ID, userID.(uint), time.Now())
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Review not allowed",
+			"message": "You can only review trips you have taken",
+		})
+		return
+	}
+
+	review := models.Review{
+		TripID:    trip.ID,
+		UserID:    userID.(uint),
+		BookingID: booking.ID,
+		Rating:    req.Rating,
+		Body:      req.Body,
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// The unique trip and user index decides between concurrent first reviews
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&review)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errReviewExists
+		}
+		return models.RefreshTripRating(tx, trip.ID)
+	})
+	if errors.Is(err, errReviewExists) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Review already exists",
+			"message": "You have already reviewed this trip, edit your review instead",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create review",
+			"message": "Could not save review to database",
+		})
+		return
+	}
+
+	config.DB.Preload("User").First(&review, review.ID)
+
+	notify.Send(trip.UserID, notify.EventReviewPosted, "New review of "+trip.Name,
+		fmt.Sprintf("%s rated %s %d out of 5.", review.User.Name, trip.Name, review.Rating),
+		fmt.Sprintf("/api/v1/trips/%d/reviews", trip.ID))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Review created successfully",
+		"data":    review,
+	})
+}
+
+// Update edits the rating and text of a review (only its author)
+func Update(c *gin.Context) {
+	review, ok := findAuthoredReview(c)
+	if !ok {
+		return
+	}
+
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	review.Rating = req.Rating
+	review.Body = req.Body
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&review).Error; err != nil {
+			return err
+		}
+		return models.RefreshTripRating(tx, review.TripID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update review",
+			"message": "Could not save changes to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Review updated successfully",
+		"data":    review,
+	})
+}
+
+// Delete removes a review and its votes (only its author), the author may review the trip again
+func Delete(c *gin.Context) {
+	review, ok := findAuthoredReview(c)
+	if !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("review_id = ?", review.ID).Delete(&models.ReviewVote{}).Error; err != nil {
+			return err
+		}
+		// Hard delete so the unique trip and user index does not block a new review
+		if err := tx.Unscoped().Delete(&review).Error; err != nil {
+			return err
+		}
+		return models.RefreshTripRating(tx, review.TripID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete review",
+			"message": "Could not remove review from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Review deleted successfully",
+	})
+}
+
+// Reply sets the trip owner's public reply to a review, each review has at most one reply
+func Reply(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to reply to a review",
+		})
+		return
+	}
+
+	var req ReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var review models.Review
+	if err := config.DB.First(&review, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Review not found",
+			"message": "The requested review does not exist",
+		})
+		return
+	}
+
+	var trip models.Trip
+	if err := config.DB.Unscoped().First(&trip, review.TripID).Error; err != nil || trip.UserID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"message": "You can only reply to reviews of your own trips",
+		})
+		return
+	}
+
+	now := time.Now()
+	review.OwnerReply = &req.Reply
+	review.RepliedAt = &now
+	if err := config.DB.Save(&review).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to reply to review",
+			"message": "Could not save reply to database",
+		})
+		return
+	}
+
+	notify.Send(review.UserID, notify.EventReviewReplied, "Reply to your review of "+trip.Name,
+		"The trip owner replied to your review: "+req.Reply,
+		fmt.Sprintf("/api/v1/trips/%d/reviews", trip.ID))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reply saved successfully",
+		"data":    review,
+	})
+}
+
+// MarkHelpful records the current user's helpful vote, voting twice has no effect
+func MarkHelpful(c *gin.Context) {
+	voteHelpful(c, true)
+}
+
+// UnmarkHelpful withdraws the current user's helpful vote
+func UnmarkHelpful(c *gin.Context) {
+	voteHelpful(c, false)
+}
+
+// voteHelpful adds or removes a helpful vote and keeps the review's counter in step
+func voteHelpful(c *gin.Context, helpful bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to vote on reviews",
+		})
+		return
+	}
+
+	var review models.Review
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&review, c.Param("id")).Error; err != nil {
+			return gorm.ErrRecordNotFound
+		}
+		if review.UserID == userID.(uint) {
+			return errOwnReview
+		}
+
+		var result *gorm.DB
+		delta := 1
+		if helpful {
+			vote := models.ReviewVote{ReviewID: review.ID, UserID: userID.(uint)}
+			result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&vote)
+		} else {
+			delta = -1
+			result = tx.Unscoped().Where("review_id = ? AND user_id = ?", review.ID, userID).Delete(&models.ReviewVote{})
+		}
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		review.HelpfulCount += delta
+		return tx.Model(&review).UpdateColumn("helpful_count", review.HelpfulCount).Error
+	})
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Review not found",
+			"message": "The requested review does not exist",
+		})
+		return
+	case errors.Is(err, errOwnReview):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid vote",
+			"message": err.Error(),
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to record vote",
+			"message": "Could not save vote to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Vote recorded successfully",
+		"data":    review,
+	})
+}
+
+// findAuthoredReview loads the review from the id parameter and verifies the current user wrote it
+func findAuthoredReview(c *gin.Context) (models.Review, bool) {
+	var review models.Review
+	if err := config.DB.First(&review, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Review not found",
+			"message": "The requested review does not exist",
+		})
+		return review, false
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists || review.UserID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"message": "You can only manage your own reviews",
+		})
+		return review, false
+	}
+
+	return review, true
+}
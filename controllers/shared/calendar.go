@@ -0,0 +1,21 @@
+package shared
+
+import (
+	"backend-go/ical"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WriteCalendar writes the calendar as the response, as a download when filename is set
+func WriteCalendar(c *gin.Context, calendar ical.Calendar, filename string) {
+	c.Header("Content-Type", ical.ContentType)
+	if filename != "" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	}
+	c.Status(http.StatusOK)
+	if err := calendar.Write(c.Writer); err != nil {
+		c.Error(err)
+	}
+}
@@ -0,0 +1,41 @@
+package shared
+
+import (
+	"backend-go/config"
+	"backend-go/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FindOwnedTrip loads a trip and verifies the authenticated user owns it, writing the error response
+// otherwise. what names the things being managed in error messages, e.g. "departures".
+func FindOwnedTrip(c *gin.Context, tripID interface{}, what string) (models.Trip, bool) {
+	var trip models.Trip
+	if err := config.DB.First(&trip, tripID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Trip not found",
+			"message": "The requested trip does not exist",
+		})
+		return trip, false
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to manage " + what,
+		})
+		return trip, false
+	}
+
+	if trip.UserID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"message": "You can only manage " + what + " of your own trips",
+		})
+		return trip, false
+	}
+
+	return trip, true
+}
@@ -0,0 +1,258 @@
+package trip
+
+import (
+	"backend-go/config"
+	"backend-go/currency"
+	"backend-go/models"
+	"backend-go/recommend"
+	"backend-go/utils"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxRecommendations caps the limit query parameter of GetRecommended
+const maxRecommendations = 100
+
+// recommendationCandidates caps how many trips are scored, picked in SQL by matched preferences,
+// a start within nearbyKm of the requested location and rating
+const (
+	recommendationCandidates = 500
+	nearbyKm                 = 100.0
+)
+
+// GetRecommended ranks trips for the current user by preference overlap, proximity to the
+// optional lat and lng, price fit and rating. Trips the user already booked are left out.
+//
+// budget is a per participant amount in major units of currency, without it the budget is
+// derived from the user's past bookings. scorer picks a registered recommend.Scorer.
+func GetRecommended(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to get recommendations",
+		})
+		return
+	}
+
+	scorer, exists := recommend.Lookup(c.DefaultQuery("scorer", recommend.DefaultScorer))
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Unknown scorer",
+			"message": "scorer must name a registered scorer, e.g. " + recommend.DefaultScorer,
+		})
+		return
+	}
+
+	limit := 20
+	if raw := c.Query("limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid limit",
+				"message": "limit must be a positive number",
+			})
+			return
+		}
+		limit = min(value, maxRecommendations)
+	}
+
+	location, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	display, rates, ok := displayCurrency(c)
+	if !ok {
+		return
+	}
+	if rates == nil {
+		var err error
+		if rates, err = models.LoadRates(config.DB); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to retrieve exchange rates",
+				"message": "Could not fetch exchange rates from database",
+			})
+			return
+		}
+	}
+
+	profile := recommend.Profile{Location: location, Rates: rates}
+
+	var preferences []models.Preference
+	if err := config.DB.Joins("JOIN user_preferences ON user_preferences.preference_id = preferences.id AND user_preferences.deleted_at IS NULL").
+		Where("user_preferences.user_id = ?", userID).Find(&preferences).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to compute recommendations",
+			"message": "Could not fetch your preferences from database",
+		})
+		return
+	}
+	profile.Preferences = make(map[uint]string, len(preferences))
+	for _, preference := range preferences {
+		profile.Preferences[preference.ID] = preference.Name
+	}
+
+	budget, ok := requestBudget(c, display, rates)
+	if !ok {
+		return
+	}
+	if budget == 0 {
+		budget = pastBookingBudget(userID.(uint), rates)
+	}
+	profile.Budget = budget
+
+	booked := config.DB.Model(&models.Booking{}).Select("trip_id").
+		Where("user_id = ? AND status <> ?", userID, models.BookingCancelled)
+
+	// Only the likeliest candidates are scored, and only the recommended ones get their owner and gallery
+	var trips []models.Trip
+	query := config.DB.Preload("Preferences").Where("user_id <> ? AND id NOT IN (?)", userID, booked)
+	if err := candidateOrder(query, preferences, location).Limit(recommendationCandidates).Find(&trips).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to compute recommendations",
+			"message": "Could not fetch trips from database",
+		})
+		return
+	}
+
+	recommendations := recommend.Rank(scorer, profile, trips, limit)
+
+	ids := make([]uint, len(recommendations))
+	for i, recommendation := range recommendations {
+		ids[i] = recommendation.Trip.ID
+	}
+	var details []models.Trip
+	if err := config.DB.Preload("User").Preload("Images").Find(&details, ids).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to compute recommendations",
+			"message": "Could not fetch trips from database",
+		})
+		return
+	}
+	byID := make(map[uint]models.Trip, len(details))
+	for _, trip := range details {
+		byID[trip.ID] = trip
+	}
+
+	tripRefs := make([]*models.Trip, len(recommendations))
+	for i := range recommendations {
+		trip := &recommendations[i].Trip
+		trip.User, trip.Images = byID[trip.ID].User, byID[trip.ID].Images
+		setDisplayPrice(&recommendations[i].Trip, display, rates)
+		recommendations[i].Trip.PrepareGallery(utils.GetBaseURL(c))
+		tripRefs[i] = &recommendations[i].Trip
+	}
+	markFavorites(c, tripRefs...)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Recommendations computed successfully",
+		"data":    recommendations,
+		"count":   len(recommendations),
+	})
+}
+
+// candidateOrder orders trips by how many of the user's preferences they match, then by whether they
+// start near location, then by rating, so the best candidates for scoring come first
+func candidateOrder(query *gorm.DB, preferences []models.Preference, location *recommend.Location) *gorm.DB {
+	var order []string
+	var vars []interface{}
+
+	if len(preferences) > 0 {
+		ids := make([]uint, len(preferences))
+		for i, preference := range preferences {
+			ids[i] = preference.ID
+		}
+		order = append(order, "(SELECT COUNT(*) FROM trip_preferences WHERE trip_preferences.trip_id = trips.id AND trip_preferences.preference_id IN ? AND trip_preferences.deleted_at IS NULL) DESC")
+		vars = append(vars, ids)
+	}
+
+	if location != nil {
+		// A box around the location is cheap to test and close enough to the distance
+		latDelta := nearbyKm / 111.0
+		lngDelta := nearbyKm / (111.0 * math.Max(math.Cos(location.Latitude*math.Pi/180), 0.01))
+		order = append(order, "CASE WHEN trips.start_latitude BETWEEN ? AND ? AND trips.start_longitude BETWEEN ? AND ? THEN 0 ELSE 1 END")
+		vars = append(vars, location.Latitude-latDelta, location.Latitude+latDelta, location.Longitude-lngDelta, location.Longitude+lngDelta)
+	}
+
+	order = append(order, "trips.rating_average DESC", "trips.id")
+	return query.Order(clause.OrderBy{Expression: clause.Expr{SQL: strings.Join(order, ", "), Vars: vars}})
+}
+
+// requestLocation reads the optional lat and lng query parameters, both or neither must be given
+func requestLocation(c *gin.Context) (*recommend.Location, bool) {
+	rawLat, rawLng := c.Query("lat"), c.Query("lng")
+	if rawLat == "" && rawLng == "" {
+		return nil, true
+	}
+
+	lat, latErr := strconv.ParseFloat(rawLat, 64)
+	lng, lngErr := strconv.ParseFloat(rawLng, 64)
+	if latErr != nil || lngErr != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid location",
+			"message": "lat and lng must be given together, within -90..90 and -180..180",
+		})
+		return nil, false
+	}
+	return &recommend.Location{Latitude: lat, Longitude: lng}, true
+}
+
+// requestBudget reads the optional budget query parameter and converts it to minor units of the base currency
+func requestBudget(c *gin.Context, code string, rates currency.Rates) (int64, bool) {
+	raw := c.Query("budget")
+	if raw == "" {
+		return 0, true
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || value <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid budget",
+			"message": "budget must be a positive amount",
+		})
+		return 0, false
+	}
+
+	if code == "" {
+		code = currency.Default
+	}
+	budget, err := rates.Convert(currency.ToMinor(value, code), code, currency.Base)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Unsupported currency",
+			"message": err.Error(),
+		})
+		return 0, false
+	}
+	return budget, true
+}
+
+// pastBookingBudget estimates a per participant budget from the user's past bookings, 0 when there are none
+func pastBookingBudget(userID uint, rates currency.Rates) int64 {
+	var bookings []models.Booking
+	config.DB.Where("user_id = ? AND status <> ? AND participants > 0", userID, models.BookingCancelled).
+		Order("created_at DESC").Limit(20).Find(&bookings)
+
+	var total int64
+	var counted int64
+	for _, booking := range bookings {
+		perParticipant := (booking.Amount + booking.Discount) / int64(booking.Participants)
+		amount, err := rates.Convert(perParticipant, booking.Currency, currency.Base)
+		if err != nil {
+			continue
+		}
+		total += amount
+		counted++
+	}
+	if counted == 0 {
+		return 0
+	}
+	return total / counted
+}
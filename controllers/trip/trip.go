@@ -2,16 +2,17 @@ package trip
 
 import (
 	"backend-go/config"
+	"backend-go/currency"
 	"backend-go/models"
-	"encoding/json"
+	"backend-go/notify"
+	"backend-go/utils"
 	"fmt"
-	"math/rand/v2"
+	"log"
 	"net/http"
-	"net/url"
-	"strings"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
 // CreateTripRequest represents the request structure for creating a trip
@@ -19,7 +20,8 @@ type CreateTripRequest struct {
 	Name           string             `json:"name" binding:"required"`
 	Description    string             `json:"description"`
 	CoverImage     string             `json:"cover_image"`
-	Price          float64            `json:"price" binding:"required,min=0"`
+	Price          float64            `json:"price" binding:"required,min=0"` // Major units of Currency
+	Currency       string             `json:"currency"`                       // ISO 4217 code, defaults to IDR
 	Duration       int                `json:"duration" binding:"required,min=1"`
 	StartLatitude  float64            `json:"start_latitude" binding:"required"`
 	StartLongitude float64            `json:"start_longitude" binding:"required"`
@@ -27,6 +29,8 @@ type CreateTripRequest struct {
 	EndLongitude   float64            `json:"end_longitude" binding:"required"`
 	PreferenceIDs  []uint             `json:"preference_ids"`
 	Points         []models.TripPoint `json:"points"`
+
+	CancellationPolicyID *uint `json:"cancellation_policy_id"`
 }
 
 // UpdateTripRequest represents the request structure for updating a trip
@@ -34,7 +38,8 @@ type UpdateTripRequest struct {
 	Name           *string            `json:"name"`
 	Description    *string            `json:"description"`
 	CoverImage     *string            `json:"cover_image"`
-	Price          *float64           `json:"price"`
+	Price          *float64           `json:"price" binding:"omitempty,min=0"`
+	Currency       *string            `json:"currency"`
 	Duration       *int               `json:"duration"`
 	StartLatitude  *float64           `json:"start_latitude"`
 	StartLongitude *float64           `json:"start_longitude"`
@@ -42,6 +47,8 @@ type UpdateTripRequest struct {
 	EndLongitude   *float64           `json:"end_longitude"`
 	PreferenceIDs  []uint             `json:"preference_ids"`
 	Points         []models.TripPoint `json:"points"`
+
+	CancellationPolicyID *uint `json:"cancellation_policy_id"` // 0 removes the policy
 }
 
 // GetAll retrieves all trips with optional filtering and pagination
@@ -54,12 +61,30 @@ func GetAll(c *gin.Context) {
 		query = query.Where("user_id = ?", userID)
 	}
 
-	// Optional filtering by price range
-	if minPrice := c.Query("min_price"); minPrice != "" {
-		query = query.Where("price >= ?", minPrice)
+	// Optional ordering by review aggregates
+	switch c.Query("sort") {
+	case "":
+	case "rating":
+		query = query.Order("rating_average DESC, review_count DESC")
+	case "reviews":
+		query = query.Order("review_count DESC, rating_average DESC")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid sort",
+			"message": "sort must be rating or reviews",
+		})
+		return
+	}
+
+	display, rates, ok := displayCurrency(c)
+	if !ok {
+		return
 	}
-	if maxPrice := c.Query("max_price"); maxPrice != "" {
-		query = query.Where("price <= ?", maxPrice)
+
+	// Optional price range, in major units of the requested currency
+	minPrice, maxPrice, ok := priceRange(c)
+	if !ok {
+		return
 	}
 
 	// Execute query
@@ -71,6 +96,23 @@ func GetAll(c *gin.Context) {
 		return
 	}
 
+	// Prices are in different currencies, so the range is applied after conversion
+	filtered := trips[:0]
+	for i := range trips {
+		setDisplayPrice(&trips[i], display, rates)
+		if inPriceRange(trips[i], minPrice, maxPrice) {
+			filtered = append(filtered, trips[i])
+		}
+	}
+	trips = filtered
+
+	tripRefs := make([]*models.Trip, len(trips))
+	for i := range trips {
+		tripRefs[i] = &trips[i]
+	}
+	markFavorites(c, tripRefs...)
+	models.ResolveTripImageURLs(trips, utils.GetBaseURL(c))
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Trips retrieved successfully",
 		"data":    trips,
@@ -83,13 +125,21 @@ func GetByID(c *gin.Context) {
 	var trip models.Trip
 	id := c.Param("id")
 
-	if err := config.DB.Preload("User").Preload("Images").Preload("Preferences").Preload("Points").First(&trip, id).Error; err != nil {
+	display, rates, ok := displayCurrency(c)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Preload("User").Preload("Images").Preload("Preferences").Preload("Points").Preload("CancellationPolicy").First(&trip, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error":   "Trip not found",
 			"message": "The requested trip does not exist",
 		})
 		return
 	}
+	setDisplayPrice(&trip, display, rates)
+	markFavorites(c, &trip)
+	trip.PrepareGallery(utils.GetBaseURL(c))
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Trip retrieved successfully",
@@ -137,12 +187,28 @@ func Create(c *gin.Context) {
 		}
 	}
 
+	if req.CancellationPolicyID != nil && !ownsPolicy(userID.(uint), *req.CancellationPolicyID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cancellation policy ID"})
+		return
+	}
+
+	code := currency.Default
+	if req.Currency != "" {
+		normalized, err := currency.Normalize(req.Currency)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency", "details": err.Error()})
+			return
+		}
+		code = normalized
+	}
+
 	// Create trip
 	trip := models.Trip{
 		Name:           req.Name,
 		Description:    req.Description,
 		CoverImage:     req.CoverImage,
-		Price:          req.Price,
+		PriceAmount:    currency.ToMinor(req.Price, code),
+		Currency:       code,
 		Duration:       req.Duration,
 		StartLatitude:  req.StartLatitude,
 		StartLongitude: req.StartLongitude,
@@ -151,6 +217,8 @@ func Create(c *gin.Context) {
 		UserID:         userID.(uint),
 		Preferences:    preferences,
 		Points:         req.Points,
+
+		CancellationPolicyID: req.CancellationPolicyID,
 	}
 
 	if err := config.DB.Create(&trip).Error; err != nil {
@@ -163,6 +231,7 @@ func Create(c *gin.Context) {
 
 	// Load associations for the response
 	config.DB.Preload("User").Preload("Preferences").Preload("Points").First(&trip, trip.ID)
+	setDisplayPrice(&trip, "", nil)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Trip created successfully",
@@ -222,6 +291,28 @@ func Update(c *gin.Context) {
 		return
 	}
 
+	if req.CancellationPolicyID != nil && *req.CancellationPolicyID != 0 && !ownsPolicy(userID.(uint), *req.CancellationPolicyID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cancellation policy ID"})
+		return
+	}
+
+	// The stored amount only means something in its currency, so a new currency needs a new price
+	if req.Currency != nil {
+		code, err := currency.Normalize(*req.Currency)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency", "details": err.Error()})
+			return
+		}
+		if code != trip.Currency && req.Price == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Price required",
+				"message": "Send the price in " + code + " along with the new currency",
+			})
+			return
+		}
+		trip.Currency = code
+	}
+
 	// Use a transaction for atomic updates
 	tx := config.DB.Begin()
 	if tx.Error != nil {
@@ -238,9 +329,10 @@ func Update(c *gin.Context) {
 	}
 	if req.CoverImage != nil {
 		trip.CoverImage = *req.CoverImage
+		trip.CoverImageID = nil // An explicit URL replaces a cover picked from the gallery
 	}
 	if req.Price != nil {
-		trip.Price = *req.Price
+		trip.PriceAmount = currency.ToMinor(*req.Price, trip.Currency)
 	}
 	if req.Duration != nil {
 		trip.Duration = *req.Duration
@@ -257,6 +349,12 @@ func Update(c *gin.Context) {
 	if req.EndLongitude != nil {
 		trip.EndLongitude = *req.EndLongitude
 	}
+	if req.CancellationPolicyID != nil {
+		trip.CancellationPolicyID = req.CancellationPolicyID
+		if *req.CancellationPolicyID == 0 {
+			trip.CancellationPolicyID = nil
+		}
+	}
 
 	if err := tx.Save(&trip).Error; err != nil {
 		tx.Rollback()
@@ -300,6 +398,9 @@ func Update(c *gin.Context) {
 
 	// Load the user data for the response
 	config.DB.Preload("User").Preload("Preferences").Preload("Points").First(&trip, trip.ID)
+	setDisplayPrice(&trip, "", nil)
+
+	notifyTravellers(trip)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Trip updated successfully",
@@ -307,6 +408,23 @@ func Update(c *gin.Context) {
 	})
 }
 
+// notifyTravellers tells every visitor with an upcoming booking that the trip changed
+func notifyTravellers(trip models.Trip) {
+	var visitorIDs []uint
+	if err := config.DB.Model(&models.Booking{}).Distinct("user_id").
+		Where("trip_id = ? AND status IN ? AND starts_at > ?", trip.ID, models.ActiveBookingStatuses, time.Now()).
+		Pluck("user_id", &visitorIDs).Error; err != nil {
+		log.Printf("Failed to find travellers of trip %d: %v", trip.ID, err)
+		return
+	}
+
+	for _, visitorID := range visitorIDs {
+		notify.Send(visitorID, notify.EventTripUpdated, trip.Name+" was updated",
+			"The owner changed the details of "+trip.Name+", a trip you booked. Check the trip before you go.",
+			fmt.Sprintf("/api/v1/trips/%d", trip.ID))
+	}
+}
+
 // Delete deletes a trip (only the trip owner)
 func Delete(c *gin.Context) {
 	var trip models.Trip
@@ -397,6 +515,7 @@ func GetMyTrips(c *gin.Context) {
 		})
 		return
 	}
+	models.ResolveTripImageURLs(trips, utils.GetBaseURL(c))
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Your trips retrieved successfully",
@@ -405,254 +524,115 @@ func GetMyTrips(c *gin.Context) {
 	})
 }
 
-func SeedTrips(c *gin.Context) {
-	// Check if user is authenticated
-	userID, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error":   "Authentication required",
-			"message": "You must be logged in to create a trip",
-		})
-		return
-	}
+// ownsPolicy checks that the cancellation policy exists and belongs to the user
+func ownsPolicy(userID, policyID uint) bool {
+	var count int64
+	config.DB.Model(&models.CancellationPolicy{}).Where("id = ? AND user_id = ?", policyID, userID).Count(&count)
+	return count > 0
+}
 
-	// Check if user has trip_owner role
-	userRole, exists := c.Get("role")
-	if !exists || userRole.(string) != "trip_owner" {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error":   "Insufficient permissions",
-			"message": "Only trip owners can create trips",
-		})
-		return
+// displayCurrency reads the optional currency query parameter and the rates needed to convert into it
+func displayCurrency(c *gin.Context) (string, currency.Rates, bool) {
+	requested := c.Query("currency")
+	if requested == "" {
+		return "", nil, true
 	}
 
-	// Query OpenStreetMap for Jakarta tourism attractions
-	osmQuery := `[out:json][timeout:25];
-	(
-		way["tourism"~"attraction|museum|viewpoint|gallery|theme_park|zoo|aquarium|artwork"]
-		   ["name"]
-		   (bbox:-6.3713,-106.9758,-6.0835,-106.6486);
-		relation["tourism"~"attraction|museum|viewpoint|gallery|theme_park|zoo|aquarium"]
-		        ["name"]
-		        (bbox:-6.3713,-106.9758,-6.0835,-106.6486);
-		node["tourism"~"attraction|museum|viewpoint|gallery|theme_park|zoo|aquarium|artwork"]
-		    ["name"]
-		    (bbox:-6.3713,-106.9758,-6.0835,-106.6486);
-	);
-	out center meta;`
-
-	osmResp, err := http.Post("https://overpass-api.de/api/interpreter",
-		"application/x-www-form-urlencoded",
-		strings.NewReader("data="+url.QueryEscape(osmQuery)))
+	code, err := currency.Normalize(requested)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to query OpenStreetMap",
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid currency",
+			"message": err.Error(),
 		})
-		return
-	}
-	defer osmResp.Body.Close()
-
-	var osmData struct {
-		Elements []struct {
-			Type   string  `json:"type"`
-			ID     int64   `json:"id"`
-			Lat    float64 `json:"lat"`
-			Lon    float64 `json:"lon"`
-			Center *struct {
-				Lat float64 `json:"lat"`
-				Lon float64 `json:"lon"`
-			} `json:"center,omitempty"`
-			Tags map[string]string `json:"tags"`
-		} `json:"elements"`
+		return "", nil, false
 	}
 
-	if err := json.NewDecoder(osmResp.Body).Decode(&osmData); err != nil {
+	rates, err := models.LoadRates(config.DB)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to parse OSM data",
+			"error":   "Failed to retrieve exchange rates",
+			"message": "Could not fetch exchange rates from database",
 		})
-		return
+		return "", nil, false
 	}
-
-	// Preference mapping
-	preferenceMap := map[string][]string{
-		"attraction": {"Sightseeing", "Photography", "Culture", "History"},
-		"museum":     {"Education", "Culture", "History", "Art"},
-		"viewpoint":  {"Photography", "Sightseeing", "Nature"},
-		"gallery":    {"Art", "Culture", "Photography"},
-		"theme_park": {"Family", "Entertainment", "Adventure"},
-		"zoo":        {"Family", "Education", "Nature"},
-		"aquarium":   {"Family", "Education", "Marine Life"},
-		"artwork":    {"Art", "Culture", "Photography"},
+	if _, err := rates.Rate(code); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Unsupported currency",
+			"message": err.Error(),
+		})
+		return "", nil, false
 	}
 
-	var trips []models.Trip
-	createdPrefs := make(map[string]uint) // Cache preferences
-
-	for i, element := range osmData.Elements {
-		if i >= 20 { // Limit to 20 attractions to avoid too many requests
-			break
-		}
-
-		name := element.Tags["name"]
-		if name == "" {
-			continue
-		}
-
-		// Get coordinates
-		lat, lon := element.Lat, element.Lon
-		if element.Center != nil {
-			lat, lon = element.Center.Lat, element.Center.Lon
-		}
-
-		// Generate description and additional info
-		description := generateDescription(element.Tags)
-
-		// Create trip points (simulated popular spots)
-		var tripPoints []models.TripPoint
-		for j := 0; j < 3; j++ {
-			// Generate nearby points for photo spots
-			offsetLat := lat + (rand.Float64()-0.5)*0.001
-			offsetLon := lon + (rand.Float64()-0.5)*0.001
-
-			tripPoints = append(tripPoints, models.TripPoint{
-				Latitude:  offsetLat,
-				Longitude: offsetLon,
-			})
-		}
-
-		// Get preferences for this attraction
-		tourismType := element.Tags["tourism"]
-		var preferences []models.Preference
-
-		if prefNames, exists := preferenceMap[tourismType]; exists {
-			for _, prefName := range prefNames {
-				if prefID, cached := createdPrefs[prefName]; cached {
-					preferences = append(preferences, models.Preference{Model: gorm.Model{ID: prefID}})
-				} else {
-					// Create new preference
-					pref := models.Preference{Name: prefName}
-					if err := config.DB.FirstOrCreate(&pref, models.Preference{Name: prefName}).Error; err == nil {
-						preferences = append(preferences, pref)
-						createdPrefs[prefName] = pref.ID
-					}
-				}
-			}
-		}
-
-		// Generate realistic price and duration based on type
-		price, duration := generatePriceAndDuration(tourismType)
-
-		trip := models.Trip{
-			Name:           name,
-			Description:    description,
-			CoverImage:     generateCoverImage(tourismType),
-			Price:          price,
-			Duration:       duration,
-			StartLatitude:  lat,
-			StartLongitude: lon,
-			EndLatitude:    lat,
-			EndLongitude:   lon,
-			UserID:         userID.(uint),
-			Points:         tripPoints,
-			Preferences:    preferences,
-		}
-
-		trips = append(trips, trip)
-	}
+	return code, rates, true
+}
 
-	if len(trips) == 0 {
-		c.JSON(http.StatusOK, gin.H{
-			"message": "No suitable attractions found",
-		})
-		return
+// setDisplayPrice fills DisplayPrice with the trip price converted to code, or in its own currency
+// when no conversion was requested. Trips priced in a currency without a rate get no display price.
+func setDisplayPrice(trip *models.Trip, code string, rates currency.Rates) {
+	if code == "" {
+		code = trip.Currency
 	}
 
-	if err := config.DB.Create(&trips).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to seed trips",
-			"message": "Could not add trips to database",
-		})
+	amount, err := rates.Convert(trip.PriceAmount, trip.Currency, code)
+	if err != nil {
+		log.Printf("Cannot convert price of trip %d: %v", trip.ID, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": fmt.Sprintf("%d trips seeded successfully", len(trips)),
-		"trips":   len(trips),
-	})
+	money := currency.NewMoney(amount, code)
+	trip.DisplayPrice = &money
 }
 
-func generateDescription(tags map[string]string) string {
-	desc := "Explore this amazing attraction in Jakarta. "
-
-	if addr := tags["addr:full"]; addr != "" {
-		desc += "Located at " + addr + ". "
+// priceRange parses the optional min_price and max_price query parameters, a negative bound means unset
+func priceRange(c *gin.Context) (float64, float64, bool) {
+	bounds := [2]float64{-1, -1}
+	for i, name := range []string{"min_price", "max_price"} {
+		raw := c.Query(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+			return 0, 0, false
+		}
+		bounds[i] = value
 	}
+	return bounds[0], bounds[1], true
+}
 
-	if website := tags["website"]; website != "" {
-		desc += "Visit their website for more information. "
+// inPriceRange compares the display price of a trip against the range in major units
+func inPriceRange(trip models.Trip, minPrice, maxPrice float64) bool {
+	if minPrice < 0 && maxPrice < 0 {
+		return true
 	}
-
-	if phone := tags["phone"]; phone != "" {
-		desc += "Contact: " + phone + ". "
+	if trip.DisplayPrice == nil {
+		return false
 	}
 
-	desc += "Perfect for photography and sightseeing!"
-	return desc
+	price := currency.ToMajor(trip.DisplayPrice.Amount, trip.DisplayPrice.Currency)
+	return (minPrice < 0 || price >= minPrice) && (maxPrice < 0 || price <= maxPrice)
 }
 
-func generatePriceAndDuration(tourismType string) (float64, int) {
-	priceMap := map[string][2]float64{
-		"museum":     {15000, 50000},
-		"theme_park": {100000, 300000},
-		"zoo":        {30000, 80000},
-		"aquarium":   {50000, 150000},
-		"attraction": {0, 25000},
-		"viewpoint":  {0, 15000},
-		"gallery":    {10000, 40000},
-		"artwork":    {0, 10000},
+// markFavorites sets IsFavorited on the trips when OptionalAuth resolved a user
+func markFavorites(c *gin.Context, trips ...*models.Trip) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		return
 	}
 
-	durationMap := map[string][2]int{
-		"museum":     {60, 180},
-		"theme_park": {240, 480},
-		"zoo":        {120, 300},
-		"aquarium":   {90, 240},
-		"attraction": {30, 120},
-		"viewpoint":  {20, 60},
-		"gallery":    {45, 120},
-		"artwork":    {10, 30},
+	ids := make([]uint, len(trips))
+	for i, trip := range trips {
+		ids[i] = trip.ID
 	}
 
-	priceRange := priceMap["attraction"]       // default
-	durationRange := durationMap["attraction"] // default
-
-	if pr, exists := priceMap[tourismType]; exists {
-		priceRange = pr
-	}
-	if dr, exists := durationMap[tourismType]; exists {
-		durationRange = dr
-	}
-
-	price := priceRange[0] + rand.Float64()*(priceRange[1]-priceRange[0])
-	duration := int(float64(durationRange[0]) + rand.Float64()*float64(durationRange[1]-durationRange[0]))
-
-	return price, duration
-}
-
-func generateCoverImage(tourismType string) string {
-	imageMap := map[string]string{
-		"museum":     "https://images.unsplash.com/photo-1566127992631-137a642a90f4?w=800",
-		"theme_park": "https://images.unsplash.com/photo-1544552866-d3ed42536cfd?w=800",
-		"zoo":        "https://images.unsplash.com/photo-1564760055775-d63b17a55c44?w=800",
-		"aquarium":   "https://images.unsplash.com/photo-1544551763-46a013bb70d5?w=800",
-		"viewpoint":  "https://images.unsplash.com/photo-1477959858617-67f85cf4f1df?w=800",
-		"gallery":    "https://images.unsplash.com/photo-1578662996442-48f60103fc96?w=800",
-		"artwork":    "https://images.unsplash.com/photo-1578662996442-48f60103fc96?w=800",
-		"attraction": "https://images.unsplash.com/photo-1539650116574-75c0c6d73f6e?w=800",
+	favorited, err := models.FavoritedTripIDs(config.DB, userID.(uint), ids)
+	if err != nil {
+		log.Printf("Cannot load favorites of user %v: %v", userID, err)
+		return
 	}
-
-	if img, exists := imageMap[tourismType]; exists {
-		return img
+	for _, trip := range trips {
+		isFavorited := favorited[trip.ID]
+		trip.IsFavorited = &isFavorited
 	}
-	return imageMap["attraction"]
 }
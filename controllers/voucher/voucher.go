@@ -0,0 +1,369 @@
+package voucher
+
+import (
+	"backend-go/config"
+	"backend-go/currency"
+	"backend-go/models"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VoucherRequest represents the request structure for creating or replacing a voucher.
+// OwnerID is only honoured for admins, trip owners always create codes for their own trips.
+type VoucherRequest struct {
+	Code           string     `json:"code" binding:"required,max=40"`
+	Description    string     `json:"description"`
+	Kind           string     `json:"kind" binding:"required,oneof=percent fixed"`
+	Percent        int        `json:"percent" binding:"min=0,max=100"`
+	Amount         int64      `json:"amount" binding:"min=0"`
+	Currency       string     `json:"currency"`
+	MinSpend       int64      `json:"min_spend" binding:"min=0"`
+	MaxRedemptions int        `json:"max_redemptions" binding:"min=0"`
+	MaxPerUser     int        `json:"max_per_user" binding:"min=0"`
+	ValidFrom      *time.Time `json:"valid_from"`
+	ValidUntil     *time.Time `json:"valid_until"`
+	Active         *bool      `json:"active"`
+	OwnerID        *uint      `json:"owner_id"`
+}
+
+// ValidateRequest represents the request structure for checking a code against a quote
+type ValidateRequest struct {
+	Code    string `json:"code" binding:"required"`
+	QuoteID uint   `json:"quote_id" binding:"required"`
+}
+
+// GetAll lists vouchers, admins see every code and trip owners their own
+func GetAll(c *gin.Context) {
+	query := config.DB.Order("created_at DESC")
+
+	if role, _ := c.Get("role"); role != "admin" {
+		userID, _ := c.Get("userID")
+		query = query.Where("owner_id = ?", userID)
+	} else if ownerID := c.Query("owner_id"); ownerID != "" {
+		query = query.Where("owner_id = ?", ownerID)
+	}
+
+	var vouchers []models.Voucher
+	if err := query.Find(&vouchers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve vouchers",
+			"message": "Could not fetch vouchers from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Vouchers retrieved successfully",
+		"data":    vouchers,
+		"count":   len(vouchers),
+	})
+}
+
+// GetByID retrieves a voucher with its redemptions
+func GetByID(c *gin.Context) {
+	voucher, ok := findManagedVoucher(c)
+	if !ok {
+		return
+	}
+
+	var redemptions []models.VoucherRedemption
+	if err := config.DB.Where("voucher_id = ?", voucher.ID).Order("created_at").Find(&redemptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve voucher",
+			"message": "Could not fetch redemptions from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Voucher retrieved successfully",
+		"data":        voucher,
+		"redemptions": redemptions,
+	})
+}
+
+// Create creates a voucher (admin or trip owner)
+func Create(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to create a voucher",
+		})
+		return
+	}
+
+	var req VoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	voucher := models.Voucher{CreatedBy: userID.(uint), Active: true}
+	if !applyRequest(c, &voucher, req) {
+		return
+	}
+
+	if codeTaken(voucher.Code, 0) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Voucher code already exists",
+			"message": "Pick a different code",
+		})
+		return
+	}
+
+	if err := config.DB.Create(&voucher).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create voucher",
+			"message": "Could not save voucher to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Voucher created successfully",
+		"data":    voucher,
+	})
+}
+
+// Update replaces a voucher's settings, its redemption count is kept
+func Update(c *gin.Context) {
+	voucher, ok := findManagedVoucher(c)
+	if !ok {
+		return
+	}
+
+	var req VoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if !applyRequest(c, &voucher, req) {
+		return
+	}
+
+	if codeTaken(voucher.Code, voucher.ID) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Voucher code already exists",
+			"message": "Pick a different code",
+		})
+		return
+	}
+
+	if err := config.DB.Save(&voucher).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update voucher",
+			"message": "Could not save changes to database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Voucher updated successfully",
+		"data":    voucher,
+	})
+}
+
+// Delete removes a voucher, bookings that redeemed it keep their discount
+func Delete(c *gin.Context) {
+	voucher, ok := findManagedVoucher(c)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Delete(&voucher).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete voucher",
+			"message": "Could not remove voucher from database",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Voucher deleted successfully",
+	})
+}
+
+// Validate checks a code against a quote for the current user without redeeming it
+func Validate(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication required",
+			"message": "You must be logged in to use a voucher",
+		})
+		return
+	}
+
+	var req ValidateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var voucher models.Voucher
+	if err := config.DB.Where("code = ?", models.NormalizeVoucherCode(req.Code)).First(&voucher).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Voucher not found",
+			"message": "The voucher code does not exist",
+		})
+		return
+	}
+
+	var quote models.Quote
+	if err := config.DB.Where("user_id = ?", userID).First(&quote, req.QuoteID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Quote not found",
+			"message": "The requested quote does not exist",
+		})
+		return
+	}
+
+	var trip models.Trip
+	if err := config.DB.First(&trip, quote.TripID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Trip not found",
+			"message": "The quoted trip no longer exists",
+		})
+		return
+	}
+
+	discount, err := voucher.Discount(trip, quote.Total, quote.Currency, time.Now())
+	if err == nil {
+		err = models.CheckVoucherUsage(config.DB, voucher, userID.(uint))
+	}
+	if errors.Is(err, models.ErrVoucherNotApplicable) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Voucher not applicable",
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to validate voucher",
+			"message": "Could not check voucher usage",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Voucher is valid",
+		"data": gin.H{
+			"code":     voucher.Code,
+			"subtotal": quote.Total,
+			"discount": discount,
+			"total":    quote.Total - discount,
+			"currency": quote.Currency,
+		},
+	})
+}
+
+// applyRequest validates req and copies it onto voucher, writing the error response on failure
+func applyRequest(c *gin.Context, voucher *models.Voucher, req VoucherRequest) bool {
+	fail := func(message string) bool {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid voucher",
+			"message": message,
+		})
+		return false
+	}
+
+	switch {
+	case req.Kind == models.VoucherPercent && req.Percent == 0:
+		return fail("Percent vouchers need a percent between 1 and 100")
+	case req.Kind == models.VoucherFixed && req.Amount == 0:
+		return fail("Fixed vouchers need an amount")
+	case req.ValidFrom != nil && req.ValidUntil != nil && !req.ValidUntil.After(*req.ValidFrom):
+		return fail("valid_until must be after valid_from")
+	}
+
+	code := ""
+	if req.Currency != "" {
+		normalized, err := currency.Normalize(req.Currency)
+		if err != nil {
+			return fail(err.Error())
+		}
+		code = normalized
+	}
+	if code == "" && (req.Kind == models.VoucherFixed || req.MinSpend > 0) {
+		return fail("A currency is required for fixed amounts and minimum spends")
+	}
+
+	// Admins pick the scope, trip owners can only create codes for their own trips
+	userID, _ := c.Get("userID")
+	ownerID := voucher.OwnerID
+	if role, _ := c.Get("role"); role == "admin" {
+		ownerID = req.OwnerID
+	} else if ownerID == nil {
+		id := userID.(uint)
+		ownerID = &id
+	}
+
+	voucher.Code = models.NormalizeVoucherCode(req.Code)
+	voucher.Description = req.Description
+	voucher.Kind = req.Kind
+	voucher.Percent = 0
+	voucher.Amount = 0
+	if req.Kind == models.VoucherPercent {
+		voucher.Percent = req.Percent
+	} else {
+		voucher.Amount = req.Amount
+	}
+	voucher.Currency = code
+	voucher.MinSpend = req.MinSpend
+	voucher.MaxRedemptions = req.MaxRedemptions
+	voucher.MaxPerUser = req.MaxPerUser
+	voucher.ValidFrom = req.ValidFrom
+	voucher.ValidUntil = req.ValidUntil
+	voucher.OwnerID = ownerID
+	if req.Active != nil {
+		voucher.Active = *req.Active
+	}
+	return true
+}
+
+// codeTaken reports whether a voucher other than exceptID uses code, deleted vouchers
+// included since the unique index still covers them
+func codeTaken(code string, exceptID uint) bool {
+	var count int64
+	config.DB.Unscoped().Model(&models.Voucher{}).Where("code = ? AND id <> ?", code, exceptID).Count(&count)
+	return count > 0
+}
+
+// findManagedVoucher loads the voucher from the id parameter, admins manage every
+// voucher and trip owners only their own
+func findManagedVoucher(c *gin.Context) (models.Voucher, bool) {
+	var voucher models.Voucher
+	if err := config.DB.First(&voucher, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Voucher not found",
+			"message": "The requested voucher does not exist",
+		})
+		return voucher, false
+	}
+
+	userID, _ := c.Get("userID")
+	if role, _ := c.Get("role"); role != "admin" && (voucher.OwnerID == nil || *voucher.OwnerID != userID.(uint)) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"message": "You can only manage your own vouchers",
+		})
+		return voucher, false
+	}
+
+	return voucher, true
+}
@@ -0,0 +1,191 @@
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Base is the currency exchange rates are quoted against, its rate is always 1
+const Base = "USD"
+
+// Default is the currency trips are priced in unless the owner picks another one
+const Default = "IDR"
+
+var (
+	// ErrUnknownCurrency is returned for codes that are not in the ISO 4217 table below
+	ErrUnknownCurrency = errors.New("unknown currency")
+
+	// ErrMissingRate is returned when no exchange rate is known for a currency
+	ErrMissingRate = errors.New("missing exchange rate")
+)
+
+// exponents holds the ISO 4217 minor unit digits of supported currencies
+var exponents = map[string]int{
+	"AUD": 2,
+	"CAD": 2,
+	"CHF": 2,
+	"CNY": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"HKD": 2,
+	"IDR": 2,
+	"INR": 2,
+	"JPY": 0,
+	"KRW": 0,
+	"MYR": 2,
+	"NZD": 2,
+	"PHP": 2,
+	"SGD": 2,
+	"THB": 2,
+	"USD": 2,
+	"VND": 0,
+}
+
+// Money is an amount in minor units of an ISO currency
+type Money struct {
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+	Formatted string `json:"formatted"`
+}
+
+// Normalize upper-cases code and checks that it is supported
+func Normalize(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if _, exists := exponents[code]; !exists {
+		return "", fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+	return code, nil
+}
+
+// Exponent returns the number of minor unit digits of code
+func Exponent(code string) int {
+	return exponents[code]
+}
+
+// ToMinor converts a major unit amount (e.g. 12.50) to minor units (1250)
+func ToMinor(major float64, code string) int64 {
+	return int64(math.Round(major * math.Pow10(Exponent(code))))
+}
+
+// ToMajor converts minor units back to a major unit amount
+func ToMajor(minor int64, code string) float64 {
+	return float64(minor) / math.Pow10(Exponent(code))
+}
+
+// Format renders an amount such as "IDR 150,000.00" or "JPY 1,200"
+func Format(minor int64, code string) string {
+	exponent := Exponent(code)
+	sign := ""
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+
+	divisor := int64(math.Pow10(exponent))
+	whole := fmt.Sprintf("%d", minor/divisor)
+
+	// Group thousands
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+
+	if exponent == 0 {
+		return fmt.Sprintf("%s %s%s", code, sign, grouped.String())
+	}
+	return fmt.Sprintf("%s %s%s.%0*d", code, sign, grouped.String(), exponent, minor%divisor)
+}
+
+// NewMoney builds a formatted Money value
+func NewMoney(minor int64, code string) Money {
+	return Money{Amount: minor, Currency: code, Formatted: Format(minor, code)}
+}
+
+// Rates maps currency codes to units per one unit of Base
+type Rates map[string]float64
+
+// Rate returns the rate of code, Base is always 1
+func (r Rates) Rate(code string) (float64, error) {
+	if code == Base {
+		return 1, nil
+	}
+	rate, exists := r[code]
+	if !exists || rate <= 0 {
+		return 0, fmt.Errorf("%w for %s", ErrMissingRate, code)
+	}
+	return rate, nil
+}
+
+// Convert converts minor units of from into minor units of to, rounding to the nearest minor unit
+func (r Rates) Convert(minor int64, from, to string) (int64, error) {
+	if from == to {
+		return minor, nil
+	}
+
+	fromRate, err := r.Rate(from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := r.Rate(to)
+	if err != nil {
+		return 0, err
+	}
+
+	major := ToMajor(minor, from) / fromRate * toRate
+	return ToMinor(major, to), nil
+}
+
+// RateTable is the exchange-rate file format, compatible with the usual rate APIs:
+//
+//	{"base": "USD", "rates": {"IDR": 16250.5, "EUR": 0.92}}
+type RateTable struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// Normalize validates the table and rebases it onto Base when it is quoted against another currency
+func (t RateTable) Normalize() (Rates, error) {
+	base := Base
+	if t.Base != "" {
+		code, err := Normalize(t.Base)
+		if err != nil {
+			return nil, err
+		}
+		base = code
+	}
+
+	rates := make(Rates, len(t.Rates))
+	for raw, rate := range t.Rates {
+		code, err := Normalize(raw)
+		if err != nil {
+			return nil, err
+		}
+		if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+			return nil, fmt.Errorf("invalid rate %v for %s", rate, code)
+		}
+		rates[code] = rate
+	}
+	rates[base] = 1
+
+	if base == Base {
+		delete(rates, Base)
+		return rates, nil
+	}
+
+	// Units of Base per one unit of base, every other rate is divided by it
+	baseRate, exists := rates[Base]
+	if !exists {
+		return nil, fmt.Errorf("%w for %s, needed to rebase from %s", ErrMissingRate, Base, base)
+	}
+	rebased := make(Rates, len(rates))
+	for code, rate := range rates {
+		if code != Base {
+			rebased[code] = rate / baseRate
+		}
+	}
+	return rebased, nil
+}
@@ -0,0 +1,128 @@
+package currency
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		minor int64
+		code  string
+		want  string
+	}{
+		{15000000, "IDR", "IDR 150,000.00"},
+		{0, "USD", "USD 0.00"},
+		{5, "USD", "USD 0.05"},
+		{99999, "EUR", "EUR 999.99"},
+		{100000, "EUR", "EUR 1,000.00"},
+		{1200, "JPY", "JPY 1,200"},
+		{999, "JPY", "JPY 999"},
+		{1234567890, "VND", "VND 1,234,567,890"},
+		{-123456, "USD", "USD -1,234.56"},
+		{-1000, "KRW", "KRW -1,000"},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.minor, tt.code); got != tt.want {
+			t.Errorf("Format(%d, %s) = %q, want %q", tt.minor, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestMinorUnits(t *testing.T) {
+	if got := ToMinor(12.5, "USD"); got != 1250 {
+		t.Errorf("ToMinor(12.5, USD) = %d, want 1250", got)
+	}
+	if got := ToMinor(0.29, "EUR"); got != 29 {
+		t.Errorf("ToMinor(0.29, EUR) = %d, want 29", got)
+	}
+	if got := ToMinor(1200.4, "JPY"); got != 1200 {
+		t.Errorf("ToMinor(1200.4, JPY) = %d, want 1200", got)
+	}
+	if got := ToMajor(1250, "USD"); got != 12.5 {
+		t.Errorf("ToMajor(1250, USD) = %v, want 12.5", got)
+	}
+	if got := ToMajor(1200, "JPY"); got != 1200 {
+		t.Errorf("ToMajor(1200, JPY) = %v, want 1200", got)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if code, err := Normalize(" idr "); err != nil || code != "IDR" {
+		t.Errorf("Normalize(idr) = %q, %v, want IDR", code, err)
+	}
+	if _, err := Normalize("XYZ"); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("Normalize(XYZ) = %v, want %v", err, ErrUnknownCurrency)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	rates := Rates{"IDR": 16250, "EUR": 0.92, "JPY": 150}
+
+	tests := []struct {
+		name     string
+		minor    int64
+		from, to string
+		want     int64
+	}{
+		{"same currency", 12345, "IDR", "IDR", 12345},
+		{"from the base", 1000, "USD", "IDR", 16250000},
+		{"to the base", 16250000, "IDR", "USD", 1000},
+		{"between two quoted currencies", 16250000, "IDR", "EUR", 920},
+		{"into a currency without minor units", 1000, "USD", "JPY", 1500},
+		{"out of a currency without minor units", 150, "JPY", "USD", 100},
+		{"rounds to the nearest minor unit", 1, "IDR", "USD", 0},
+		{"rounds half up", 8125, "IDR", "USD", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rates.Convert(tt.minor, tt.from, tt.to)
+			if err != nil || got != tt.want {
+				t.Errorf("Convert(%d, %s, %s) = %d, %v, want %d", tt.minor, tt.from, tt.to, got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := rates.Convert(100, "IDR", "SGD"); !errors.Is(err, ErrMissingRate) {
+		t.Errorf("Convert to SGD = %v, want %v", err, ErrMissingRate)
+	}
+	if _, err := (Rates{"SGD": 0}).Convert(100, "SGD", "USD"); !errors.Is(err, ErrMissingRate) {
+		t.Errorf("Convert from a zero rate = %v, want %v", err, ErrMissingRate)
+	}
+}
+
+func TestRateTableNormalize(t *testing.T) {
+	rates, err := RateTable{Base: "usd", Rates: map[string]float64{"idr": 16250, "USD": 1}}.Normalize()
+	if err != nil || len(rates) != 1 || rates["IDR"] != 16250 {
+		t.Errorf("Normalize USD table = %v, %v, want IDR 16250 only", rates, err)
+	}
+
+	// Quoted against EUR, rebased onto USD
+	rates, err = RateTable{Base: "EUR", Rates: map[string]float64{"USD": 1.25, "IDR": 20000}}.Normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Rates{"EUR": 0.8, "IDR": 16000}
+	if len(rates) != len(want) {
+		t.Errorf("rebased rates = %v, want %v", rates, want)
+	}
+	for code, rate := range want {
+		if math.Abs(rates[code]-rate) > 1e-9 {
+			t.Errorf("rebased %s = %v, want %v", code, rates[code], rate)
+		}
+	}
+
+	invalid := []RateTable{
+		{Base: "EUR", Rates: map[string]float64{"IDR": 20000}},
+		{Rates: map[string]float64{"XYZ": 1}},
+		{Rates: map[string]float64{"IDR": 0}},
+		{Rates: map[string]float64{"IDR": math.Inf(1)}},
+		{Base: "XYZ"},
+	}
+	for _, table := range invalid {
+		if rates, err := table.Normalize(); err == nil {
+			t.Errorf("Normalize(%+v) = %v, want an error", table, rates)
+		}
+	}
+}
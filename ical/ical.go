@@ -0,0 +1,130 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of iCalendar files
+const ContentType = "text/calendar; charset=utf-8"
+
+// prodID identifies the application that produced a calendar
+const prodID = "-//backend-go//trips//EN"
+
+// Event is a VEVENT
+type Event struct {
+	UID         string // Stable across exports so calendar apps update instead of duplicating
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Latitude    *float64
+	Longitude   *float64
+	Status      string // TENTATIVE, CONFIRMED or CANCELLED
+	Updated     time.Time
+}
+
+// Calendar is a VCALENDAR, written as iCalendar (RFC 5545) with the subset of
+// properties calendar apps need to show trips
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// Write writes the calendar with CRLF line endings and lines folded at 75 octets
+func (cal Calendar) Write(w io.Writer) error {
+	return cal.write(w, time.Now())
+}
+
+// write writes the calendar as generated at now. With METHOD:PUBLISH the DTSTAMP of every
+// event is the time the calendar was generated, when the event last changed is LAST-MODIFIED
+func (cal Calendar) write(w io.Writer, now time.Time) error {
+	out := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(out, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", prodID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if cal.Name != "" {
+		line("X-WR-CALNAME", escape(cal.Name))
+	}
+
+	for _, event := range cal.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", escape(event.UID))
+		line("DTSTAMP", formatTime(now))
+		line("DTSTART", formatTime(event.Start))
+		line("DTEND", formatTime(event.End))
+		line("SUMMARY", escape(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION", escape(event.Description))
+		}
+		if event.Location != "" {
+			line("LOCATION", escape(event.Location))
+		}
+		if event.Latitude != nil && event.Longitude != nil {
+			line("GEO", fmt.Sprintf("%.6f;%.6f", *event.Latitude, *event.Longitude))
+		}
+		if event.URL != "" {
+			line("URL", event.URL)
+		}
+		if event.Status != "" {
+			line("STATUS", event.Status)
+		}
+		if !event.Updated.IsZero() {
+			line("LAST-MODIFIED", formatTime(event.Updated))
+		}
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+	return out.Flush()
+}
+
+// formatTime formats t as a UTC date-time
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escape escapes a TEXT value
+func escape(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(value)
+}
+
+// writeFolded writes a content line, folding it so no physical line exceeds 75 octets
+// and never splitting a multi-byte character
+func writeFolded(out *bufio.Writer, content string) {
+	limit := 75
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(content[cut]) {
+			cut--
+		}
+		out.WriteString(content[:cut])
+		out.WriteString("\r\n ")
+		content = content[cut:]
+		limit = 74 // Continuation lines start with a space
+	}
+	out.WriteString(content)
+	out.WriteString("\r\n")
+}
+
+// isRuneStart reports whether b starts a UTF-8 sequence
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
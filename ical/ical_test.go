@@ -0,0 +1,69 @@
+package ical
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestCalendarWrite(t *testing.T) {
+	latitude, longitude := -8.409518, 115.188919
+	calendar := Calendar{
+		Name: "Bali, Lombok; and more",
+		Events: []Event{
+			{
+				UID:         "booking-1@example.com",
+				Start:       time.Date(2026, 11, 1, 8, 0, 0, 0, time.FixedZone("WITA", 8*60*60)),
+				End:         time.Date(2026, 11, 3, 17, 0, 0, 0, time.FixedZone("WITA", 8*60*60)),
+				Summary:     "Sunrise trek, Mount Batur; breakfast at the summit",
+				Description: "Bring a jacket\\torch.\r\nPick-up at 2:00, from the hotel lobby.\nSampai jumpa di Kintamani — perjalanan yang sangat indah menuju puncak gunung berapi aktif",
+				Location:    "Kintamani, Bali",
+				URL:         "https://example.com/api/v1/bookings/1",
+				Latitude:    &latitude,
+				Longitude:   &longitude,
+				Status:      "CONFIRMED",
+				Updated:     time.Date(2026, 10, 2, 9, 30, 0, 0, time.UTC),
+			},
+			{
+				UID:     "departure-2@example.com",
+				Start:   time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC),
+				End:     time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC),
+				Summary: "Ñandú ✈ 日本語のツアー日本語のツアー日本語のツアー日本語のツアー",
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	if err := calendar.write(&out, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "calendar.golden")
+	if *update {
+		if err := os.WriteFile(golden, out.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("Write =\n%s\nwant\n%s", out.Bytes(), want)
+	}
+
+	for i, line := range strings.Split(strings.TrimSuffix(out.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line %d is %d octets: %q", i+1, len(line), line)
+		}
+		if strings.ContainsAny(line, "\r\n") {
+			t.Errorf("line %d has a bare line break: %q", i+1, line)
+		}
+	}
+}
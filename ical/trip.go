@@ -0,0 +1,23 @@
+package ical
+
+import (
+	"backend-go/models"
+	"fmt"
+	"time"
+)
+
+// TripEvent builds the event of a trip starting at start, url links back to the trip
+func TripEvent(uid string, trip models.Trip, start time.Time, url string) Event {
+	return Event{
+		UID:         uid,
+		Start:       start,
+		End:         trip.EndsAt(start),
+		Summary:     trip.Name,
+		Description: trip.Description,
+		Location:    fmt.Sprintf("%.6f, %.6f", trip.StartLatitude, trip.StartLongitude),
+		URL:         url,
+		Latitude:    &trip.StartLatitude,
+		Longitude:   &trip.StartLongitude,
+		Updated:     trip.UpdatedAt,
+	}
+}
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"backend-go/commands"
 	"backend-go/config"
+	"backend-go/controllers/booking"
+	"backend-go/controllers/image"
 	"backend-go/models"
+	"backend-go/notify"
+	"backend-go/payments"
+	"backend-go/reconcile"
 	"backend-go/routes"
 	"log"
+	"os"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -25,16 +32,44 @@ func main() {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	// Run a CLI command instead of the server, e.g. "go run main.go seed -file ..."
+	if len(os.Args) > 1 {
+		if err := commands.Run(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Refuse to start without a payment gateway rather than failing the first payment
+	payments.Gateway()
+
+	// Send refunds again that the gateway never confirmed
+	payments.StartRefundRetries()
+
+	// Deliver queued notifications in the background
+	notify.Start()
+
+	// Cancel unpaid bookings whose hold ran out in the background
+	go booking.ExpireHolds()
+
+	// Discard abandoned resumable uploads in the background
+	go image.ExpireUploads()
+
+	// Clean up orphaned image files and rows when IMAGE_GC_INTERVAL is set
+	reconcile.Start()
+
 	// Initialize Gin router
 	router := gin.Default()
 
 	// Add CORS middleware
 	router.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, Range, If-None-Match, Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata")
+		c.Header("Access-Control-Expose-Headers", "ETag, Location, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Upload-Offset, Upload-Length, Upload-Expires, Upload-Image-Id")
 
-		if c.Request.Method == "OPTIONS" {
+		// Answer preflights here, plain OPTIONS requests reach the routes for tus discovery
+		if c.Request.Method == "OPTIONS" && c.GetHeader("Access-Control-Request-Method") != "" {
 			c.AbortWithStatus(204)
 			return
 		}
@@ -0,0 +1,35 @@
+package media
+
+import (
+	"image"
+	"math/bits"
+
+	"golang.org/x/image/draw"
+)
+
+// NearDuplicateDistance is how many bits two fingerprints may differ in for their images to count as
+// near-duplicates, e.g. the same photo resized, recompressed or lightly edited
+const NearDuplicateDistance = 10
+
+// Fingerprint returns the difference hash of an image: the image is shrunk to 9x8 grey pixels and
+// each of the 64 bits says whether a pixel is brighter than its right neighbour
+func Fingerprint(img image.Image) uint64 {
+	grey := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.BiLinear.Scale(grey, grey.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if grey.GrayAt(x, y).Y > grey.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// Distance returns how many bits two fingerprints differ in
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
@@ -0,0 +1,206 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+var errMalformed = errors.New("malformed image structure")
+
+// stripJPEG drops the APP1 (EXIF, GPS, XMP, maker notes), APP13 (IPTC) and comment segments
+// without re-encoding. Colour profiles and other segments needed to decode are kept. The EXIF
+// orientation survives in a minimal APP1 of its own so phone photos are still displayed upright.
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, errMalformed
+	}
+
+	var kept [][]byte
+	var orientation uint16
+	pos := 2
+	for {
+		if pos+4 > len(data) || data[pos] != 0xFF {
+			return nil, errMalformed
+		}
+		marker := data[pos+1]
+		if marker == 0xFF { // Fill byte
+			pos++
+			continue
+		}
+		if marker == 0xDA { // Start of scan, the entropy coded data and the rest follow
+			break
+		}
+
+		end := pos + 2 + int(binary.BigEndian.Uint16(data[pos+2:]))
+		if end < pos+4 || end > len(data) {
+			return nil, errMalformed
+		}
+		segment := data[pos:end]
+
+		switch marker {
+		case 0xE1:
+			if orientation == 0 {
+				orientation = exifOrientation(segment[4:])
+			}
+		case 0xED, 0xFE:
+			// Dropped
+		default:
+			kept = append(kept, segment)
+		}
+		pos = end
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+	// The orientation goes right after a leading JFIF header, where viewers expect EXIF
+	if len(kept) > 0 && kept[0][1] == 0xE0 {
+		out.Write(kept[0])
+		kept = kept[1:]
+	}
+	if orientation > 1 {
+		out.Write(orientationSegment(orientation))
+	}
+	for _, segment := range kept {
+		out.Write(segment)
+	}
+	out.Write(data[pos:])
+	return out.Bytes(), nil
+}
+
+// exifOrientation reads the orientation tag from IFD0 of an EXIF APP1 payload, 0 when absent
+func exifOrientation(payload []byte) uint16 {
+	if len(payload) < 14 || string(payload[:6]) != "Exif\x00\x00" {
+		return 0
+	}
+	tiff := payload[6:]
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 0
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			value := order.Uint16(tiff[entry+8:])
+			if value >= 1 && value <= 8 {
+				return value
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// orientationSegment builds an APP1 segment whose EXIF holds only the orientation tag
+func orientationSegment(orientation uint16) []byte {
+	tiff := []byte{
+		'M', 'M', 0x00, 0x2A, // Big endian TIFF header
+		0x00, 0x00, 0x00, 0x08, // IFD0 right after the header
+		0x00, 0x01, // One entry
+		0x01, 0x12, 0x00, 0x03, // Orientation, SHORT
+		0x00, 0x00, 0x00, 0x01, // Count
+		byte(orientation >> 8), byte(orientation), 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, // No next IFD
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// pngMetadataChunks are the ancillary PNG chunks that carry metadata
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// stripPNG drops the metadata chunks of a PNG, leaving image data and colour information untouched
+func stripPNG(data []byte) ([]byte, error) {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if len(data) < len(signature) || string(data[:len(signature)]) != signature {
+		return nil, errMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.WriteString(signature)
+
+	pos := len(signature)
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, errMalformed
+		}
+		kind := string(data[pos+4 : pos+8])
+		if !pngMetadataChunks[kind] {
+			chunk := data[pos:end]
+			if crc32.ChecksumIEEE(chunk[4:8+length]) != binary.BigEndian.Uint32(chunk[8+length:]) {
+				return nil, errMalformed
+			}
+			out.Write(chunk)
+		}
+		pos = end
+		if kind == "IEND" {
+			return out.Bytes(), nil
+		}
+	}
+	return nil, errMalformed
+}
+
+// stripWebP drops the EXIF and XMP chunks of a WebP file and clears their flags in the VP8X header
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:12])
+
+	pos := 12
+	for pos+8 <= len(data) {
+		kind := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		if size < 0 || pos+8+size > len(data) {
+			return nil, errMalformed
+		}
+		end := min(pos+8+size+size%2, len(data)) // Chunks are padded to an even size
+
+		switch kind {
+		case "EXIF", "XMP ":
+			// Metadata, dropped
+		case "VP8X":
+			chunk := append([]byte{}, data[pos:end]...)
+			if size > 0 {
+				chunk[8] &^= 0x08 | 0x04 // EXIF and XMP flags
+			}
+			out.Write(chunk)
+		default:
+			out.Write(data[pos:end])
+		}
+		pos = end
+	}
+
+	result := out.Bytes()
+	binary.LittleEndian.PutUint32(result[4:], uint32(len(result)-8))
+	return result, nil
+}
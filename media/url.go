@@ -0,0 +1,41 @@
+package media
+
+import (
+	"os"
+	"strings"
+)
+
+// routePrefix is where the API serves stored images, a key such as covers/x.jpg is served at
+// /api/v1/covers/x.jpg
+const routePrefix = "/api/v1/"
+
+// ConfiguredBaseURL returns the origin image URLs are built from when it is configured: MEDIA_BASE_URL,
+// typically a CDN in front of the image routes, or else BASE_URL. It is empty when neither is set.
+func ConfiguredBaseURL() string {
+	if base := os.Getenv("MEDIA_BASE_URL"); base != "" {
+		return strings.TrimSuffix(base, "/")
+	}
+	return strings.TrimSuffix(os.Getenv("BASE_URL"), "/")
+}
+
+// RoutePath returns the root-relative path a stored key is served at
+func RoutePath(key string) string {
+	return routePrefix + key
+}
+
+// KeyFromPath returns the stored key a root-relative path produced by RoutePath points at
+func KeyFromPath(p string) (string, bool) {
+	if !strings.HasPrefix(p, routePrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(p, routePrefix), true
+}
+
+// PublicURL builds the URL a stored key is served at. MEDIA_BASE_URL takes precedence over base so a
+// CDN wins over the request's own origin, an empty base gives a root-relative URL.
+func PublicURL(base, key string) string {
+	if cdn := os.Getenv("MEDIA_BASE_URL"); cdn != "" {
+		base = cdn
+	}
+	return strings.TrimSuffix(base, "/") + RoutePath(key)
+}
@@ -0,0 +1,113 @@
+package media
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"io"
+	"net/http"
+)
+
+var (
+	// ErrTooLarge is returned for files over the size limit
+	ErrTooLarge = errors.New("file is too large")
+
+	// ErrUnsupportedType is returned when the content is not a JPEG, PNG, GIF or WebP image
+	ErrUnsupportedType = errors.New("unsupported file type, upload a JPEG, PNG, GIF or WebP image")
+
+	// ErrInvalidImage is returned when the content claims to be an image but does not decode
+	ErrInvalidImage = errors.New("file is not a valid image")
+)
+
+// MaxPixels caps width times height so small files cannot expand into huge bitmaps when decoded
+const MaxPixels = 50_000_000
+
+// allowedTypes are the MIME types accepted for upload, detected from the content
+var allowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Processed is an upload that passed validation, with its metadata stripped
+type Processed struct {
+	Data        []byte
+	MimeType    string
+	Ext         string
+	Width       int
+	Height      int
+	SHA256      string // Hex digest of Data, the stored file's content hash
+	Fingerprint uint64 // Perceptual hash to spot near-duplicates
+}
+
+// Process reads an upload of at most maxSize bytes, detects its type from its magic bytes,
+// decodes it to make sure it is a real image and strips EXIF, GPS and other metadata.
+// The client's filename and Content-Type are never trusted.
+func Process(r io.Reader, maxSize int64) (Processed, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return Processed{}, err
+	}
+	if int64(len(data)) > maxSize {
+		return Processed{}, ErrTooLarge
+	}
+
+	mimeType := http.DetectContentType(data)
+	if !allowedTypes[mimeType] {
+		return Processed{}, ErrUnsupportedType
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || "image/"+format != mimeType {
+		return Processed{}, ErrInvalidImage
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return Processed{}, fmt.Errorf("%w: %dx%d pixels is over the limit", ErrInvalidImage, config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, ErrInvalidImage
+	}
+
+	switch mimeType {
+	case "image/jpeg":
+		data, err = stripJPEG(data)
+	case "image/png":
+		data, err = stripPNG(data)
+	case "image/webp":
+		data, err = stripWebP(data)
+	case "image/gif":
+		data, err = stripGIF(data)
+	}
+	if err != nil {
+		return Processed{}, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	sum := sha256.Sum256(data)
+	return Processed{
+		Data:        data,
+		MimeType:    mimeType,
+		Ext:         Extension(mimeType),
+		Width:       config.Width,
+		Height:      config.Height,
+		SHA256:      hex.EncodeToString(sum[:]),
+		Fingerprint: Fingerprint(img),
+	}, nil
+}
+
+// stripGIF re-encodes a GIF, which keeps every frame and the loop count but drops
+// comments and application extensions such as XMP
+func stripGIF(data []byte) ([]byte, error) {
+	decoded, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	err = gif.EncodeAll(&out, decoded)
+	return out.Bytes(), err
+}
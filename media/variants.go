@@ -0,0 +1,142 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif" // GIF uploads are resized from their first frame
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // Register the WebP decoder with image.Decode
+)
+
+// Variant is a resized rendition of an image, never wider than Width and never upscaled
+type Variant struct {
+	Name  string `json:"name"`
+	Width int    `json:"width"`
+}
+
+// Variants lists the renditions served next to the original, smallest first
+var Variants = []Variant{
+	{Name: "thumb", Width: 150},
+	{Name: "medium", Width: 600},
+	{Name: "large", Width: 1200},
+}
+
+// jpegQuality is the quality variants are encoded with
+const jpegQuality = 82
+
+// VariantFolder caches resized renditions in storage, generated on first request. The variant of
+// images/x.jpg is kept at variants/images/<variant>/x.jpg or .png, depending on its format.
+const VariantFolder = "variants"
+
+// variantExtensions are the formats Resize produces
+var variantExtensions = []string{".jpg", ".png"}
+
+// VariantKey returns the storage key of a variant of the stored key, in the format of ext
+func VariantKey(key string, variant Variant, ext string) string {
+	base := strings.TrimSuffix(path.Base(key), path.Ext(key))
+	return path.Join(VariantFolder, path.Dir(key), variant.Name, base+ext)
+}
+
+// VariantKeys returns every key a variant of the stored key may be cached under
+func VariantKeys(key string, variant Variant) []string {
+	keys := make([]string, 0, len(variantExtensions))
+	for _, ext := range variantExtensions {
+		keys = append(keys, VariantKey(key, variant, ext))
+	}
+	return keys
+}
+
+// AllVariantKeys returns every key any variant of the stored key may be cached under
+func AllVariantKeys(key string) []string {
+	var keys []string
+	for _, variant := range Variants {
+		keys = append(keys, VariantKeys(key, variant)...)
+	}
+	return keys
+}
+
+// VariantSource returns the stored key a cached variant was rendered from, without its extension
+func VariantSource(variantKey string) (string, bool) {
+	rest, ok := strings.CutPrefix(variantKey, VariantFolder+"/")
+	if !ok {
+		return "", false
+	}
+	dir, name := path.Split(rest)
+	folder := path.Dir(path.Clean(dir)) // Drop the variant name
+	if folder == "." || name == "" {
+		return "", false
+	}
+	return path.Join(folder, strings.TrimSuffix(name, path.Ext(name))), true
+}
+
+// LookupVariant returns a variant by name
+func LookupVariant(name string) (Variant, bool) {
+	for _, variant := range Variants {
+		if variant.Name == name {
+			return variant, true
+		}
+	}
+	return Variant{}, false
+}
+
+// VariantForWidth returns the smallest variant at least width wide, or the largest one. Snapping
+// requested widths to variants keeps the number of cached renditions bounded.
+func VariantForWidth(width int) Variant {
+	for _, variant := range Variants {
+		if variant.Width >= width {
+			return variant
+		}
+	}
+	return Variants[len(Variants)-1]
+}
+
+// Resize decodes an image and scales it down to width, keeping its aspect ratio. JPEG sources
+// stay JPEG, anything else becomes PNG so transparency survives. It returns the encoded image
+// and its MIME type.
+func Resize(src io.Reader, width int) ([]byte, string, error) {
+	img, format, err := image.Decode(src)
+	if err != nil {
+		return nil, "", err
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dx() <= 0 || bounds.Dy() <= 0 {
+		return nil, "", errors.New("image has no pixels")
+	}
+	if bounds.Dx() > width {
+		height := max(1, bounds.Dy()*width/bounds.Dx())
+		scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Over, nil)
+		img = scaled
+	}
+
+	var out bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: jpegQuality})
+		return out.Bytes(), "image/jpeg", err
+	}
+	err = png.Encode(&out, img)
+	return out.Bytes(), "image/png", err
+}
+
+// Extension returns the file extension for a MIME type produced by this package
+func Extension(mimeType string) string {
+	switch mimeType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	}
+	return ""
+}
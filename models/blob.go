@@ -0,0 +1,83 @@
+package models
+
+import (
+	"backend-go/media"
+	"errors"
+	"path"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BlobFolder keeps uploaded files under their content hash, e.g. blobs/3f/3fa2...e1.jpg
+const BlobFolder = "blobs"
+
+// Blob is a stored file, kept once however many images show it. The same photo uploaded to
+// several trips is stored once and each of its images holds a reference.
+type Blob struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	StorageKey string    `json:"-" gorm:"not null;uniqueIndex"`
+	SHA256     *string   `json:"sha256" gorm:"column:sha256;size:64;uniqueIndex"` // Unset for files stored before deduplication
+	Size       int64     `json:"size"`
+	MimeType   string    `json:"mime_type"`
+	RefCount   int       `json:"ref_count" gorm:"not null;default:0"` // Live images using the blob, it goes with the last one
+}
+
+// BlobKey returns the storage key of the content with the hex SHA-256 hash
+func BlobKey(hash, ext string) string {
+	return path.Join(BlobFolder, hash[:2], hash+ext)
+}
+
+// AcquireBlob takes a reference to the blob of the content with the hex SHA-256 hash, creating it
+// when the content is new. A RefCount of 1 means the caller must store the file, within the same
+// transaction so a concurrent upload of the same content waits until the file is there.
+func AcquireBlob(tx *gorm.DB, hash, ext string, size int64, mimeType string) (Blob, error) {
+	var blob Blob
+	now := time.Now()
+	err := tx.Raw(`INSERT INTO blobs (created_at, updated_at, storage_key, sha256, size, mime_type, ref_count)
+		VALUES (?, ?, ?, ?, ?, ?, 1)
+		ON CONFLICT (sha256) DO UPDATE SET ref_count = blobs.ref_count + 1, updated_at = EXCLUDED.updated_at
+		RETURNING *`, now, now, BlobKey(hash, ext), hash, size, mimeType).Scan(&blob).Error
+	return blob, err
+}
+
+// ReleaseBlob drops an image's reference to its blob. When that was the last reference the blob
+// is deleted and the storage keys of its file and cached variants are returned for the caller to
+// delete, before the transaction commits so a new upload of the same content stores it again.
+// Images from before deduplication own their file outright. Call it only when deleting the image
+// row affected exactly one row, or a concurrent delete of the same image releases the blob twice.
+func ReleaseBlob(tx *gorm.DB, image Image) ([]string, error) {
+	if image.BlobID == nil {
+		return append([]string{image.StorageKey}, media.AllVariantKeys(image.StorageKey)...), nil
+	}
+
+	var blob Blob
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&blob, *image.BlobID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if blob.RefCount > 1 {
+		return nil, tx.Model(&blob).UpdateColumn("ref_count", gorm.Expr("ref_count - 1")).Error
+	}
+	if err := tx.Delete(&blob).Error; err != nil {
+		return nil, err
+	}
+	return append([]string{blob.StorageKey}, media.AllVariantKeys(blob.StorageKey)...), nil
+}
+
+// FileKey returns the storage key of the file an image shows
+func FileKey(db *gorm.DB, image Image) (string, error) {
+	if image.BlobID == nil {
+		return image.StorageKey, nil
+	}
+	var blob Blob
+	if err := db.Select("storage_key").First(&blob, *image.BlobID).Error; err != nil {
+		return "", err
+	}
+	return blob.StorageKey, nil
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Booking statuses
+const (
+	BookingPending   = "pending"
+	BookingConfirmed = "confirmed"
+	BookingCancelled = "cancelled"
+	BookingCompleted = "completed"
+)
+
+// BookingHold is how long an unpaid booking holds its seats, it is cancelled when no payment went
+// through by then
+const BookingHold = 30 * time.Minute
+
+// bookingTransitions lists the statuses each status can move to
+var bookingTransitions = map[string][]string{
+	BookingPending:   {BookingConfirmed, BookingCancelled},
+	BookingConfirmed: {BookingCancelled, BookingCompleted},
+}
+
+// Booking reserves seats on one occurrence (StartsAt) of a departure
+type Booking struct {
+	gorm.Model
+	TripID       uint       `json:"trip_id" gorm:"not null;index"`
+	Trip         Trip       `json:"trip" gorm:"foreignKey:TripID"`
+	DepartureID  uint       `json:"departure_id" gorm:"not null;index:idx_booking_slot"`
+	StartsAt     time.Time  `json:"starts_at" gorm:"not null;index:idx_booking_slot"`
+	UserID       uint       `json:"user_id" gorm:"not null;index"`
+	User         User       `json:"user" gorm:"foreignKey:UserID"`
+	Participants int        `json:"participants" gorm:"not null"`
+	Amount       int64      `json:"amount" gorm:"not null;default:0"` // Total due in minor units of Currency
+	Currency     string     `json:"currency" gorm:"type:varchar(3);not null;default:IDR"`
+	Status       string     `json:"status" gorm:"not null;type:varchar(20);default:pending;check:status IN ('pending', 'confirmed', 'cancelled', 'completed')"`
+	CancelledAt  *time.Time `json:"cancelled_at"`
+
+	// When a pending booking with an amount due gives up its seats unless it is paid
+	HoldExpiresAt *time.Time `json:"hold_expires_at" gorm:"index"`
+
+	// Voucher redeemed on the booking, Discount is already taken off Amount
+	Discount  int64 `json:"discount" gorm:"not null;default:0"`
+	VoucherID *uint `json:"voucher_id" gorm:"index"`
+
+	// Quote the booking was made against, Amount is its total less Discount
+	QuoteID *uint  `json:"quote_id" gorm:"uniqueIndex"`
+	Quote   *Quote `json:"quote,omitempty" gorm:"foreignKey:QuoteID"`
+
+	// Snapshot of the trip's cancellation policy when the booking was made
+	CancellationRules CancellationRules `json:"cancellation_rules" gorm:"type:text;serializer:json"`
+}
+
+// CanTransition reports whether a booking may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, next := range bookingTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// HoldExpired reports whether the booking is still unpaid after its hold ran out at now
+func (b Booking) HoldExpired(now time.Time) bool {
+	return b.Status == BookingPending && b.HoldExpiresAt != nil && !now.Before(*b.HoldExpiresAt)
+}
+
+// ActiveBookingStatuses are the statuses that hold seats
+var ActiveBookingStatuses = []string{BookingPending, BookingConfirmed}
+
+// BookedSeats sums the participants of active bookings on one occurrence of a departure
+func BookedSeats(tx *gorm.DB, departureID uint, startsAt time.Time) (int, error) {
+	var booked int
+	err := tx.Model(&Booking{}).
+		Where("departure_id = ? AND starts_at = ? AND status IN ?", departureID, startsAt, ActiveBookingStatuses).
+		Select("COALESCE(SUM(participants), 0)").
+		Scan(&booked).Error
+	return booked, err
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBookingHoldExpired(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	earlier, later := now.Add(-time.Minute), now.Add(time.Minute)
+
+	tests := []struct {
+		name    string
+		booking Booking
+		want    bool
+	}{
+		{"pending past its hold", Booking{Status: BookingPending, HoldExpiresAt: &earlier}, true},
+		{"pending at the end of its hold", Booking{Status: BookingPending, HoldExpiresAt: &now}, true},
+		{"pending within its hold", Booking{Status: BookingPending, HoldExpiresAt: &later}, false},
+		{"pending without a hold", Booking{Status: BookingPending}, false},
+		{"confirmed past its hold", Booking{Status: BookingConfirmed, HoldExpiresAt: &earlier}, false},
+		{"cancelled past its hold", Booking{Status: BookingCancelled, HoldExpiresAt: &earlier}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.booking.HoldExpired(now); got != tt.want {
+			t.Errorf("%s: HoldExpired = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"backend-go/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CalendarFeed holds the secret token of a user's iCalendar feed URL
+type CalendarFeed struct {
+	gorm.Model
+	UserID uint   `json:"user_id" gorm:"not null;uniqueIndex"`
+	Token  string `json:"token" gorm:"not null;uniqueIndex;type:varchar(64)"`
+}
+
+// EnsureCalendarFeed returns the user's feed, creating it with a fresh token on first use
+func EnsureCalendarFeed(db *gorm.DB, userID uint) (CalendarFeed, error) {
+	feed := CalendarFeed{UserID: userID, Token: utils.RandomToken()}
+	if err := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "user_id"}}, DoNothing: true}).Create(&feed).Error; err != nil {
+		return feed, err
+	}
+
+	err := db.Where("user_id = ?", userID).First(&feed).Error
+	return feed, err
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// CancellationRule refunds RefundPercent of the paid amount when a booking is
+// cancelled at least MinHoursBefore hours before the departure starts
+type CancellationRule struct {
+	MinHoursBefore int `json:"min_hours_before" binding:"min=0"`
+	RefundPercent  int `json:"refund_percent" binding:"min=0,max=100"`
+}
+
+// CancellationRules is an ordered set of rules, an empty set means no policy applies
+type CancellationRules []CancellationRule
+
+// RefundPercent picks the rule with the largest MinHoursBefore that still applies.
+// Without rules the visitor gets a full refund, with rules and none matching nothing.
+func (rules CancellationRules) RefundPercent(hoursBefore float64) int {
+	// A policy saved without rules is snapshotted as an empty, not nil, set
+	if len(rules) == 0 {
+		return 100
+	}
+
+	sorted := append(CancellationRules{}, rules...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].MinHoursBefore > sorted[j].MinHoursBefore
+	})
+
+	for _, rule := range sorted {
+		if hoursBefore >= float64(rule.MinHoursBefore) {
+			return rule.RefundPercent
+		}
+	}
+	return 0
+}
+
+// CancellationPolicy is a reusable template of rules an owner attaches to trips
+type CancellationPolicy struct {
+	gorm.Model
+	UserID uint              `json:"user_id" gorm:"not null;index"`
+	Name   string            `json:"name" gorm:"not null"`
+	Rules  CancellationRules `json:"rules" gorm:"type:text;serializer:json"`
+}
+
+// Cancellation initiators
+const (
+	CancelledByVisitor = "visitor"
+	CancelledByOwner   = "owner"
+)
+
+// RefundDecision records how the refund of a cancelled booking was computed
+type RefundDecision struct {
+	gorm.Model
+	BookingID            uint              `json:"booking_id" gorm:"not null;index"`
+	CancelledBy          uint              `json:"cancelled_by" gorm:"not null"`
+	Initiator            string            `json:"initiator" gorm:"not null;type:varchar(20)"`
+	Reason               string            `json:"reason"`
+	Rules                CancellationRules `json:"rules" gorm:"type:text;serializer:json"`
+	HoursBeforeDeparture float64           `json:"hours_before_departure"`
+	RefundPercent        int               `json:"refund_percent"`
+	PaidAmount           int64             `json:"paid_amount"`
+	RefundAmount         int64             `json:"refund_amount"`
+	Currency             string            `json:"currency" gorm:"type:varchar(3)"`
+}
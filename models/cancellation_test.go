@@ -0,0 +1,39 @@
+package models
+
+import "testing"
+
+func TestRefundPercent(t *testing.T) {
+	moderate := CancellationRules{
+		{MinHoursBefore: 48, RefundPercent: 50},
+		{MinHoursBefore: 168, RefundPercent: 100},
+	}
+
+	tests := []struct {
+		name        string
+		rules       CancellationRules
+		hoursBefore float64
+		want        int
+	}{
+		{"no policy", nil, 1, 100},
+		{"policy without rules", CancellationRules{}, 1, 100},
+		{"well ahead", moderate, 200, 100},
+		{"exactly at the larger threshold", moderate, 168, 100},
+		{"just under the larger threshold", moderate, 167.9, 50},
+		{"exactly at the smaller threshold", moderate, 48, 50},
+		{"too late", moderate, 47.5, 0},
+		{"after the start", moderate, -2, 0},
+		{"rule from the start", CancellationRules{{MinHoursBefore: 0, RefundPercent: 20}}, 0.5, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rules.RefundPercent(tt.hoursBefore); got != tt.want {
+				t.Errorf("RefundPercent(%v) = %d, want %d", tt.hoursBefore, got, tt.want)
+			}
+		})
+	}
+
+	// Sorting works on a copy, the booking's snapshot keeps its order
+	if moderate[0].MinHoursBefore != 48 {
+		t.Errorf("RefundPercent reordered the rules: %+v", moderate)
+	}
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"backend-go/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Conversation is a message thread between a visitor and a trip owner about a trip, or about
+// one booking when BookingID is set. There is one thread per booking and one per trip and visitor.
+type Conversation struct {
+	gorm.Model
+	TripID        uint      `json:"trip_id" gorm:"not null;index;uniqueIndex:idx_conversation_trip_visitor,where:booking_id IS NULL"`
+	Trip          Trip      `json:"trip" gorm:"foreignKey:TripID"`
+	BookingID     *uint     `json:"booking_id" gorm:"uniqueIndex"`
+	VisitorID     uint      `json:"visitor_id" gorm:"not null;index;uniqueIndex:idx_conversation_trip_visitor"`
+	Visitor       User      `json:"visitor" gorm:"foreignKey:VisitorID"`
+	OwnerID       uint      `json:"owner_id" gorm:"not null;index"`
+	Owner         User      `json:"owner" gorm:"foreignKey:OwnerID"`
+	LastMessageAt time.Time `json:"last_message_at" gorm:"not null;index"`
+	Messages      []Message `json:"messages,omitempty" gorm:"foreignKey:ConversationID"`
+
+	// Computed for the current user, never stored
+	UnreadCount     int64    `json:"unread_count" gorm:"-"`
+	LastMessage     *Message `json:"last_message,omitempty" gorm:"-"`
+	ContactsVisible bool     `json:"contacts_visible" gorm:"-"`
+}
+
+// Message is one message of a conversation, ReadAt is set once the recipient has read it
+type Message struct {
+	gorm.Model
+	ConversationID uint       `json:"conversation_id" gorm:"not null;index"`
+	SenderID       uint       `json:"sender_id" gorm:"not null"`
+	Body           string     `json:"body" gorm:"type:text;not null"`
+	ReadAt         *time.Time `json:"read_at"`
+
+	// Set when contact details were hidden from Body, never stored
+	Masked bool `json:"masked" gorm:"-"`
+}
+
+// HasParticipant reports whether the user is the visitor or the owner of the conversation
+func (conv Conversation) HasParticipant(userID uint) bool {
+	return conv.VisitorID == userID || conv.OwnerID == userID
+}
+
+// MaskContacts hides phone numbers and email addresses in the message body
+func (m *Message) MaskContacts() {
+	m.Body, m.Masked = utils.MaskContactDetails(m.Body)
+}
+
+// ContactsVisibleIn finds which conversations may show contact details: those whose visitor has a
+// confirmed or completed booking on the trip. The result is keyed by conversation ID.
+func ContactsVisibleIn(db *gorm.DB, conversations []Conversation) (map[uint]bool, error) {
+	visible := make(map[uint]bool, len(conversations))
+	if len(conversations) == 0 {
+		return visible, nil
+	}
+
+	tripIDs := make([]uint, len(conversations))
+	visitorIDs := make([]uint, len(conversations))
+	for i, conv := range conversations {
+		tripIDs[i] = conv.TripID
+		visitorIDs[i] = conv.VisitorID
+	}
+
+	var bookings []Booking
+	if err := db.Select("trip_id", "user_id").
+		Where("trip_id IN ? AND user_id IN ? AND status IN ?", tripIDs, visitorIDs, []string{BookingConfirmed, BookingCompleted}).
+		Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+
+	type pair struct{ tripID, visitorID uint }
+	confirmed := make(map[pair]bool, len(bookings))
+	for _, booking := range bookings {
+		confirmed[pair{booking.TripID, booking.UserID}] = true
+	}
+	for _, conv := range conversations {
+		visible[conv.ID] = confirmed[pair{conv.TripID, conv.VisitorID}]
+	}
+	return visible, nil
+}
+
+// UnreadMessageCounts counts the messages the user has not read yet in each conversation
+func UnreadMessageCounts(db *gorm.DB, userID uint, conversationIDs []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(conversationIDs))
+	if len(conversationIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		ConversationID uint
+		Unread         int64
+	}
+	err := db.Model(&Message{}).
+		Select("conversation_id, COUNT(*) AS unread").
+		Where("conversation_id IN ? AND sender_id <> ? AND read_at IS NULL", conversationIDs, userID).
+		Group("conversation_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.ConversationID] = row.Unread
+	}
+	return counts, nil
+}
+
+// LatestMessages loads the most recent message of each conversation, keyed by conversation ID
+func LatestMessages(db *gorm.DB, conversationIDs []uint) (map[uint]Message, error) {
+	latest := make(map[uint]Message, len(conversationIDs))
+	if len(conversationIDs) == 0 {
+		return latest, nil
+	}
+
+	var messages []Message
+	if err := db.Raw(`SELECT DISTINCT ON (conversation_id) * FROM messages
+		WHERE conversation_id IN ? AND deleted_at IS NULL
+		ORDER BY conversation_id, created_at DESC, id DESC`, conversationIDs).
+		Scan(&messages).Error; err != nil {
+		return nil, err
+	}
+	for _, message := range messages {
+		latest[message.ConversationID] = message
+	}
+	return latest, nil
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/teambition/rrule-go"
+	"gorm.io/gorm"
+)
+
+// Departure schedules when a trip runs, either once at StartsAt or repeatedly
+// following RRule with StartsAt as the first occurrence (DTSTART)
+type Departure struct {
+	gorm.Model
+	TripID        uint                `json:"trip_id" gorm:"not null;index"`
+	StartsAt      time.Time           `json:"starts_at" gorm:"not null"`
+	RRule         string              `json:"rrule"`                                    // e.g. "FREQ=WEEKLY;BYDAY=SA,SU", empty for one-off departures
+	Timezone      string              `json:"timezone" gorm:"not null;default:UTC"`     // IANA zone the rule and blackout dates are evaluated in
+	Capacity      int                 `json:"capacity" gorm:"not null"`                 // Seats per occurrence
+	CutoffMinutes int                 `json:"cutoff_minutes" gorm:"not null;default:0"` // Booking closes this long before an occurrence starts
+	Blackouts     []DepartureBlackout `json:"blackouts,omitempty" gorm:"foreignKey:DepartureID"`
+}
+
+// DepartureBlackout removes every occurrence on Date (YYYY-MM-DD, in the departure's timezone)
+type DepartureBlackout struct {
+	gorm.Model
+	DepartureID uint   `json:"departure_id" gorm:"not null;index"`
+	Date        string `json:"date" gorm:"type:varchar(10);not null"`
+}
+
+// Location returns the departure's timezone, falling back to UTC
+func (d Departure) Location() *time.Location {
+	if loc, err := time.LoadLocation(d.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// Rule parses RRule with StartsAt as DTSTART, it returns nil for one-off departures
+func (d Departure) Rule() (*rrule.RRule, error) {
+	if d.RRule == "" {
+		return nil, nil
+	}
+
+	loc := d.Location()
+	option, err := rrule.StrToROptionInLocation(strings.TrimPrefix(d.RRule, "RRULE:"), loc)
+	if err != nil {
+		return nil, err
+	}
+	if !option.Dtstart.IsZero() {
+		return nil, errors.New("rrule must not contain DTSTART, use starts_at instead")
+	}
+	// Departures run at most a few times a day, finer rules would expand to unbounded occurrence lists
+	switch option.Freq {
+	case rrule.DAILY, rrule.WEEKLY, rrule.MONTHLY, rrule.YEARLY:
+	default:
+		return nil, errors.New("rrule FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY")
+	}
+	if len(option.Byminute) > 1 || len(option.Bysecond) > 1 {
+		return nil, errors.New("rrule BYMINUTE and BYSECOND take a single value")
+	}
+	option.Dtstart = d.StartsAt.In(loc)
+	return rrule.NewRRule(*option)
+}
+
+// Occurrences returns the start times in [from, to) that are not blacked out
+func (d Departure) Occurrences(from, to time.Time) ([]time.Time, error) {
+	rule, err := d.Rule()
+	if err != nil {
+		return nil, err
+	}
+
+	var starts []time.Time
+	if rule == nil {
+		if !d.StartsAt.Before(from) && d.StartsAt.Before(to) {
+			starts = append(starts, d.StartsAt)
+		}
+	} else {
+		for _, start := range rule.Between(from, to, true) {
+			if start.Before(to) {
+				starts = append(starts, start)
+			}
+		}
+	}
+
+	blackouts := make(map[string]bool, len(d.Blackouts))
+	for _, blackout := range d.Blackouts {
+		blackouts[blackout.Date] = true
+	}
+
+	loc := d.Location()
+	var open []time.Time
+	for _, start := range starts {
+		if !blackouts[start.In(loc).Format("2006-01-02")] {
+			open = append(open, start.UTC())
+		}
+	}
+	return open, nil
+}
+
+// HasOccurrence reports whether the departure runs exactly at start
+func (d Departure) HasOccurrence(start time.Time) (bool, error) {
+	occurrences, err := d.Occurrences(start, start.Add(time.Second))
+	if err != nil {
+		return false, err
+	}
+	return len(occurrences) == 1 && occurrences[0].Equal(start), nil
+}
+
+// BookingClosesAt returns the cut-off time for the occurrence starting at start
+func (d Departure) BookingClosesAt(start time.Time) time.Time {
+	return start.Add(-time.Duration(d.CutoffMinutes) * time.Minute)
+}
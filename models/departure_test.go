@@ -0,0 +1,137 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDepartureOccurrences(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(s string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed.UTC()
+	}
+
+	tests := []struct {
+		name      string
+		departure Departure
+		from, to  string
+		want      []string
+	}{
+		{
+			name:      "one-off inside the range",
+			departure: Departure{StartsAt: utc("2026-11-01T01:00:00Z")},
+			from:      "2026-11-01T00:00:00Z", to: "2026-11-02T00:00:00Z",
+			want: []string{"2026-11-01T01:00:00Z"},
+		},
+		{
+			name:      "one-off at the end of the range",
+			departure: Departure{StartsAt: utc("2026-11-02T00:00:00Z")},
+			from:      "2026-11-01T00:00:00Z", to: "2026-11-02T00:00:00Z",
+		},
+		{
+			name:      "one-off at the start of the range",
+			departure: Departure{StartsAt: utc("2026-11-01T00:00:00Z")},
+			from:      "2026-11-01T00:00:00Z", to: "2026-11-02T00:00:00Z",
+			want: []string{"2026-11-01T00:00:00Z"},
+		},
+		{
+			name:      "weekends at 8 in Jakarta",
+			departure: Departure{StartsAt: time.Date(2026, 10, 31, 8, 0, 0, 0, jakarta), RRule: "FREQ=WEEKLY;BYDAY=SA,SU", Timezone: "Asia/Jakarta"},
+			from:      "2026-10-30T00:00:00Z", to: "2026-11-09T00:00:00Z",
+			want: []string{"2026-10-31T01:00:00Z", "2026-11-01T01:00:00Z", "2026-11-07T01:00:00Z", "2026-11-08T01:00:00Z"},
+		},
+		{
+			name:      "RRULE prefix and a count",
+			departure: Departure{StartsAt: utc("2026-11-01T10:00:00Z"), RRule: "RRULE:FREQ=DAILY;COUNT=2"},
+			from:      "2026-10-01T00:00:00Z", to: "2026-12-01T00:00:00Z",
+			want: []string{"2026-11-01T10:00:00Z", "2026-11-02T10:00:00Z"},
+		},
+		{
+			name:      "occurrence at the end of the range is excluded",
+			departure: Departure{StartsAt: utc("2026-11-01T10:00:00Z"), RRule: "FREQ=DAILY"},
+			from:      "2026-11-01T10:00:00Z", to: "2026-11-03T10:00:00Z",
+			want: []string{"2026-11-01T10:00:00Z", "2026-11-02T10:00:00Z"},
+		},
+		{
+			name: "blackout dates are in the departure's timezone",
+			departure: Departure{
+				StartsAt:  time.Date(2026, 10, 31, 8, 0, 0, 0, jakarta),
+				RRule:     "FREQ=DAILY",
+				Timezone:  "Asia/Jakarta",
+				Blackouts: []DepartureBlackout{{Date: "2026-11-01"}},
+			},
+			from: "2026-10-31T00:00:00Z", to: "2026-11-03T00:00:00Z",
+			want: []string{"2026-10-31T01:00:00Z", "2026-11-02T01:00:00Z"},
+		},
+		{
+			name:      "local time is kept across a DST change",
+			departure: Departure{StartsAt: time.Date(2026, 10, 31, 9, 0, 0, 0, newYork), RRule: "FREQ=DAILY;COUNT=2", Timezone: "America/New_York"},
+			from:      "2026-10-30T00:00:00Z", to: "2026-11-03T00:00:00Z",
+			want: []string{"2026-10-31T13:00:00Z", "2026-11-01T14:00:00Z"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.departure.Occurrences(utc(tt.from), utc(tt.to))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []time.Time
+			for _, s := range tt.want {
+				want = append(want, utc(s))
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Occurrences = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDepartureRuleErrors(t *testing.T) {
+	for _, rule := range []string{
+		"FREQ=SOMETIMES",
+		"DTSTART:20261101T000000Z\nFREQ=DAILY",
+		"FREQ=DAILY;DTSTART=20261101T000000Z",
+		"FREQ=HOURLY",
+		"FREQ=MINUTELY",
+		"FREQ=SECONDLY",
+		"FREQ=DAILY;BYMINUTE=0,15,30,45",
+		"FREQ=DAILY;BYHOUR=9;BYSECOND=0,30",
+	} {
+		departure := Departure{StartsAt: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), RRule: rule}
+		if _, err := departure.Occurrences(departure.StartsAt, departure.StartsAt.AddDate(0, 1, 0)); err == nil {
+			t.Errorf("Occurrences with rrule %q did not fail", rule)
+		}
+	}
+}
+
+func TestDepartureHasOccurrence(t *testing.T) {
+	departure := Departure{StartsAt: time.Date(2026, 11, 1, 10, 0, 0, 0, time.UTC), RRule: "FREQ=WEEKLY"}
+	tests := []struct {
+		start time.Time
+		want  bool
+	}{
+		{time.Date(2026, 11, 8, 10, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 11, 8, 10, 0, 1, 0, time.UTC), false},
+		{time.Date(2026, 11, 9, 10, 0, 0, 0, time.UTC), false},
+		{time.Date(2026, 10, 25, 10, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		if got, err := departure.HasOccurrence(tt.start); err != nil || got != tt.want {
+			t.Errorf("HasOccurrence(%v) = %v, %v, want %v", tt.start, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"backend-go/currency"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ExchangeRate stores how many units of Currency one unit of currency.Base buys
+type ExchangeRate struct {
+	gorm.Model
+	Currency string  `json:"currency" gorm:"type:varchar(3);not null;uniqueIndex"`
+	Rate     float64 `json:"rate" gorm:"not null"`
+}
+
+// LoadRates reads the exchange-rate table into a lookup map
+func LoadRates(db *gorm.DB) (currency.Rates, error) {
+	var rows []ExchangeRate
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	rates := make(currency.Rates, len(rows))
+	for _, row := range rows {
+		rates[row.Currency] = row.Rate
+	}
+	return rates, nil
+}
+
+// SaveRates inserts or replaces the given rates
+func SaveRates(db *gorm.DB, rates currency.Rates) error {
+	if len(rates) == 0 {
+		return nil
+	}
+
+	rows := make([]ExchangeRate, 0, len(rates))
+	for code, rate := range rates {
+		rows = append(rows, ExchangeRate{Currency: code, Rate: rate})
+	}
+
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "currency"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "updated_at"}),
+	}).Create(&rows).Error
+}
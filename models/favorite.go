@@ -0,0 +1,50 @@
+package models
+
+import "gorm.io/gorm"
+
+// Favorite is a trip saved by a user
+type Favorite struct {
+	gorm.Model
+	UserID uint `json:"user_id" gorm:"not null;uniqueIndex:idx_favorite_user_trip"`
+	TripID uint `json:"trip_id" gorm:"not null;uniqueIndex:idx_favorite_user_trip"`
+	Trip   Trip `json:"trip" gorm:"foreignKey:TripID"`
+}
+
+// Collection is a named, ordered list of trips. Public collections can be viewed by
+// anyone, every collection can be shared through its ShareToken link.
+type Collection struct {
+	gorm.Model
+	UserID      uint             `json:"user_id" gorm:"not null;index"`
+	Name        string           `json:"name" gorm:"not null"`
+	Description string           `json:"description"`
+	Public      bool             `json:"public" gorm:"not null;default:false"`
+	ShareToken  string           `json:"share_token,omitempty" gorm:"not null;uniqueIndex;type:varchar(64)"`
+	Items       []CollectionItem `json:"items,omitempty" gorm:"foreignKey:CollectionID"`
+}
+
+// CollectionItem places a trip in a collection, items are listed by Position
+type CollectionItem struct {
+	gorm.Model
+	CollectionID uint   `json:"collection_id" gorm:"not null;uniqueIndex:idx_collection_trip"`
+	TripID       uint   `json:"trip_id" gorm:"not null;uniqueIndex:idx_collection_trip"`
+	Trip         Trip   `json:"trip" gorm:"foreignKey:TripID"`
+	Position     int    `json:"position" gorm:"not null;default:0"`
+	Note         string `json:"note"`
+}
+
+// FavoritedTripIDs returns which of tripIDs the user has favorited
+func FavoritedTripIDs(db *gorm.DB, userID uint, tripIDs []uint) (map[uint]bool, error) {
+	favorited := make(map[uint]bool)
+	if len(tripIDs) == 0 {
+		return favorited, nil
+	}
+
+	var ids []uint
+	if err := db.Model(&Favorite{}).Where("user_id = ? AND trip_id IN ?", userID, tripIDs).Pluck("trip_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		favorited[id] = true
+	}
+	return favorited, nil
+}
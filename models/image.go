@@ -1,15 +1,121 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"backend-go/media"
+	"sort"
+
+	"gorm.io/gorm"
+)
 
 type Image struct {
 	gorm.Model
 
 	TripID       *uint  `json:"trip_id"`
-	URL          string `json:"url" gorm:"not null"`
+	StorageKey   string `json:"-" gorm:"not null;default:''"` // Public name, e.g. images/image_x.jpg, the file is the blob's
+	BlobID       *uint  `json:"-" gorm:"index"`               // Stored file, shared by images of the same content
 	FileName     string `json:"file_name" gorm:"not null"`
 	OriginalName string `json:"original_name"`
 	FileSize     int64  `json:"file_size"`
 	MimeType     string `json:"mime_type"`
+	ContentHash  string `json:"-" gorm:"size:64;index"` // SHA-256 of the stored file, the ETag it is served with
+	Fingerprint  *int64 `json:"-"`                      // Perceptual hash, see media.Fingerprint
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
 	UploadedBy   *uint  `json:"uploaded_by"`
+
+	// Gallery details, images are shown in Position order
+	Position int    `json:"position" gorm:"not null;default:0"`
+	Caption  string `json:"caption"`
+	AltText  string `json:"alt_text"`
+
+	// Public URLs, built from StorageKey when the image is loaded and never stored
+	URL      string            `json:"url" gorm:"-"`
+	Variants map[string]string `json:"variants" gorm:"-"` // URL of each resized variant by name
+}
+
+// AfterFind fills in the URLs of loaded images, including preloaded trip galleries, from the
+// configured base URL. Handlers call ResolveImageURLs to fall back to the request's origin.
+func (img *Image) AfterFind(tx *gorm.DB) error {
+	img.ResolveURLs(media.ConfiguredBaseURL())
+	return nil
+}
+
+// AfterCreate fills in the URLs of new images
+func (img *Image) AfterCreate(tx *gorm.DB) error {
+	img.ResolveURLs(media.ConfiguredBaseURL())
+	return nil
+}
+
+// ResolveURLs builds the image's public URL and its variant URLs against base
+func (img *Image) ResolveURLs(base string) {
+	if img.StorageKey == "" {
+		return
+	}
+	img.URL = media.PublicURL(base, img.StorageKey)
+	img.Variants = make(map[string]string, len(media.Variants))
+	for _, variant := range media.Variants {
+		img.Variants[variant.Name] = img.URL + "?size=" + variant.Name
+	}
+}
+
+// ResolveImageURLs builds the URLs of images against base, usually utils.GetBaseURL of the request
+func ResolveImageURLs(images []Image, base string) {
+	for i := range images {
+		images[i].ResolveURLs(base)
+	}
+}
+
+// ResolveTripImageURLs prepares the galleries of trips against base
+func ResolveTripImageURLs(trips []Trip, base string) {
+	for i := range trips {
+		trips[i].PrepareGallery(base)
+	}
+}
+
+// PrepareGallery orders the trip's images by position and builds their URLs against base. A cover
+// picked from the gallery fills CoverImage with that image's URL.
+func (t *Trip) PrepareGallery(base string) {
+	sort.SliceStable(t.Images, func(i, j int) bool {
+		if t.Images[i].Position != t.Images[j].Position {
+			return t.Images[i].Position < t.Images[j].Position
+		}
+		return t.Images[i].ID < t.Images[j].ID
+	})
+	ResolveImageURLs(t.Images, base)
+
+	if t.CoverImageID == nil {
+		return
+	}
+	for _, image := range t.Images {
+		if image.ID == *t.CoverImageID {
+			t.CoverImage = image.URL
+		}
+	}
+}
+
+// NextImagePosition returns the position after the last image of a trip's gallery
+func NextImagePosition(db *gorm.DB, tripID uint) (int, error) {
+	var next int
+	err := db.Model(&Image{}).Where("trip_id = ?", tripID).Select("COALESCE(MAX(position) + 1, 0)").Scan(&next).Error
+	return next, err
+}
+
+// ClearTripCover unsets the image as the cover of any trip, before it leaves its gallery
+func ClearTripCover(tx *gorm.DB, imageID uint) error {
+	return tx.Model(&Trip{}).Where("cover_image_id = ?", imageID).
+		Updates(map[string]interface{}{"cover_image_id": nil, "cover_image": ""}).Error
+}
+
+// StorageUsed returns the bytes taken up by the images a user uploaded
+func StorageUsed(db *gorm.DB, userID uint) (int64, error) {
+	var used int64
+	err := db.Model(&Image{}).Where("uploaded_by = ?", userID).Select("COALESCE(SUM(file_size), 0)").Scan(&used).Error
+	return used, err
+}
+
+// CountTripImages returns the number of images in a trip's gallery
+func CountTripImages(db *gorm.DB, tripID uint) (int64, error) {
+	var count int64
+	err := db.Model(&Image{}).Where("trip_id = ?", tripID).Count(&count).Error
+	return count, err
 }
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"backend-go/utils"
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Itinerary is a visitor's personal plan across trips, possibly from different owners
+type Itinerary struct {
+	gorm.Model
+	UserID      uint            `json:"user_id" gorm:"not null;index"`
+	Name        string          `json:"name" gorm:"not null"`
+	Description string          `json:"description"`
+	Items       []ItineraryItem `json:"items,omitempty" gorm:"foreignKey:ItineraryID"`
+}
+
+// ItineraryItem schedules a trip in an itinerary. DepartureID and BookingID are set
+// when the item is a departure occurrence or an existing booking.
+type ItineraryItem struct {
+	gorm.Model
+	ItineraryID uint      `json:"itinerary_id" gorm:"not null;index"`
+	TripID      uint      `json:"trip_id" gorm:"not null"`
+	Trip        Trip      `json:"trip" gorm:"foreignKey:TripID"`
+	DepartureID *uint     `json:"departure_id"`
+	BookingID   *uint     `json:"booking_id"`
+	StartsAt    time.Time `json:"starts_at" gorm:"not null"`
+	Note        string    `json:"note"`
+
+	// Derived from the trip duration, never stored
+	EndsAt time.Time `json:"ends_at" gorm:"-"`
+}
+
+// ItineraryLeg is the move from the end of one item to the start of the next
+type ItineraryLeg struct {
+	FromItemID uint    `json:"from_item_id"`
+	ToItemID   uint    `json:"to_item_id"`
+	DistanceKm float64 `json:"distance_km"`
+	GapMinutes int     `json:"gap_minutes"` // Negative when the items overlap
+}
+
+// ItineraryOverlap is a pair of items whose schedules collide
+type ItineraryOverlap struct {
+	ItemID         uint `json:"item_id"`
+	OtherItemID    uint `json:"other_item_id"`
+	OverlapMinutes int  `json:"overlap_minutes"`
+}
+
+// ItineraryPlan is an itinerary with its items in time order and the analysis of its schedule
+type ItineraryPlan struct {
+	Itinerary
+	Legs            []ItineraryLeg     `json:"legs"`
+	Overlaps        []ItineraryOverlap `json:"overlaps"`
+	TotalDistanceKm float64            `json:"total_distance_km"`
+}
+
+// Plan sorts the items by start time and computes the legs between them and every overlap.
+// Items must have their Trip loaded.
+func (it Itinerary) Plan() ItineraryPlan {
+	items := append([]ItineraryItem{}, it.Items...)
+	for i := range items {
+		items[i].EndsAt = items[i].Trip.EndsAt(items[i].StartsAt)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if !items[i].StartsAt.Equal(items[j].StartsAt) {
+			return items[i].StartsAt.Before(items[j].StartsAt)
+		}
+		return items[i].ID < items[j].ID
+	})
+
+	plan := ItineraryPlan{Itinerary: it, Legs: []ItineraryLeg{}, Overlaps: []ItineraryOverlap{}}
+	plan.Items = items
+
+	for i := 1; i < len(items); i++ {
+		from, to := items[i-1], items[i]
+		distance := utils.DistanceKm(from.Trip.EndLatitude, from.Trip.EndLongitude, to.Trip.StartLatitude, to.Trip.StartLongitude)
+		distance = math.Round(distance*100) / 100
+		plan.Legs = append(plan.Legs, ItineraryLeg{
+			FromItemID: from.ID,
+			ToItemID:   to.ID,
+			DistanceKm: distance,
+			GapMinutes: int(to.StartsAt.Sub(from.EndsAt).Minutes()),
+		})
+		plan.TotalDistanceKm += distance
+	}
+	plan.TotalDistanceKm = math.Round(plan.TotalDistanceKm*100) / 100
+
+	// Items are sorted by start, so only later items that start before this one ends can collide
+	for i := range items {
+		for j := i + 1; j < len(items) && items[j].StartsAt.Before(items[i].EndsAt); j++ {
+			end := items[i].EndsAt
+			if items[j].EndsAt.Before(end) {
+				end = items[j].EndsAt
+			}
+			plan.Overlaps = append(plan.Overlaps, ItineraryOverlap{
+				ItemID:         items[i].ID,
+				OtherItemID:    items[j].ID,
+				OverlapMinutes: int(end.Sub(items[j].StartsAt).Minutes()),
+			})
+		}
+	}
+
+	return plan
+}
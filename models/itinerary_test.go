@@ -0,0 +1,92 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// itineraryItem schedules a trip of duration minutes starting at longitude start and ending at
+// longitude end on the equator
+func itineraryItem(id uint, startsAt time.Time, duration int, start, end float64) ItineraryItem {
+	item := ItineraryItem{
+		StartsAt: startsAt,
+		Trip:     Trip{Duration: duration, StartLongitude: start, EndLongitude: end},
+	}
+	item.ID = id
+	return item
+}
+
+func TestItineraryPlan(t *testing.T) {
+	day := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	at := func(days, hours, minutes int) time.Time {
+		return day.AddDate(0, 0, days).Add(time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute)
+	}
+
+	itinerary := Itinerary{Name: "Java"}
+	itinerary.Items = []ItineraryItem{
+		itineraryItem(2, at(0, 11, 0), 120, 1, 1),
+		itineraryItem(6, at(2, 8, 0), 60, 3, 3),
+		itineraryItem(1, at(0, 8, 0), 240, 0, 1),
+		itineraryItem(3, at(1, 8, 0), 60, 2, 2),
+		itineraryItem(4, at(0, 9, 0), 30, 1, 1),
+		itineraryItem(5, at(2, 8, 0), 60, 2, 2),
+	}
+
+	plan := itinerary.Plan()
+
+	var order []uint
+	for _, item := range plan.Items {
+		order = append(order, item.ID)
+	}
+	if want := []uint{1, 4, 2, 3, 5, 6}; !reflect.DeepEqual(order, want) {
+		t.Errorf("items in order %v, want %v", order, want)
+	}
+	if !plan.Items[0].EndsAt.Equal(at(0, 12, 0)) {
+		t.Errorf("first item ends at %v, want %v", plan.Items[0].EndsAt, at(0, 12, 0))
+	}
+
+	// One degree of longitude on the equator is 111.19 km
+	wantLegs := []ItineraryLeg{
+		{FromItemID: 1, ToItemID: 4, DistanceKm: 0, GapMinutes: -180},
+		{FromItemID: 4, ToItemID: 2, DistanceKm: 0, GapMinutes: 90},
+		{FromItemID: 2, ToItemID: 3, DistanceKm: 111.19, GapMinutes: 1140},
+		{FromItemID: 3, ToItemID: 5, DistanceKm: 0, GapMinutes: 1380},
+		{FromItemID: 5, ToItemID: 6, DistanceKm: 111.19, GapMinutes: -60},
+	}
+	if !reflect.DeepEqual(plan.Legs, wantLegs) {
+		t.Errorf("legs = %+v, want %+v", plan.Legs, wantLegs)
+	}
+	if plan.TotalDistanceKm != 222.38 {
+		t.Errorf("total distance = %v, want 222.38", plan.TotalDistanceKm)
+	}
+
+	wantOverlaps := []ItineraryOverlap{
+		{ItemID: 1, OtherItemID: 4, OverlapMinutes: 30}, // Inside the first item
+		{ItemID: 1, OtherItemID: 2, OverlapMinutes: 60}, // Starts before the first item ends
+		{ItemID: 5, OtherItemID: 6, OverlapMinutes: 60}, // Same start
+	}
+	if !reflect.DeepEqual(plan.Overlaps, wantOverlaps) {
+		t.Errorf("overlaps = %+v, want %+v", plan.Overlaps, wantOverlaps)
+	}
+
+	// The itinerary itself keeps its items as loaded
+	if itinerary.Items[0].ID != 2 || !itinerary.Items[0].EndsAt.IsZero() {
+		t.Errorf("Plan modified the itinerary's items")
+	}
+}
+
+func TestItineraryPlanEmpty(t *testing.T) {
+	plan := Itinerary{}.Plan()
+	if plan.Legs == nil || plan.Overlaps == nil || len(plan.Legs) != 0 || len(plan.Overlaps) != 0 || plan.TotalDistanceKm != 0 {
+		t.Errorf("empty plan = %+v, want empty legs and overlaps", plan)
+	}
+
+	back := Itinerary{Items: []ItineraryItem{
+		itineraryItem(1, time.Date(2026, 11, 1, 8, 0, 0, 0, time.UTC), 60, 0, 0),
+		itineraryItem(2, time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC), 60, 0, 0),
+	}}.Plan()
+	if len(back.Overlaps) != 0 || back.Legs[0].GapMinutes != 0 {
+		t.Errorf("back to back plan = %+v, want no overlap and no gap", back)
+	}
+}
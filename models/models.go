@@ -2,24 +2,167 @@ package models
 
 import (
 	"backend-go/config"
+	"backend-go/currency"
 	"log"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 // AutoMigrate runs auto-migration for all models
 func AutoMigrate() error {
 	err := config.DB.AutoMigrate(
 		&User{},
+		&CancellationPolicy{},
 		&Trip{},
+		&Blob{},
 		&Image{},
+		&UploadSession{},
 		&Preference{},
 		&UserPreference{},
 		&TripPreference{},
 		&TripPoint{},
+		&PricingRule{},
+		&Departure{},
+		&DepartureBlackout{},
+		&Quote{},
+		&Voucher{},
+		&Booking{},
+		&VoucherRedemption{},
+		&Review{},
+		&ReviewVote{},
+		&Favorite{},
+		&Collection{},
+		&CollectionItem{},
+		&Itinerary{},
+		&ItineraryItem{},
+		&CalendarFeed{},
+		&Conversation{},
+		&Message{},
+		&Notification{},
+		&NotificationDelivery{},
+		&NotificationPreference{},
+		&PushSubscription{},
+		&Payment{},
+		&PaymentRefund{},
+		&WebhookEvent{},
+		&RefundDecision{},
+		&ExchangeRate{},
 	)
 	if err != nil {
 		log.Printf("Failed to migrate database: %v", err)
 		return err
 	}
+	if err := migrateTripPrices(); err != nil {
+		log.Printf("Failed to migrate trip prices: %v", err)
+		return err
+	}
+	if err := migrateImageURLs(); err != nil {
+		log.Printf("Failed to migrate image URLs: %v", err)
+		return err
+	}
+	if err := migrateImageBlobs(); err != nil {
+		log.Printf("Failed to migrate image blobs: %v", err)
+		return err
+	}
+	if err := migrateRefundSequences(); err != nil {
+		log.Printf("Failed to migrate refund sequences: %v", err)
+		return err
+	}
+	if err := migrateBookingHolds(); err != nil {
+		log.Printf("Failed to migrate booking holds: %v", err)
+		return err
+	}
 	log.Println("Database migration completed successfully!")
 	return nil
 }
+
+// migrateTripPrices moves the legacy float price column (IDR, major units) into price_amount
+func migrateTripPrices() error {
+	migrator := config.DB.Migrator()
+	if !migrator.HasColumn(&Trip{}, "price") {
+		return nil
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("UPDATE trips SET price_amount = ROUND(price * 100), currency = ? WHERE price IS NOT NULL", currency.Default).Error; err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&Trip{}, "price")
+	})
+}
+
+// migrateImageURLs replaces the legacy absolute url column of images, which pointed at
+// localhost:8080, with storage keys. Trip cover images copied from those URLs become root-relative.
+func migrateImageURLs() error {
+	migrator := config.DB.Migrator()
+	if !migrator.HasColumn(&Image{}, "url") {
+		return nil
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`UPDATE images SET storage_key = CASE
+			WHEN url LIKE '%/covers/%' THEN 'covers/' || file_name
+			ELSE 'images/' || file_name
+		END WHERE storage_key = ''`).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`UPDATE trips SET cover_image = SUBSTRING(cover_image FROM LENGTH('http://localhost:8080') + 1)
+			WHERE cover_image LIKE 'http://localhost:8080/api/v1/%'`).Error; err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&Image{}, "url")
+	})
+}
+
+// migrateImageBlobs gives each image stored before deduplication a blob of its own, keeping the file
+// where it is. A blob takes its image's content hash when no other blob has it, so later uploads of the
+// same content share it.
+func migrateImageBlobs() error {
+	var pending int64
+	if err := config.DB.Model(&Image{}).Where("blob_id IS NULL AND storage_key <> ''").Count(&pending).Error; err != nil {
+		return err
+	}
+	if pending == 0 {
+		return nil
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`INSERT INTO blobs (created_at, updated_at, storage_key, size, mime_type, ref_count)
+			SELECT NOW(), NOW(), storage_key, file_size, mime_type, 0 FROM images
+			WHERE blob_id IS NULL AND storage_key <> '' AND deleted_at IS NULL
+			ON CONFLICT (storage_key) DO NOTHING`).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`UPDATE images SET blob_id = blobs.id FROM blobs
+			WHERE images.blob_id IS NULL AND images.deleted_at IS NULL AND blobs.storage_key = images.storage_key`).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`UPDATE blobs SET ref_count = (
+				SELECT COUNT(*) FROM images WHERE images.blob_id = blobs.id AND images.deleted_at IS NULL
+			) WHERE ref_count = 0`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`UPDATE blobs SET sha256 = first.content_hash FROM (
+				SELECT DISTINCT ON (content_hash) content_hash, blob_id FROM images
+				WHERE content_hash <> '' AND blob_id IS NOT NULL AND deleted_at IS NULL
+				ORDER BY content_hash, id
+			) first
+			WHERE blobs.id = first.blob_id AND blobs.sha256 IS NULL
+			AND NOT EXISTS (SELECT 1 FROM blobs taken WHERE taken.sha256 = first.content_hash)`).Error
+	})
+}
+
+// migrateRefundSequences numbers the refunds recorded before refunds had a sequence, in the order they were made
+func migrateRefundSequences() error {
+	return config.DB.Exec(`UPDATE payment_refunds SET sequence = numbered.sequence FROM (
+		SELECT id, ROW_NUMBER() OVER (PARTITION BY payment_id ORDER BY id) AS sequence FROM payment_refunds
+	) AS numbered WHERE payment_refunds.id = numbered.id AND payment_refunds.sequence = 0`).Error
+}
+
+// migrateBookingHolds gives unpaid bookings made before holds expired a full hold from now
+func migrateBookingHolds() error {
+	return config.DB.Model(&Booking{}).
+		Where("status = ? AND amount > 0 AND hold_expires_at IS NULL", BookingPending).
+		Update("hold_expires_at", time.Now().Add(BookingHold)).Error
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Notification delivery statuses
+const (
+	DeliveryPending = "pending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"  // Gave up after the last retry
+	DeliverySkipped = "skipped" // Nothing to deliver to, e.g. no push subscription
+)
+
+// Notification is an entry of a user's in-app inbox, also delivered on the channels the user enabled
+type Notification struct {
+	gorm.Model
+	UserID     uint                   `json:"user_id" gorm:"not null;index"`
+	User       User                   `json:"-" gorm:"foreignKey:UserID"`
+	Event      string                 `json:"event" gorm:"type:varchar(50);not null"`
+	Title      string                 `json:"title" gorm:"not null"`
+	Body       string                 `json:"body" gorm:"type:text"`
+	Link       string                 `json:"link"` // API path of what the notification is about
+	ReadAt     *time.Time             `json:"read_at" gorm:"index"`
+	Deliveries []NotificationDelivery `json:"deliveries,omitempty" gorm:"foreignKey:NotificationID"`
+}
+
+// NotificationDelivery is the delivery of a notification on one channel, retried until it is sent
+type NotificationDelivery struct {
+	gorm.Model
+	NotificationID uint          `json:"notification_id" gorm:"not null;index"`
+	Notification   *Notification `json:"-" gorm:"foreignKey:NotificationID"`
+	Channel        string        `json:"channel" gorm:"type:varchar(20);not null"`
+	Status         string        `json:"status" gorm:"type:varchar(20);not null;default:pending;index:idx_delivery_due"`
+	Attempts       int           `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt  time.Time     `json:"next_attempt_at" gorm:"not null;index:idx_delivery_due"`
+	LastError      string        `json:"last_error"`
+	SentAt         *time.Time    `json:"sent_at"`
+}
+
+// NotificationPreference turns a channel on or off for one event, channels default to on
+type NotificationPreference struct {
+	gorm.Model
+	UserID  uint   `json:"user_id" gorm:"not null;uniqueIndex:idx_notification_preference"`
+	Event   string `json:"event" gorm:"type:varchar(50);not null;uniqueIndex:idx_notification_preference"`
+	Channel string `json:"channel" gorm:"type:varchar(20);not null;uniqueIndex:idx_notification_preference"`
+	Enabled bool   `json:"enabled" gorm:"not null"`
+}
+
+// PushSubscription is a browser's Web Push subscription, the keys come from PushSubscription.toJSON()
+type PushSubscription struct {
+	gorm.Model
+	UserID   uint   `json:"user_id" gorm:"not null;index"`
+	Endpoint string `json:"endpoint" gorm:"type:text;not null;uniqueIndex"`
+	P256dh   string `json:"-" gorm:"not null"`
+	Auth     string `json:"-" gorm:"not null"`
+}
+
+// DisabledChannels returns the channels the user turned off for an event
+func DisabledChannels(db *gorm.DB, userID uint, event string) (map[string]bool, error) {
+	var preferences []NotificationPreference
+	if err := db.Where("user_id = ? AND event = ? AND enabled = ?", userID, event, false).Find(&preferences).Error; err != nil {
+		return nil, err
+	}
+
+	disabled := make(map[string]bool, len(preferences))
+	for _, preference := range preferences {
+		disabled[preference.Channel] = true
+	}
+	return disabled, nil
+}
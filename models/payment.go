@@ -0,0 +1,77 @@
+package models
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Payment statuses
+const (
+	PaymentRequiresPayment   = "requires_payment"
+	PaymentAuthorized        = "authorized"
+	PaymentCaptured          = "captured"
+	PaymentFailed            = "failed"
+	PaymentCancelled         = "cancelled"
+	PaymentPartiallyRefunded = "partially_refunded"
+	PaymentRefunded          = "refunded"
+)
+
+// Payment is a payment intent created with a gateway for a booking.
+// Amounts are in minor units of Currency (e.g. cents, sen).
+type Payment struct {
+	gorm.Model
+	BookingID      uint            `json:"booking_id" gorm:"not null;index"`
+	Gateway        string          `json:"gateway" gorm:"not null;uniqueIndex:idx_payment_provider_ref"`
+	ProviderRef    string          `json:"provider_ref" gorm:"not null;uniqueIndex:idx_payment_provider_ref"`
+	Amount         int64           `json:"amount" gorm:"not null"`
+	Currency       string          `json:"currency" gorm:"not null;type:varchar(3)"`
+	Status         string          `json:"status" gorm:"not null;type:varchar(20)"`
+	CapturedAmount int64           `json:"captured_amount" gorm:"not null;default:0"`
+	RefundedAmount int64           `json:"refunded_amount" gorm:"not null;default:0"` // Includes refunds still pending with the gateway
+	PendingAction  string          `json:"-" gorm:"type:varchar(20)"`                 // Gateway call decided by a webhook and not made yet
+	Refunds        []PaymentRefund `json:"refunds,omitempty" gorm:"foreignKey:PaymentID"`
+}
+
+// Refundable returns how much of the captured amount has not been refunded yet
+func (p Payment) Refundable() int64 {
+	return p.CapturedAmount - p.RefundedAmount
+}
+
+// Gateway calls a webhook can leave pending, they are made once its transaction commits
+const (
+	PaymentActionCapture = "capture"
+	PaymentActionCancel  = "cancel"
+)
+
+// Refund statuses, a refund is recorded as pending before the gateway is asked to pay it out
+const (
+	RefundPending   = "pending"
+	RefundSucceeded = "succeeded"
+	RefundFailed    = "failed"
+)
+
+// PaymentRefund records a refund issued through the gateway
+type PaymentRefund struct {
+	gorm.Model
+	PaymentID   uint   `json:"payment_id" gorm:"not null;index"`
+	Sequence    int    `json:"sequence" gorm:"not null;default:0"` // Numbers the refunds of a payment from 1
+	ProviderRef string `json:"provider_ref"`
+	Amount      int64  `json:"amount" gorm:"not null"`
+	Reason      string `json:"reason"`
+	Status      string `json:"status" gorm:"not null;type:varchar(20);default:'succeeded'"`
+	Attempts    int    `json:"attempts" gorm:"not null;default:0"` // Times the gateway was asked for the refund
+}
+
+// IdempotencyKey identifies the refund to the gateway, so retrying it never pays out twice
+func (r PaymentRefund) IdempotencyKey() string {
+	return fmt.Sprintf("refund-%d-%d", r.PaymentID, r.Sequence)
+}
+
+// WebhookEvent remembers processed gateway events so that retried deliveries are ignored
+type WebhookEvent struct {
+	gorm.Model
+	Gateway string `json:"gateway" gorm:"not null;uniqueIndex:idx_webhook_event"`
+	EventID string `json:"event_id" gorm:"not null;uniqueIndex:idx_webhook_event"`
+	Type    string `json:"type"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "testing"
+
+func TestRefundIdempotencyKey(t *testing.T) {
+	first := PaymentRefund{PaymentID: 12, Sequence: 1}
+	second := PaymentRefund{PaymentID: 12, Sequence: 2}
+	if first.IdempotencyKey() != "refund-12-1" || first.IdempotencyKey() == second.IdempotencyKey() {
+		t.Errorf("keys = %q and %q, want refund-12-1 and a different one", first.IdempotencyKey(), second.IdempotencyKey())
+	}
+}
@@ -0,0 +1,267 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Pricing rule kinds
+const (
+	PricingTier          = "tier"           // Per participant price of a tier such as child or senior
+	PricingGroupDiscount = "group_discount" // Percent off when at least MinParticipants travel together
+	PricingWeekend       = "weekend"        // Percent surcharge on Saturday and Sunday departures
+	PricingSeason        = "season"         // Percent surcharge on departures between StartDate and EndDate
+)
+
+// DefaultTier is priced at Trip.PriceAmount unless a tier rule overrides it
+const DefaultTier = "adult"
+
+// quoteValidity is how long a quote can be booked against
+const quoteValidity = 30 * time.Minute
+
+var (
+	// ErrUnknownTier is returned when a participant breakdown names a tier the trip does not price
+	ErrUnknownTier = errors.New("unknown participant tier")
+
+	// ErrNoParticipants is returned for an empty participant breakdown
+	ErrNoParticipants = errors.New("at least one participant is required")
+)
+
+// PricingRule adjusts the price of a trip. Amounts are minor units of the trip currency,
+// dates are "YYYY-MM-DD" in the departure timezone and both ends are inclusive.
+type PricingRule struct {
+	gorm.Model
+	TripID          uint   `json:"trip_id" gorm:"not null;index"`
+	Kind            string `json:"kind" gorm:"not null;type:varchar(20);check:kind IN ('tier', 'group_discount', 'weekend', 'season')" binding:"required,oneof=tier group_discount weekend season"`
+	Name            string `json:"name"`
+	Tier            string `json:"tier,omitempty" gorm:"type:varchar(30)"`
+	Amount          int64  `json:"amount,omitempty" binding:"min=0"`
+	MinParticipants int    `json:"min_participants,omitempty" binding:"min=0"`
+	Percent         int    `json:"percent,omitempty" binding:"min=0,max=100"`
+	StartDate       string `json:"start_date,omitempty" gorm:"type:varchar(10)"`
+	EndDate         string `json:"end_date,omitempty" gorm:"type:varchar(10)"`
+}
+
+// Validate checks that the fields required by the rule kind are set
+func (r PricingRule) Validate() error {
+	switch r.Kind {
+	case PricingTier:
+		if r.Tier == "" {
+			return errors.New("tier rules need a tier name")
+		}
+	case PricingGroupDiscount:
+		if r.MinParticipants < 2 || r.Percent == 0 {
+			return errors.New("group discounts need min_participants of at least 2 and a percent")
+		}
+	case PricingWeekend:
+		if r.Percent == 0 {
+			return errors.New("weekend surcharges need a percent")
+		}
+	case PricingSeason:
+		start, err := time.Parse(time.DateOnly, r.StartDate)
+		if err != nil {
+			return errors.New("season start_date must be formatted as YYYY-MM-DD")
+		}
+		end, err := time.Parse(time.DateOnly, r.EndDate)
+		if err != nil {
+			return errors.New("season end_date must be formatted as YYYY-MM-DD")
+		}
+		if end.Before(start) {
+			return errors.New("season end_date must not be before start_date")
+		}
+		if r.Percent == 0 {
+			return errors.New("season surcharges need a percent")
+		}
+	default:
+		return fmt.Errorf("unknown pricing rule kind %q", r.Kind)
+	}
+	return nil
+}
+
+// ParticipantBreakdown counts participants per tier. It also accepts a bare
+// number in JSON, meaning that many participants of the default tier.
+type ParticipantBreakdown map[string]int
+
+// UnmarshalJSON accepts either {"adult": 2, "child": 1} or 3
+func (p *ParticipantBreakdown) UnmarshalJSON(data []byte) error {
+	var count int
+	if err := json.Unmarshal(data, &count); err == nil {
+		*p = ParticipantBreakdown{DefaultTier: count}
+		return nil
+	}
+
+	var tiers map[string]int
+	if err := json.Unmarshal(data, &tiers); err != nil {
+		return errors.New("participants must be a number or an object of tier counts")
+	}
+	*p = tiers
+	return nil
+}
+
+// Total is the number of participants across tiers
+func (p ParticipantBreakdown) Total() int {
+	total := 0
+	for _, count := range p {
+		total += count
+	}
+	return total
+}
+
+// QuoteLine is one itemized entry of a quote, discounts have a negative Amount
+type QuoteLine struct {
+	Kind       string `json:"kind"`
+	Label      string `json:"label"`
+	Quantity   int    `json:"quantity,omitempty"`
+	UnitAmount int64  `json:"unit_amount,omitempty"`
+	Percent    int    `json:"percent,omitempty"`
+	Amount     int64  `json:"amount"`
+}
+
+// Quote is the itemized price of one departure occurrence for a participant breakdown.
+// Quotes are stored so that a booking pays exactly what the visitor was quoted.
+type Quote struct {
+	gorm.Model
+	TripID       uint                 `json:"trip_id" gorm:"not null;index"`
+	UserID       uint                 `json:"user_id" gorm:"not null;default:0;index"` // Only this visitor can book or check vouchers against the quote
+	DepartureID  uint                 `json:"departure_id" gorm:"not null"`
+	StartsAt     time.Time            `json:"starts_at" gorm:"not null"`
+	Participants ParticipantBreakdown `json:"participants" gorm:"type:text;serializer:json"`
+	Lines        []QuoteLine          `json:"lines" gorm:"type:text;serializer:json"`
+	Subtotal     int64                `json:"subtotal"`
+	Total        int64                `json:"total"`
+	Currency     string               `json:"currency" gorm:"type:varchar(3);not null"`
+	ExpiresAt    time.Time            `json:"expires_at"`
+}
+
+// Expired reports whether the quote can no longer be booked at now
+func (q Quote) Expired(now time.Time) bool {
+	return !now.Before(q.ExpiresAt)
+}
+
+// NewQuote prices participants on the occurrence of departure starting at startsAt.
+// Tier prices make up the subtotal, weekend and season surcharges are added on top of it
+// and the best matching group discount is taken off the surcharged amount.
+func NewQuote(trip Trip, rules []PricingRule, departure Departure, startsAt time.Time, participants ParticipantBreakdown, now time.Time) (Quote, error) {
+	quote := Quote{
+		TripID:       trip.ID,
+		DepartureID:  departure.ID,
+		StartsAt:     startsAt,
+		Participants: ParticipantBreakdown{},
+		Currency:     trip.Currency,
+		ExpiresAt:    now.Add(quoteValidity),
+	}
+
+	tierPrices := map[string]int64{DefaultTier: trip.PriceAmount}
+	for _, rule := range rules {
+		if rule.Kind == PricingTier {
+			tierPrices[rule.Tier] = rule.Amount
+		}
+	}
+
+	// Tier lines, in a stable order
+	tiers := make([]string, 0, len(participants))
+	for tier, count := range participants {
+		if count < 0 {
+			return quote, fmt.Errorf("participant count of %s cannot be negative", tier)
+		}
+		if count == 0 {
+			continue
+		}
+		if _, exists := tierPrices[tier]; !exists {
+			return quote, fmt.Errorf("%w: %s", ErrUnknownTier, tier)
+		}
+		tiers = append(tiers, tier)
+		quote.Participants[tier] = count
+	}
+	if len(tiers) == 0 {
+		return quote, ErrNoParticipants
+	}
+	sort.Strings(tiers)
+
+	for _, tier := range tiers {
+		line := QuoteLine{
+			Kind:       PricingTier,
+			Label:      tier,
+			Quantity:   participants[tier],
+			UnitAmount: tierPrices[tier],
+			Amount:     tierPrices[tier] * int64(participants[tier]),
+		}
+		quote.Lines = append(quote.Lines, line)
+		quote.Subtotal += line.Amount
+	}
+
+	// Surcharges depend on the local date the trip starts on
+	local := startsAt.In(departure.Location())
+	date := local.Format(time.DateOnly)
+	adjusted := quote.Subtotal
+	for _, rule := range rules {
+		applies := false
+		switch rule.Kind {
+		case PricingWeekend:
+			applies = local.Weekday() == time.Saturday || local.Weekday() == time.Sunday
+		case PricingSeason:
+			applies = date >= rule.StartDate && date <= rule.EndDate
+		}
+		if !applies {
+			continue
+		}
+
+		line := QuoteLine{
+			Kind:    rule.Kind,
+			Label:   ruleLabel(rule),
+			Percent: rule.Percent,
+			Amount:  percentOf(quote.Subtotal, rule.Percent),
+		}
+		quote.Lines = append(quote.Lines, line)
+		adjusted += line.Amount
+	}
+
+	// Only the group discount with the highest threshold that is met applies
+	var discount *PricingRule
+	total := quote.Participants.Total()
+	for i, rule := range rules {
+		if rule.Kind == PricingGroupDiscount && total >= rule.MinParticipants &&
+			(discount == nil || rule.MinParticipants > discount.MinParticipants) {
+			discount = &rules[i]
+		}
+	}
+	if discount != nil {
+		line := QuoteLine{
+			Kind:    discount.Kind,
+			Label:   ruleLabel(*discount),
+			Percent: discount.Percent,
+			Amount:  -percentOf(adjusted, discount.Percent),
+		}
+		quote.Lines = append(quote.Lines, line)
+		adjusted += line.Amount
+	}
+
+	quote.Total = adjusted
+	return quote, nil
+}
+
+// percentOf returns percent of amount rounded to the nearest minor unit
+func percentOf(amount int64, percent int) int64 {
+	return (amount*int64(percent) + 50) / 100
+}
+
+// ruleLabel describes a rule on a quote line, falling back to a generated label
+func ruleLabel(rule PricingRule) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	switch rule.Kind {
+	case PricingGroupDiscount:
+		return fmt.Sprintf("Group of %d or more", rule.MinParticipants)
+	case PricingWeekend:
+		return "Weekend surcharge"
+	case PricingSeason:
+		return fmt.Sprintf("Peak season %s to %s", rule.StartDate, rule.EndDate)
+	}
+	return rule.Kind
+}
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewQuote(t *testing.T) {
+	trip := Trip{PriceAmount: 100000, Currency: "IDR"}
+	trip.ID = 7
+	departure := Departure{Timezone: "Asia/Jakarta"}
+	departure.ID = 3
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	child := PricingRule{Kind: PricingTier, Tier: "child", Amount: 60000}
+	weekend := PricingRule{Kind: PricingWeekend, Percent: 10}
+	season := PricingRule{Kind: PricingSeason, Name: "Holidays", Percent: 20, StartDate: "2026-12-20", EndDate: "2027-01-05"}
+	groupOf4 := PricingRule{Kind: PricingGroupDiscount, MinParticipants: 4, Percent: 5}
+	groupOf6 := PricingRule{Kind: PricingGroupDiscount, MinParticipants: 6, Percent: 15}
+
+	// Friday 2026-11-06 at 23:00 UTC is Saturday morning in Jakarta
+	saturdayInJakarta := time.Date(2026, 11, 6, 23, 0, 0, 0, time.UTC)
+	wednesday := time.Date(2026, 11, 4, 2, 0, 0, 0, time.UTC)
+	christmas := time.Date(2026, 12, 25, 2, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		rules        []PricingRule
+		startsAt     time.Time
+		participants ParticipantBreakdown
+		lines        []QuoteLine
+		subtotal     int64
+		total        int64
+	}{
+		{
+			name:         "default tier only",
+			startsAt:     wednesday,
+			participants: ParticipantBreakdown{DefaultTier: 2},
+			lines:        []QuoteLine{{Kind: PricingTier, Label: DefaultTier, Quantity: 2, UnitAmount: 100000, Amount: 200000}},
+			subtotal:     200000,
+			total:        200000,
+		},
+		{
+			name:         "tiers in name order, empty tiers left out",
+			rules:        []PricingRule{child},
+			startsAt:     wednesday,
+			participants: ParticipantBreakdown{"child": 1, DefaultTier: 2, "senior": 0},
+			lines: []QuoteLine{
+				{Kind: PricingTier, Label: DefaultTier, Quantity: 2, UnitAmount: 100000, Amount: 200000},
+				{Kind: PricingTier, Label: "child", Quantity: 1, UnitAmount: 60000, Amount: 60000},
+			},
+			subtotal: 260000,
+			total:    260000,
+		},
+		{
+			name:         "weekend in the departure's timezone",
+			rules:        []PricingRule{weekend, season},
+			startsAt:     saturdayInJakarta,
+			participants: ParticipantBreakdown{DefaultTier: 1},
+			lines: []QuoteLine{
+				{Kind: PricingTier, Label: DefaultTier, Quantity: 1, UnitAmount: 100000, Amount: 100000},
+				{Kind: PricingWeekend, Label: "Weekend surcharge", Percent: 10, Amount: 10000},
+			},
+			subtotal: 100000,
+			total:    110000,
+		},
+		{
+			name:         "surcharges add up on the subtotal and the discount applies to the surcharged amount",
+			rules:        []PricingRule{weekend, season, groupOf4},
+			startsAt:     time.Date(2026, 12, 26, 2, 0, 0, 0, time.UTC),
+			participants: ParticipantBreakdown{DefaultTier: 4},
+			lines: []QuoteLine{
+				{Kind: PricingTier, Label: DefaultTier, Quantity: 4, UnitAmount: 100000, Amount: 400000},
+				{Kind: PricingWeekend, Label: "Weekend surcharge", Percent: 10, Amount: 40000},
+				{Kind: PricingSeason, Label: "Holidays", Percent: 20, Amount: 80000},
+				{Kind: PricingGroupDiscount, Label: "Group of 4 or more", Percent: 5, Amount: -26000},
+			},
+			subtotal: 400000,
+			total:    494000,
+		},
+		{
+			name:         "highest group threshold met wins",
+			rules:        []PricingRule{groupOf6, groupOf4},
+			startsAt:     wednesday,
+			participants: ParticipantBreakdown{DefaultTier: 7},
+			lines: []QuoteLine{
+				{Kind: PricingTier, Label: DefaultTier, Quantity: 7, UnitAmount: 100000, Amount: 700000},
+				{Kind: PricingGroupDiscount, Label: "Group of 6 or more", Percent: 15, Amount: -105000},
+			},
+			subtotal: 700000,
+			total:    595000,
+		},
+		{
+			name:         "season ends are inclusive",
+			rules:        []PricingRule{{Kind: PricingSeason, Percent: 20, StartDate: "2026-12-20", EndDate: "2026-12-25"}},
+			startsAt:     christmas,
+			participants: ParticipantBreakdown{DefaultTier: 1},
+			lines: []QuoteLine{
+				{Kind: PricingTier, Label: DefaultTier, Quantity: 1, UnitAmount: 100000, Amount: 100000},
+				{Kind: PricingSeason, Label: "Peak season 2026-12-20 to 2026-12-25", Percent: 20, Amount: 20000},
+			},
+			subtotal: 100000,
+			total:    120000,
+		},
+		{
+			name:         "percentages round to the nearest minor unit",
+			rules:        []PricingRule{{Kind: PricingTier, Tier: "child", Amount: 333}, {Kind: PricingWeekend, Percent: 15}},
+			startsAt:     saturdayInJakarta,
+			participants: ParticipantBreakdown{"child": 1},
+			lines: []QuoteLine{
+				{Kind: PricingTier, Label: "child", Quantity: 1, UnitAmount: 333, Amount: 333},
+				{Kind: PricingWeekend, Label: "Weekend surcharge", Percent: 15, Amount: 50},
+			},
+			subtotal: 333,
+			total:    383,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := NewQuote(trip, tt.rules, departure, tt.startsAt, tt.participants, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(quote.Lines, tt.lines) {
+				t.Errorf("lines = %+v, want %+v", quote.Lines, tt.lines)
+			}
+			if quote.Subtotal != tt.subtotal || quote.Total != tt.total {
+				t.Errorf("subtotal, total = %d, %d, want %d, %d", quote.Subtotal, quote.Total, tt.subtotal, tt.total)
+			}
+			if quote.TripID != 7 || quote.DepartureID != 3 || quote.Currency != "IDR" || !quote.StartsAt.Equal(tt.startsAt) {
+				t.Errorf("quote = %+v, want trip 7, departure 3 in IDR at %v", quote, tt.startsAt)
+			}
+			if !quote.ExpiresAt.Equal(now.Add(quoteValidity)) || quote.Expired(now) || !quote.Expired(quote.ExpiresAt) {
+				t.Errorf("quote expires at %v, want %v", quote.ExpiresAt, now.Add(quoteValidity))
+			}
+		})
+	}
+}
+
+func TestNewQuoteErrors(t *testing.T) {
+	trip := Trip{PriceAmount: 100000, Currency: "IDR"}
+	startsAt := time.Date(2026, 11, 4, 2, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		participants ParticipantBreakdown
+		want         error
+	}{
+		{"no participants", ParticipantBreakdown{}, ErrNoParticipants},
+		{"only empty tiers", ParticipantBreakdown{DefaultTier: 0}, ErrNoParticipants},
+		{"unknown tier", ParticipantBreakdown{"child": 1}, ErrUnknownTier},
+		{"negative count", ParticipantBreakdown{DefaultTier: -1}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewQuote(trip, nil, Departure{}, startsAt, tt.participants, startsAt)
+			if err == nil || (tt.want != nil && !errors.Is(err, tt.want)) {
+				t.Errorf("NewQuote = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParticipantBreakdownUnmarshal(t *testing.T) {
+	tests := []struct {
+		json string
+		want ParticipantBreakdown
+	}{
+		{`3`, ParticipantBreakdown{DefaultTier: 3}},
+		{`{"adult": 2, "child": 1}`, ParticipantBreakdown{DefaultTier: 2, "child": 1}},
+	}
+	for _, tt := range tests {
+		var got ParticipantBreakdown
+		if err := json.Unmarshal([]byte(tt.json), &got); err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Unmarshal(%s) = %v, %v, want %v", tt.json, got, err, tt.want)
+		}
+	}
+
+	var got ParticipantBreakdown
+	if err := json.Unmarshal([]byte(`"two"`), &got); err == nil {
+		t.Error(`Unmarshal("two") did not fail`)
+	}
+}
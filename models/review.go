@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Review is a visitor's rating of a trip, backed by the booking that made them eligible
+type Review struct {
+	gorm.Model
+	TripID       uint   `json:"trip_id" gorm:"not null;uniqueIndex:idx_review_trip_user"`
+	UserID       uint   `json:"user_id" gorm:"not null;uniqueIndex:idx_review_trip_user"`
+	User         User   `json:"user" gorm:"foreignKey:UserID"`
+	BookingID    uint   `json:"booking_id" gorm:"not null"`
+	Rating       int    `json:"rating" gorm:"not null;check:rating BETWEEN 1 AND 5"`
+	Body         string `json:"body" gorm:"type:text"`
+	HelpfulCount int    `json:"helpful_count" gorm:"not null;default:0;index"`
+
+	// The trip owner's single public reply
+	OwnerReply *string    `json:"owner_reply" gorm:"type:text"`
+	RepliedAt  *time.Time `json:"replied_at"`
+}
+
+// ReviewVote marks a review as helpful, once per user
+type ReviewVote struct {
+	gorm.Model
+	ReviewID uint `json:"review_id" gorm:"not null;uniqueIndex:idx_review_vote"`
+	UserID   uint `json:"user_id" gorm:"not null;uniqueIndex:idx_review_vote"`
+}
+
+// ReviewEligibleBooking finds a booking that lets the user review the trip: a completed booking,
+// or a confirmed one whose departure has already started, which counts as a verified visit
+func ReviewEligibleBooking(tx *gorm.DB, tripID, userID uint, now time.Time) (Booking, error) {
+	var booking Booking
+	err := tx.Where("trip_id = ? AND user_id = ?", tripID, userID).
+		Where("status = ? OR (status = ? AND starts_at <= ?)", BookingCompleted, BookingConfirmed, now).
+		Order("starts_at DESC").
+		First(&booking).Error
+	return booking, err
+}
+
+// RefreshTripRating recomputes the stored rating aggregates of a trip from its reviews
+func RefreshTripRating(tx *gorm.DB, tripID uint) error {
+	return tx.Exec(`UPDATE trips SET
+		review_count = (SELECT COUNT(*) FROM reviews WHERE trip_id = ? AND deleted_at IS NULL),
+		rating_average = COALESCE((SELECT ROUND(AVG(rating), 2) FROM reviews WHERE trip_id = ? AND deleted_at IS NULL), 0)
+		WHERE id = ?`, tripID, tripID, tripID).Error
+}
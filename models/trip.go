@@ -1,27 +1,64 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"backend-go/currency"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Trip struct {
 	gorm.Model
-	Name        string  `json:"name" gorm:"not null"`
-	Description string  `json:"description"`
-	CoverImage  string  `json:"cover_image"`
-	Price       float64 `json:"price" gorm:"not null"`
-	Duration    int     `json:"duration" gorm:"not null"`
+	Name        string `json:"name" gorm:"not null"`
+	Description string `json:"description"`
+	CoverImage  string `json:"cover_image"`
+	Duration    int    `json:"duration" gorm:"not null"`
+
+	// Authoritative price per participant in minor units of Currency
+	PriceAmount int64  `json:"price_amount" gorm:"not null;default:0"`
+	Currency    string `json:"currency" gorm:"type:varchar(3);not null;default:IDR"`
+
+	// Price converted to the currency requested by the client, never stored
+	DisplayPrice *currency.Money `json:"display_price,omitempty" gorm:"-"`
 
 	StartLatitude  float64 `json:"start_latitude" gorm:"not null"`
 	StartLongitude float64 `json:"start_longitude" gorm:"not null"`
 	EndLatitude    float64 `json:"end_latitude" gorm:"not null"`
 	EndLongitude   float64 `json:"end_longitude" gorm:"not null"`
 
+	// Aggregates of the trip's reviews, kept up to date when reviews change
+	RatingAverage float64 `json:"rating_average" gorm:"not null;default:0;index"`
+	ReviewCount   int     `json:"review_count" gorm:"not null;default:0;index"`
+
+	// Whether the requesting user saved the trip, only set when a user is logged in
+	IsFavorited *bool `json:"is_favorited,omitempty" gorm:"-"`
+
+	// OSM reference ("node/123", "way/456") for trips imported by the seed command
+	OSMID *string `json:"osm_id,omitempty" gorm:"uniqueIndex"`
+
+	// Cancellation policy applied to new bookings, none means full refunds
+	CancellationPolicyID *uint               `json:"cancellation_policy_id"`
+	CancellationPolicy   *CancellationPolicy `json:"cancellation_policy,omitempty" gorm:"foreignKey:CancellationPolicyID"`
+
 	// User association
 	UserID uint `json:"user_id" gorm:"not null"`
 	User   User `json:"user" gorm:"foreignKey:UserID"`
 
-	Images      []Image      `json:"images,omitempty" gorm:"foreignKey:TripID"`
-	Preferences []Preference `json:"preferences,omitempty" gorm:"many2many:trip_preferences;"`
-	Points      []TripPoint  `json:"points,omitempty" gorm:"foreignKey:TripID"`
+	Images []Image `json:"images,omitempty" gorm:"foreignKey:TripID"`
+
+	// Gallery image used as the cover, its URL replaces CoverImage in responses
+	CoverImageID *uint        `json:"cover_image_id"`
+	Preferences  []Preference `json:"preferences,omitempty" gorm:"many2many:trip_preferences;"`
+	Points       []TripPoint  `json:"points,omitempty" gorm:"foreignKey:TripID"`
+	Departures   []Departure  `json:"departures,omitempty" gorm:"foreignKey:TripID"`
+
+	// Tier prices, group discounts and surcharges on top of PriceAmount
+	PricingRules []PricingRule `json:"pricing_rules,omitempty" gorm:"foreignKey:TripID"`
+}
+
+// EndsAt returns when the trip ends if it starts at start, Duration is in minutes
+func (t Trip) EndsAt(start time.Time) time.Time {
+	return start.Add(time.Duration(t.Duration) * time.Minute)
 }
 
 type TripPoint struct {
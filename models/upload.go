@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Kinds of image a resumable upload becomes once complete
+const (
+	UploadKindImage = "image"
+	UploadKindCover = "cover"
+)
+
+// UploadSession tracks a resumable (tus) upload from its creation until the last byte arrives and
+// it becomes an Image. Received chunks are kept in storage until then.
+type UploadSession struct {
+	gorm.Model
+	Token        string     `json:"token" gorm:"not null;uniqueIndex;type:varchar(64)"`
+	UserID       uint       `json:"user_id" gorm:"not null;index"`
+	Kind         string     `json:"kind" gorm:"not null;default:'image'"`
+	TripID       *uint      `json:"trip_id"`
+	FileName     string     `json:"file_name"` // The client's filename from Upload-Metadata
+	UploadLength int64      `json:"upload_length" gorm:"not null"`
+	UploadOffset int64      `json:"upload_offset" gorm:"not null;default:0"`
+	LockedUntil  *time.Time `json:"-"` // Set while a PATCH is writing, so concurrent PATCHes are refused
+	ExpiresAt    time.Time  `json:"expires_at" gorm:"not null;index"`
+	ImageID      *uint      `json:"image_id"`
+	Image        *Image     `json:"image,omitempty" gorm:"foreignKey:ImageID"`
+}
+
+// Complete reports whether every byte has been received and the image created
+func (s *UploadSession) Complete() bool {
+	return s.ImageID != nil
+}
+
+// StorageReserved returns the bytes a user's unfinished resumable uploads will take up once complete
+func StorageReserved(db *gorm.DB, userID uint) (int64, error) {
+	var reserved int64
+	err := db.Model(&UploadSession{}).Where("user_id = ? AND image_id IS NULL AND expires_at > ?", userID, time.Now()).
+		Select("COALESCE(SUM(upload_length), 0)").Scan(&reserved).Error
+	return reserved, err
+}
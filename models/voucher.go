@@ -0,0 +1,121 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Voucher kinds
+const (
+	VoucherPercent = "percent" // Percent off the booking total
+	VoucherFixed   = "fixed"   // Amount off the booking total, in minor units of Currency
+)
+
+// ErrVoucherNotApplicable wraps every reason a voucher cannot be used on a booking
+var ErrVoucherNotApplicable = errors.New("voucher cannot be applied")
+
+// Voucher is a promo code. Codes without an owner are platform-wide, owner codes
+// only apply to that owner's trips. Zero limits and missing dates mean unlimited.
+type Voucher struct {
+	gorm.Model
+	Code           string     `json:"code" gorm:"not null;uniqueIndex;type:varchar(40)"`
+	Description    string     `json:"description"`
+	Kind           string     `json:"kind" gorm:"not null;type:varchar(10);check:kind IN ('percent', 'fixed')"`
+	Percent        int        `json:"percent,omitempty"`
+	Amount         int64      `json:"amount,omitempty"`
+	Currency       string     `json:"currency,omitempty" gorm:"type:varchar(3)"` // Currency of Amount and MinSpend
+	MinSpend       int64      `json:"min_spend"`
+	MaxRedemptions int        `json:"max_redemptions"`
+	MaxPerUser     int        `json:"max_per_user"`
+	Redemptions    int        `json:"redemptions" gorm:"not null;default:0"`
+	ValidFrom      *time.Time `json:"valid_from"`
+	ValidUntil     *time.Time `json:"valid_until"`
+	Active         bool       `json:"active" gorm:"not null;default:true"`
+
+	OwnerID   *uint `json:"owner_id" gorm:"index"`
+	CreatedBy uint  `json:"created_by" gorm:"not null"`
+}
+
+// VoucherRedemption records a voucher used on a booking
+type VoucherRedemption struct {
+	gorm.Model
+	VoucherID uint   `json:"voucher_id" gorm:"not null;index"`
+	BookingID uint   `json:"booking_id" gorm:"not null;uniqueIndex"`
+	UserID    uint   `json:"user_id" gorm:"not null;index"`
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency" gorm:"type:varchar(3)"`
+}
+
+// NormalizeVoucherCode makes codes case-insensitive
+func NormalizeVoucherCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Discount checks the voucher against a booking total for a trip and returns the amount taken off.
+// Usage limits that need the redemption table are checked by CheckVoucherUsage.
+func (v Voucher) Discount(trip Trip, total int64, currency string, now time.Time) (int64, error) {
+	switch {
+	case !v.Active:
+		return 0, fmt.Errorf("%w: the code is no longer active", ErrVoucherNotApplicable)
+	case v.ValidFrom != nil && now.Before(*v.ValidFrom):
+		return 0, fmt.Errorf("%w: the code is not valid yet", ErrVoucherNotApplicable)
+	case v.ValidUntil != nil && !now.Before(*v.ValidUntil):
+		return 0, fmt.Errorf("%w: the code has expired", ErrVoucherNotApplicable)
+	case v.OwnerID != nil && *v.OwnerID != trip.UserID:
+		return 0, fmt.Errorf("%w: the code is not valid for this trip", ErrVoucherNotApplicable)
+	case v.MaxRedemptions > 0 && v.Redemptions >= v.MaxRedemptions:
+		return 0, fmt.Errorf("%w: the code has been fully redeemed", ErrVoucherNotApplicable)
+	}
+
+	// Amounts are only comparable in the voucher's own currency
+	if (v.Kind == VoucherFixed || v.MinSpend > 0) && v.Currency != currency {
+		return 0, fmt.Errorf("%w: the code is only valid for prices in %s", ErrVoucherNotApplicable, v.Currency)
+	}
+	if total < v.MinSpend {
+		return 0, fmt.Errorf("%w: a minimum spend of %d is required", ErrVoucherNotApplicable, v.MinSpend)
+	}
+
+	if v.Kind == VoucherFixed {
+		return min(v.Amount, total), nil
+	}
+	return percentOf(total, v.Percent), nil
+}
+
+// CheckVoucherUsage enforces the per-user limit of a voucher
+func CheckVoucherUsage(tx *gorm.DB, voucher Voucher, userID uint) error {
+	if voucher.MaxPerUser == 0 {
+		return nil
+	}
+
+	var used int64
+	if err := tx.Model(&VoucherRedemption{}).Where("voucher_id = ? AND user_id = ?", voucher.ID, userID).Count(&used).Error; err != nil {
+		return err
+	}
+	if used >= int64(voucher.MaxPerUser) {
+		return fmt.Errorf("%w: you have already used this code", ErrVoucherNotApplicable)
+	}
+	return nil
+}
+
+// ReleaseVoucher gives back the redemption of a booking being cancelled, so it no longer counts
+// toward the voucher's limits. The voucher row is locked like when the code was redeemed.
+func ReleaseVoucher(tx *gorm.DB, booking Booking) error {
+	if booking.VoucherID == nil {
+		return nil
+	}
+
+	var voucher Voucher
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&voucher, *booking.VoucherID).Error; err != nil {
+		return err
+	}
+	result := tx.Where("booking_id = ?", booking.ID).Delete(&VoucherRedemption{})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	return tx.Model(&voucher).UpdateColumn("redemptions", gorm.Expr("GREATEST(redemptions - 1, 0)")).Error
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func mockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+		Logger:                 logger.Discard,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, mock
+}
+
+func TestReleaseVoucher(t *testing.T) {
+	voucherID := uint(7)
+	booking := Booking{VoucherID: &voucherID}
+	booking.ID = 42
+
+	t.Run("gives back the redemption", func(t *testing.T) {
+		db, mock := mockDB(t)
+		mock.ExpectQuery(`SELECT \* FROM "vouchers" WHERE "vouchers"\."id" = \$1 .* FOR UPDATE`).
+			WithArgs(voucherID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "redemptions"}).AddRow(voucherID, 3))
+		mock.ExpectExec(`UPDATE "voucher_redemptions" SET "deleted_at"=\$1 WHERE booking_id = \$2`).
+			WithArgs(sqlmock.AnyArg(), booking.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE "vouchers" SET "redemptions"=GREATEST\(redemptions - 1, 0\) WHERE .*"id" = \$1`).
+			WithArgs(voucherID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		if err := ReleaseVoucher(db, booking); err != nil {
+			t.Fatalf("ReleaseVoucher: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("already released", func(t *testing.T) {
+		db, mock := mockDB(t)
+		mock.ExpectQuery(`SELECT \* FROM "vouchers" .* FOR UPDATE`).
+			WithArgs(voucherID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "redemptions"}).AddRow(voucherID, 3))
+		mock.ExpectExec(`UPDATE "voucher_redemptions" SET "deleted_at"`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		if err := ReleaseVoucher(db, booking); err != nil {
+			t.Fatalf("ReleaseVoucher: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("without a voucher", func(t *testing.T) {
+		db, mock := mockDB(t)
+		if err := ReleaseVoucher(db, Booking{}); err != nil {
+			t.Fatalf("ReleaseVoucher: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"backend-go/models"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// SMTPChannel emails notifications through an SMTP server, upgrading to TLS when the server offers STARTTLS
+type SMTPChannel struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+// NewSMTPChannel creates an SMTP channel, port defaults to 587 and authentication is skipped without a username
+func NewSMTPChannel(host, port, username, password, from string) *SMTPChannel {
+	if port == "" {
+		port = "587"
+	}
+	if from == "" {
+		from = username
+	}
+	return &SMTPChannel{addr: net.JoinHostPort(host, port), host: host, username: username, password: password, from: from}
+}
+
+func (ch *SMTPChannel) Name() string {
+	return "email"
+}
+
+func (ch *SMTPChannel) Deliver(ctx context.Context, user models.User, notification models.Notification) error {
+	if user.Email == "" {
+		return ErrNoRecipient
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(deliveryTimeout)
+	}
+	dialer := net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", ch.addr)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, ch.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if supported, _ := client.Extension("STARTTLS"); supported {
+		if err := client.StartTLS(&tls.Config{ServerName: ch.host}); err != nil {
+			return err
+		}
+	}
+	if ch.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", ch.username, ch.password, ch.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(ch.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(user.Email); err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(ch.message(user, notification)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// message builds a plain text email, linking to the notification's subject when BASE_URL is set
+func (ch *SMTPChannel) message(user models.User, notification models.Notification) []byte {
+	body := notification.Body
+	if baseURL := os.Getenv("BASE_URL"); baseURL != "" && notification.Link != "" {
+		body += "\n\n" + strings.TrimSuffix(baseURL, "/") + notification.Link
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", ch.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", (&mail.Address{Name: user.Name, Address: user.Email}).String())
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", notification.Title))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	msg.WriteString("\r\n")
+	return msg.Bytes()
+}
@@ -0,0 +1,26 @@
+package notify
+
+import (
+	"backend-go/models"
+	"context"
+	"log"
+)
+
+// LogChannel writes notifications to the application log. It stands in for email during local
+// development so notifications can be followed without an SMTP server.
+type LogChannel struct{}
+
+// NewLogChannel creates a log channel
+func NewLogChannel() *LogChannel {
+	return &LogChannel{}
+}
+
+func (LogChannel) Name() string {
+	return "log"
+}
+
+func (LogChannel) Deliver(ctx context.Context, user models.User, notification models.Notification) error {
+	log.Printf("Notification %d to %s <%s> [%s] %s: %s", notification.ID, user.Name, user.Email,
+		notification.Event, notification.Title, notification.Body)
+	return nil
+}
@@ -0,0 +1,246 @@
+package notify
+
+import (
+	"backend-go/config"
+	"backend-go/models"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Events users are notified about
+const (
+	EventBookingCreated   = "booking.created"
+	EventBookingUpdated   = "booking.updated"
+	EventBookingCancelled = "booking.cancelled"
+	EventTripUpdated      = "trip.updated"
+	EventReviewPosted     = "review.posted"
+	EventReviewReplied    = "review.replied"
+	EventMessageReceived  = "message.received"
+	EventPasswordChanged  = "account.password_changed"
+)
+
+// EventInfo describes an event for the notification preferences screen
+type EventInfo struct {
+	Event       string `json:"event"`
+	Description string `json:"description"`
+}
+
+// Events lists every event in display order
+var Events = []EventInfo{
+	{EventBookingCreated, "Someone booked one of your trips"},
+	{EventBookingUpdated, "Your booking was confirmed or completed"},
+	{EventBookingCancelled, "A booking was cancelled"},
+	{EventTripUpdated, "A trip you booked was changed"},
+	{EventReviewPosted, "Someone reviewed one of your trips"},
+	{EventReviewReplied, "A trip owner replied to your review"},
+	{EventMessageReceived, "You received a message"},
+	{EventPasswordChanged, "Your password was changed"},
+}
+
+// IsEvent reports whether event is one of Events
+func IsEvent(event string) bool {
+	for _, info := range Events {
+		if info.Event == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrNoRecipient is returned by channels that have nowhere to deliver to for a user,
+// the delivery is skipped instead of retried
+var ErrNoRecipient = errors.New("no recipient on this channel")
+
+// Channel is implemented by every delivery adapter. The in-app inbox is not a channel,
+// every notification is stored there before it is handed to the channels.
+type Channel interface {
+	Name() string
+	Deliver(ctx context.Context, user models.User, notification models.Notification) error
+}
+
+// Delivery tuning
+const (
+	MaxAttempts     = 5
+	retryBase       = 30 * time.Second // Doubled after every failed attempt
+	deliveryTimeout = 30 * time.Second
+	deliveryLease   = 5 * time.Minute // A claimed delivery is retried after this if its worker died
+	pollInterval    = 15 * time.Second
+	workers         = 4
+)
+
+var (
+	setupOnce sync.Once
+	channels  map[string]Channel
+	queue     = make(chan uint, 256)
+)
+
+func setup() {
+	channels = make(map[string]Channel)
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		channels["email"] = NewSMTPChannel(host, os.Getenv("SMTP_PORT"), os.Getenv("SMTP_USERNAME"),
+			os.Getenv("SMTP_PASSWORD"), os.Getenv("SMTP_FROM"))
+	} else {
+		log.Println("Warning: SMTP_HOST is not set, notification emails are written to the log")
+		channels["email"] = NewLogChannel()
+	}
+
+	if publicKey, privateKey := os.Getenv("VAPID_PUBLIC_KEY"), os.Getenv("VAPID_PRIVATE_KEY"); publicKey != "" && privateKey != "" {
+		channel, err := NewWebPushChannel(publicKey, privateKey, os.Getenv("VAPID_SUBJECT"))
+		if err != nil {
+			log.Fatalf("Invalid VAPID keys: %v", err)
+		}
+		channels["webpush"] = channel
+	}
+}
+
+// Channels returns the names of the configured channels users can turn on and off
+func Channels() []string {
+	setupOnce.Do(setup)
+	names := make([]string, 0, len(channels))
+	for name := range channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns a configured channel by name
+func Lookup(name string) (Channel, bool) {
+	setupOnce.Do(setup)
+	channel, exists := channels[name]
+	return channel, exists
+}
+
+// Send stores a notification in the user's inbox and queues its delivery on every channel the
+// user has not turned off for the event. It never waits for a channel, failures are logged since
+// a notification must not fail the request that caused it. link is an API path such as /api/v1/bookings/1.
+func Send(userID uint, event, title, body, link string) {
+	notification := models.Notification{UserID: userID, Event: event, Title: title, Body: body, Link: link}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&notification).Error; err != nil {
+			return err
+		}
+
+		disabled, err := models.DisabledChannels(tx, userID, event)
+		if err != nil {
+			return err
+		}
+		for _, name := range Channels() {
+			if disabled[name] {
+				continue
+			}
+			notification.Deliveries = append(notification.Deliveries, models.NotificationDelivery{
+				NotificationID: notification.ID,
+				Channel:        name,
+				Status:         models.DeliveryPending,
+				NextAttemptAt:  notification.CreatedAt,
+			})
+		}
+		if len(notification.Deliveries) == 0 {
+			return nil
+		}
+		return tx.Create(&notification.Deliveries).Error
+	})
+	if err != nil {
+		log.Printf("Failed to store %s notification for user %d: %v", event, userID, err)
+		return
+	}
+
+	for _, delivery := range notification.Deliveries {
+		select {
+		case queue <- delivery.ID:
+		default:
+			// The queue is full, the poller picks the delivery up
+		}
+	}
+}
+
+// Start runs the delivery workers and the poller that retries failed deliveries and picks up
+// those queued while the server was down. Without it deliveries wait in the database.
+func Start() {
+	setupOnce.Do(setup)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for id := range queue {
+				deliver(id)
+			}
+		}()
+	}
+	go poll()
+}
+
+// poll queues the pending deliveries that are due
+func poll() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var ids []uint
+		if err := config.DB.Model(&models.NotificationDelivery{}).
+			Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, time.Now()).
+			Order("next_attempt_at").Limit(cap(queue)).
+			Pluck("id", &ids).Error; err != nil {
+			log.Printf("Failed to poll notification deliveries: %v", err)
+			continue
+		}
+		for _, id := range ids {
+			queue <- id
+		}
+	}
+}
+
+// deliver claims a due delivery, so a delivery queued twice or by several servers is sent once,
+// hands it to its channel and records the outcome
+func deliver(id uint) {
+	now := time.Now()
+	claim := config.DB.Model(&models.NotificationDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", id, models.DeliveryPending, now).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": now.Add(deliveryLease),
+		})
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return
+	}
+
+	var delivery models.NotificationDelivery
+	if err := config.DB.Preload("Notification").Preload("Notification.User").First(&delivery, id).Error; err != nil {
+		log.Printf("Failed to load notification delivery %d: %v", id, err)
+		return
+	}
+
+	err := errors.New("channel is no longer configured")
+	if channel, exists := Lookup(delivery.Channel); exists {
+		ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+		err = channel.Deliver(ctx, delivery.Notification.User, *delivery.Notification)
+		cancel()
+	}
+
+	updates := map[string]interface{}{}
+	switch {
+	case err == nil:
+		updates["status"] = models.DeliverySent
+		updates["sent_at"] = time.Now()
+	case errors.Is(err, ErrNoRecipient):
+		updates["status"] = models.DeliverySkipped
+	case delivery.Attempts >= MaxAttempts:
+		updates["status"] = models.DeliveryFailed
+		updates["last_error"] = err.Error()
+		log.Printf("Giving up on %s delivery %d after %d attempts: %v", delivery.Channel, id, delivery.Attempts, err)
+	default:
+		updates["next_attempt_at"] = time.Now().Add(retryBase << (delivery.Attempts - 1))
+		updates["last_error"] = err.Error()
+	}
+	if err := config.DB.Model(&delivery).Updates(updates).Error; err != nil {
+		log.Printf("Failed to record notification delivery %d: %v", id, err)
+	}
+}
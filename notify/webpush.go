@@ -0,0 +1,241 @@
+package notify
+
+import (
+	"backend-go/config"
+	"backend-go/models"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// pushTTL is how long push services keep a message for an offline browser
+const pushTTL = 24 * time.Hour
+
+// WebPushChannel sends notifications to the browsers a user subscribed with, encrypted as
+// aes128gcm (RFC 8291) and authenticated with VAPID (RFC 8292)
+type WebPushChannel struct {
+	publicKey  string // Base64url uncompressed P-256 point, also handed to browsers to subscribe
+	privateKey *ecdsa.PrivateKey
+	subject    string
+	client     *http.Client
+}
+
+// NewWebPushChannel creates a Web Push channel from base64url VAPID keys as generated by the
+// vapid-keys command. subject is a mailto: or https: contact for push services.
+func NewWebPushChannel(publicKey, privateKey, subject string) (*WebPushChannel, error) {
+	raw, err := decodeBase64URL(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("private key: %w", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("private key: %w", err)
+	}
+
+	point := key.PublicKey().Bytes()
+	if base64.RawURLEncoding.EncodeToString(point) != strings.TrimRight(publicKey, "=") {
+		return nil, errors.New("public key does not match the private key")
+	}
+
+	if subject == "" {
+		subject = "mailto:admin@localhost"
+	}
+
+	signer := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(point[1:33]),
+			Y:     new(big.Int).SetBytes(point[33:]),
+		},
+		D: new(big.Int).SetBytes(raw),
+	}
+	return &WebPushChannel{
+		publicKey:  base64.RawURLEncoding.EncodeToString(point),
+		privateKey: signer,
+		subject:    subject,
+		client:     &http.Client{Timeout: deliveryTimeout},
+	}, nil
+}
+
+// GenerateVAPIDKeys creates a new base64url encoded VAPID key pair
+func GenerateVAPIDKeys() (publicKey, privateKey string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+		base64.RawURLEncoding.EncodeToString(key.Bytes()), nil
+}
+
+// PublicKey returns the applicationServerKey browsers subscribe with
+func (ch *WebPushChannel) PublicKey() string {
+	return ch.publicKey
+}
+
+func (ch *WebPushChannel) Name() string {
+	return "webpush"
+}
+
+// Deliver pushes to every subscription of the user. Subscriptions the push service reports as
+// gone are removed, the delivery fails if any other subscription could not be reached.
+func (ch *WebPushChannel) Deliver(ctx context.Context, user models.User, notification models.Notification) error {
+	var subscriptions []models.PushSubscription
+	if err := config.DB.Where("user_id = ?", user.ID).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		return ErrNoRecipient
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":    notification.ID,
+		"event": notification.Event,
+		"title": notification.Title,
+		"body":  notification.Body,
+		"link":  notification.Link,
+	})
+	if err != nil {
+		return err
+	}
+
+	var failed []error
+	for _, subscription := range subscriptions {
+		status, err := ch.push(ctx, subscription, payload)
+		switch {
+		case status == http.StatusNotFound || status == http.StatusGone:
+			config.DB.Unscoped().Delete(&subscription)
+		case err != nil:
+			failed = append(failed, err)
+		}
+	}
+	return errors.Join(failed...)
+}
+
+// push sends one encrypted message and returns the push service's status code
+func (ch *WebPushChannel) push(ctx context.Context, subscription models.PushSubscription, payload []byte) (int, error) {
+	body, err := encryptPayload(subscription, payload)
+	if err != nil {
+		return 0, err
+	}
+
+	endpoint, err := url.Parse(subscription.Endpoint)
+	if err != nil {
+		return 0, err
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": endpoint.Scheme + "://" + endpoint.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": ch.subject,
+	}).SignedString(ch.privateKey)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("vapid t=%s, k=%s", token, ch.publicKey))
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", fmt.Sprint(int(pushTTL.Seconds())))
+
+	resp, err := ch.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("push service responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// encryptPayload encrypts payload for a subscription as a single aes128gcm record (RFC 8291)
+func encryptPayload(subscription models.PushSubscription, payload []byte) ([]byte, error) {
+	rawPublic, err := decodeBase64URL(subscription.P256dh)
+	if err != nil {
+		return nil, err
+	}
+	authSecret, err := decodeBase64URL(subscription.Auth)
+	if err != nil {
+		return nil, err
+	}
+	userAgentKey, err := ecdh.P256().NewPublicKey(rawPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	serverKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := serverKey.ECDH(userAgentKey)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	serverPublic := serverKey.PublicKey().Bytes()
+	keyInfo := append(append([]byte("WebPush: info\x00"), rawPublic...), serverPublic...)
+	ikm, err := hkdf.Key(sha256.New, shared, authSecret, string(keyInfo), 32)
+	if err != nil {
+		return nil, err
+	}
+	contentKey, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Header: salt, record size, key id length and the server's public key as key id
+	record := append(append([]byte{}, payload...), 0x02) // 0x02 pads the last record
+	header := make([]byte, 0, 16+4+1+len(serverPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, 4096)
+	header = append(header, byte(len(serverPublic)))
+	header = append(header, serverPublic...)
+	return gcm.Seal(header, nonce, record, nil), nil
+}
+
+// decodeBase64URL decodes base64url with or without padding
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
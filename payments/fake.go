@@ -0,0 +1,169 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// fakeSignatureHeader carries the hex HMAC-SHA256 of the webhook body
+const fakeSignatureHeader = "X-Fake-Signature"
+
+// FakeGateway is an in-process gateway for tests and local development. It keeps
+// intents in memory and can produce signed webhook payloads with SignedEvent.
+type FakeGateway struct {
+	secret string
+
+	mu      sync.Mutex
+	intents map[string]*FakeIntent
+	refunds map[string]string // Refund IDs by idempotency key
+}
+
+// FakeIntent is the in-memory state of an intent created with FakeGateway
+type FakeIntent struct {
+	Amount   int64
+	Currency string
+	Captured bool
+	Canceled bool
+	Refunded int64
+}
+
+// NewFakeGateway creates a fake gateway that signs webhooks with secret, which must not be empty
+func NewFakeGateway(secret string) *FakeGateway {
+	return &FakeGateway{secret: secret, intents: make(map[string]*FakeIntent), refunds: make(map[string]string)}
+}
+
+func (g *FakeGateway) Name() string {
+	return "fake"
+}
+
+func (g *FakeGateway) CreateIntent(ctx context.Context, amount int64, currency, reference string) (Intent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ref := "fake_pi_" + uuid.New().String()
+	g.intents[ref] = &FakeIntent{Amount: amount, Currency: currency}
+	return Intent{ProviderRef: ref, ClientSecret: ref + "_secret"}, nil
+}
+
+func (g *FakeGateway) Capture(ctx context.Context, providerRef string) error {
+	return g.update(providerRef, func(intent *FakeIntent) error {
+		if intent.Canceled {
+			return fmt.Errorf("intent %s is cancelled", providerRef)
+		}
+		intent.Captured = true
+		return nil
+	})
+}
+
+func (g *FakeGateway) Cancel(ctx context.Context, providerRef string) error {
+	return g.update(providerRef, func(intent *FakeIntent) error {
+		if intent.Captured {
+			return fmt.Errorf("intent %s is already captured", providerRef)
+		}
+		intent.Canceled = true
+		return nil
+	})
+}
+
+func (g *FakeGateway) Refund(ctx context.Context, providerRef string, amount int64, idempotencyKey string) (string, error) {
+	var ref string
+	err := g.update(providerRef, func(intent *FakeIntent) error {
+		// Like Stripe, a retried refund returns the first result instead of paying out again
+		if existing, exists := g.refunds[idempotencyKey]; exists && idempotencyKey != "" {
+			ref = existing
+			return nil
+		}
+		if !intent.Captured || intent.Refunded+amount > intent.Amount {
+			return fmt.Errorf("%w: cannot refund %d on intent %s", ErrRejected, amount, providerRef)
+		}
+		intent.Refunded += amount
+		ref = "fake_re_" + uuid.New().String()
+		if idempotencyKey != "" {
+			g.refunds[idempotencyKey] = ref
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return ref, nil
+}
+
+// Intent returns a copy of the in-memory state of an intent
+func (g *FakeGateway) Intent(providerRef string) (FakeIntent, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	intent, exists := g.intents[providerRef]
+	if !exists {
+		return FakeIntent{}, false
+	}
+	return *intent, true
+}
+
+// fakeEvent is the JSON body of a fake webhook
+type fakeEvent struct {
+	ID          string    `json:"id"`
+	Type        EventType `json:"type"`
+	ProviderRef string    `json:"provider_ref"`
+	Amount      int64     `json:"amount"`
+}
+
+// SignedEvent builds the headers and body of a webhook delivery for event,
+// ready to be posted to the webhook endpoint
+func (g *FakeGateway) SignedEvent(event Event) (http.Header, []byte) {
+	if event.ID == "" {
+		event.ID = "fake_evt_" + uuid.New().String()
+	}
+	body, _ := json.Marshal(fakeEvent(event))
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(fakeSignatureHeader, g.sign(body))
+	return header, body
+}
+
+func (g *FakeGateway) ParseWebhook(header http.Header, body []byte) (Event, error) {
+	if g.secret == "" {
+		return Event{}, ErrInvalidSignature
+	}
+	signature, err := hex.DecodeString(header.Get(fakeSignatureHeader))
+	if err != nil || !hmac.Equal(signature, g.mac(body)) {
+		return Event{}, ErrInvalidSignature
+	}
+
+	var event fakeEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return Event{}, fmt.Errorf("invalid fake event: %w", err)
+	}
+	return Event(event), nil
+}
+
+func (g *FakeGateway) update(providerRef string, apply func(*FakeIntent) error) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	intent, exists := g.intents[providerRef]
+	if !exists {
+		return fmt.Errorf("%w: intent %s not found", ErrRejected, providerRef)
+	}
+	return apply(intent)
+}
+
+func (g *FakeGateway) mac(body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(g.secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+func (g *FakeGateway) sign(body []byte) string {
+	return hex.EncodeToString(g.mac(body))
+}
@@ -0,0 +1,120 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFakeWebhookRoundTrip(t *testing.T) {
+	gateway := NewFakeGateway("whsec_test")
+	sent := Event{Type: EventCaptured, ProviderRef: "fake_pi_1", Amount: 150000}
+
+	header, body := gateway.SignedEvent(sent)
+	event, err := gateway.ParseWebhook(header, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.ID == "" || event.Type != sent.Type || event.ProviderRef != sent.ProviderRef || event.Amount != sent.Amount {
+		t.Errorf("ParseWebhook = %+v, want %+v with a generated ID", event, sent)
+	}
+
+	// A delivery keeps its event ID, so redeliveries can be recognized
+	sent.ID = "evt_1"
+	header, body = gateway.SignedEvent(sent)
+	if event, err := gateway.ParseWebhook(header, body); err != nil || event.ID != "evt_1" {
+		t.Errorf("ParseWebhook = %+v, %v, want ID evt_1", event, err)
+	}
+}
+
+func TestFakeWebhookRejectsBadSignatures(t *testing.T) {
+	gateway := NewFakeGateway("whsec_test")
+	header, body := gateway.SignedEvent(Event{Type: EventAuthorized, ProviderRef: "fake_pi_1"})
+
+	tampered := append([]byte{}, body...)
+	tampered[len(tampered)-2] = '9'
+	if _, err := gateway.ParseWebhook(header, tampered); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("tampered body: %v, want %v", err, ErrInvalidSignature)
+	}
+
+	if _, err := NewFakeGateway("other").ParseWebhook(header, body); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("other secret: %v, want %v", err, ErrInvalidSignature)
+	}
+
+	unsigned := header.Clone()
+	unsigned.Del(fakeSignatureHeader)
+	if _, err := gateway.ParseWebhook(unsigned, body); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("missing signature: %v, want %v", err, ErrInvalidSignature)
+	}
+
+	// Without a secret anyone could sign, so nothing is accepted
+	open := NewFakeGateway("")
+	header, body = open.SignedEvent(Event{Type: EventCaptured, ProviderRef: "fake_pi_1"})
+	if _, err := open.ParseWebhook(header, body); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("empty secret: %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestFakeRefundIdempotency(t *testing.T) {
+	ctx := context.Background()
+	gateway := NewFakeGateway("whsec_test")
+	intent, err := gateway.CreateIntent(ctx, 1000, "IDR", "booking-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := gateway.Refund(ctx, intent.ProviderRef, 400, "refund-1-1"); err == nil {
+		t.Error("refunded an intent that was never captured")
+	}
+	if err := gateway.Capture(ctx, intent.ProviderRef); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := gateway.Refund(ctx, intent.ProviderRef, 400, "refund-1-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	retried, err := gateway.Refund(ctx, intent.ProviderRef, 400, "refund-1-1")
+	if err != nil || retried != first {
+		t.Errorf("retried refund = %q, %v, want %q", retried, err, first)
+	}
+	if state, _ := gateway.Intent(intent.ProviderRef); state.Refunded != 400 {
+		t.Errorf("refunded %d after a retry, want 400", state.Refunded)
+	}
+
+	second, err := gateway.Refund(ctx, intent.ProviderRef, 600, "refund-1-2")
+	if err != nil || second == first {
+		t.Errorf("second refund = %q, %v, want a new refund", second, err)
+	}
+	if _, err := gateway.Refund(ctx, intent.ProviderRef, 1, "refund-1-3"); err == nil {
+		t.Error("refunded more than was captured")
+	}
+	if state, _ := gateway.Intent(intent.ProviderRef); state.Refunded != 1000 {
+		t.Errorf("refunded %d, want 1000", state.Refunded)
+	}
+}
+
+func TestFakeCaptureAndCancel(t *testing.T) {
+	ctx := context.Background()
+	gateway := NewFakeGateway("whsec_test")
+
+	captured, _ := gateway.CreateIntent(ctx, 1000, "IDR", "booking-1")
+	if err := gateway.Capture(ctx, captured.ProviderRef); err != nil {
+		t.Fatal(err)
+	}
+	if err := gateway.Cancel(ctx, captured.ProviderRef); err == nil {
+		t.Error("cancelled a captured intent")
+	}
+
+	cancelled, _ := gateway.CreateIntent(ctx, 1000, "IDR", "booking-2")
+	if err := gateway.Cancel(ctx, cancelled.ProviderRef); err != nil {
+		t.Fatal(err)
+	}
+	if err := gateway.Capture(ctx, cancelled.ProviderRef); err == nil {
+		t.Error("captured a cancelled intent")
+	}
+
+	if err := gateway.Capture(ctx, "fake_pi_missing"); err == nil {
+		t.Error("captured an unknown intent")
+	}
+}
@@ -0,0 +1,338 @@
+package payments
+
+import (
+	"backend-go/config"
+	"backend-go/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrDuplicateEvent is returned for webhook deliveries that were already processed
+	ErrDuplicateEvent = errors.New("webhook event already processed")
+
+	// ErrNotRefundable is returned when a refund exceeds the captured, not yet refunded amount
+	ErrNotRefundable = errors.New("amount exceeds the refundable amount")
+)
+
+// Start creates a payment intent for the booking's amount with the active gateway.
+// The returned client secret is only handed to the payer and never stored.
+func Start(ctx context.Context, booking models.Booking) (models.Payment, string, error) {
+	gateway := Gateway()
+
+	intent, err := gateway.CreateIntent(ctx, booking.Amount, booking.Currency, fmt.Sprintf("booking-%d", booking.ID))
+	if err != nil {
+		return models.Payment{}, "", err
+	}
+
+	payment := models.Payment{
+		BookingID:   booking.ID,
+		Gateway:     gateway.Name(),
+		ProviderRef: intent.ProviderRef,
+		Amount:      booking.Amount,
+		Currency:    booking.Currency,
+		Status:      models.PaymentRequiresPayment,
+	}
+	if err := config.DB.Create(&payment).Error; err != nil {
+		// Do not leave an intent behind that nothing points to
+		gateway.Cancel(ctx, intent.ProviderRef)
+		return models.Payment{}, "", err
+	}
+
+	return payment, intent.ClientSecret, nil
+}
+
+// HandleWebhook verifies a webhook delivery and applies it. The event is recorded in the
+// same transaction as its effects, so a failed delivery is retried by the gateway and a
+// successful one is never applied twice. Gateway calls the event calls for are made once the
+// transaction has committed: a capture or cancel that fails is left pending on the payment and
+// made again when the gateway redelivers the event.
+func HandleWebhook(ctx context.Context, gateway PaymentGateway, header http.Header, body []byte) error {
+	event, err := gateway.ParseWebhook(header, body)
+	if errors.Is(err, ErrInvalidSignature) {
+		return err
+	}
+	if err != nil || event.ID == "" {
+		return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	var refund *models.PaymentRefund
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		record := models.WebhookEvent{Gateway: gateway.Name(), EventID: event.ID, Type: string(event.Type)}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrDuplicateEvent
+		}
+
+		if event.Type == EventIgnored {
+			return nil
+		}
+
+		var payment models.Payment
+		err := tx.Where("gateway = ? AND provider_ref = ?", gateway.Name(), event.ProviderRef).
+			First(&payment).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Ignoring %s webhook %s for unknown intent %s", gateway.Name(), event.ID, event.ProviderRef)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Lock the booking before the payment, in the same order as a cancellation, so the two
+		// cannot deadlock
+		var booking models.Booking
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, payment.BookingID).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, payment.ID).Error; err != nil {
+			return err
+		}
+
+		refund, err = applyEvent(tx, event, &payment, &booking)
+		return err
+	})
+	if errors.Is(err, ErrDuplicateEvent) {
+		// The first delivery may have been retried because its gateway call failed
+		if err := completeAction(ctx, gateway, event.ProviderRef); err != nil {
+			return err
+		}
+		return ErrDuplicateEvent
+	}
+	if err != nil {
+		return err
+	}
+	if err := completeAction(ctx, gateway, event.ProviderRef); err != nil {
+		return err
+	}
+	if refund == nil {
+		return nil
+	}
+
+	// The event is applied, a refund that does not go through is left to RetryRefunds rather than redelivery
+	if err := IssueRefund(ctx, refund); err != nil {
+		log.Printf("Failed to refund payment %d after %s webhook %s: %v", refund.PaymentID, gateway.Name(), event.ID, err)
+	}
+	return nil
+}
+
+// applyEvent moves the payment and its booking forward according to event. A capture or cancel it
+// decides on is left in the payment's PendingAction and a refund is returned, both to be made with
+// the gateway once tx commits.
+func applyEvent(tx *gorm.DB, event Event, payment *models.Payment, booking *models.Booking) (*models.PaymentRefund, error) {
+	switch event.Type {
+	case EventAuthorized:
+		if payment.Status == models.PaymentCancelled && payment.CapturedAmount == 0 {
+			// Cancelled here, e.g. when the booking's hold ran out, while the payer was authorizing it
+			payment.PendingAction = models.PaymentActionCancel
+			break
+		}
+		if payment.Status != models.PaymentRequiresPayment && payment.Status != models.PaymentFailed {
+			return nil, nil
+		}
+		// Only take the money if the seats are still held for the visitor
+		if booking.Status == models.BookingPending {
+			payment.Status = models.PaymentAuthorized
+			payment.PendingAction = models.PaymentActionCapture
+		} else {
+			payment.Status = models.PaymentCancelled
+			payment.PendingAction = models.PaymentActionCancel
+		}
+
+	case EventCaptured:
+		if payment.Status == models.PaymentCaptured || payment.RefundedAmount > 0 {
+			return nil, nil
+		}
+		payment.Status = models.PaymentCaptured
+		payment.CapturedAmount = event.Amount
+		payment.PendingAction = ""
+
+		switch booking.Status {
+		case models.BookingPending:
+			booking.Status = models.BookingConfirmed
+			if err := tx.Save(booking).Error; err != nil {
+				return nil, err
+			}
+		case models.BookingCancelled:
+			// The visitor cancelled while the capture was in flight
+			refund, err := ReserveRefund(tx, payment, payment.Refundable(), "booking cancelled before the payment completed")
+			if err != nil {
+				return nil, err
+			}
+			return &refund, nil
+		}
+
+	case EventFailed:
+		if payment.Status == models.PaymentRequiresPayment || payment.Status == models.PaymentAuthorized {
+			payment.Status = models.PaymentFailed
+		}
+
+	case EventCancelled:
+		if payment.Status != models.PaymentCaptured && payment.RefundedAmount == 0 {
+			payment.Status = models.PaymentCancelled
+		}
+
+	case EventRefunded:
+		// Refunds issued through Refund are already counted, the event carries the running total
+		if event.Amount > payment.RefundedAmount {
+			payment.RefundedAmount = event.Amount
+		}
+		payment.Status = refundStatus(*payment)
+	}
+
+	return nil, tx.Save(payment).Error
+}
+
+// completeAction makes the capture or cancel a webhook left pending on the payment of providerRef.
+// Both are idempotent with the gateway, so making one again after a lost response is harmless.
+func completeAction(ctx context.Context, gateway PaymentGateway, providerRef string) error {
+	var payment models.Payment
+	err := config.DB.Where("gateway = ? AND provider_ref = ? AND pending_action <> ''", gateway.Name(), providerRef).
+		First(&payment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch payment.PendingAction {
+	case models.PaymentActionCapture:
+		err = gateway.Capture(ctx, providerRef)
+	case models.PaymentActionCancel:
+		err = gateway.Cancel(ctx, providerRef)
+	}
+	if err != nil {
+		return fmt.Errorf("%s payment %d: %w", payment.PendingAction, payment.ID, err)
+	}
+
+	return config.DB.Model(&models.Payment{}).
+		Where("id = ? AND pending_action = ?", payment.ID, payment.PendingAction).
+		Update("pending_action", "").Error
+}
+
+// CompleteAction makes the capture or cancel left pending on payment, for callers that set
+// PendingAction themselves
+func CompleteAction(ctx context.Context, payment models.Payment) error {
+	gateway, exists := Lookup(payment.Gateway)
+	if !exists {
+		return fmt.Errorf("payment gateway %q is not configured", payment.Gateway)
+	}
+	return completeAction(ctx, gateway, payment.ProviderRef)
+}
+
+// ReserveRefund records a pending refund of amount of a captured payment and counts it as refunded,
+// so concurrent refunds cannot exceed what was captured. tx should hold a lock on the payment row.
+// No money moves until IssueRefund runs after tx commits, so a rolled back transaction never
+// leaves a refund behind.
+func ReserveRefund(tx *gorm.DB, payment *models.Payment, amount int64, reason string) (models.PaymentRefund, error) {
+	if amount <= 0 || amount > payment.Refundable() {
+		return models.PaymentRefund{}, ErrNotRefundable
+	}
+
+	// The payment row lock keeps the sequence unique
+	var previous int64
+	if err := tx.Unscoped().Model(&models.PaymentRefund{}).Where("payment_id = ?", payment.ID).Count(&previous).Error; err != nil {
+		return models.PaymentRefund{}, err
+	}
+
+	refund := models.PaymentRefund{
+		PaymentID: payment.ID,
+		Sequence:  int(previous) + 1,
+		Amount:    amount,
+		Reason:    reason,
+		Status:    models.RefundPending,
+	}
+	if err := tx.Create(&refund).Error; err != nil {
+		return models.PaymentRefund{}, err
+	}
+
+	payment.RefundedAmount += amount
+	if err := tx.Save(payment).Error; err != nil {
+		return models.PaymentRefund{}, err
+	}
+
+	return refund, nil
+}
+
+// IssueRefund asks the gateway to pay out a pending refund and records the outcome. A refund the
+// gateway rejects is marked failed and its amount becomes refundable again. When the outcome is
+// unknown, after a timeout or a gateway error, the refund stays pending and RetryRefunds sends it
+// again with the same idempotency key.
+func IssueRefund(ctx context.Context, refund *models.PaymentRefund) error {
+	var payment models.Payment
+	if err := config.DB.First(&payment, refund.PaymentID).Error; err != nil {
+		return err
+	}
+	if err := config.DB.Model(&models.PaymentRefund{}).Where("id = ?", refund.ID).
+		Update("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+		return err
+	}
+	refund.Attempts++
+
+	gateway, exists := Lookup(payment.Gateway)
+	if !exists {
+		return fmt.Errorf("payment gateway %q is not configured", payment.Gateway)
+	}
+	status, providerRef, refundErr := sendRefund(ctx, gateway, payment, *refund)
+	if status == models.RefundPending {
+		return refundErr
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, refund.PaymentID).Error; err != nil {
+			return err
+		}
+
+		// A concurrent retry of the same refund may have recorded the outcome already
+		result := tx.Model(&models.PaymentRefund{}).Where("id = ? AND status = ?", refund.ID, models.RefundPending).
+			Updates(map[string]interface{}{"status": status, "provider_ref": providerRef})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		refund.Status = status
+		refund.ProviderRef = providerRef
+
+		if status == models.RefundFailed {
+			payment.RefundedAmount -= refund.Amount
+		} else {
+			payment.Status = refundStatus(payment)
+		}
+		return tx.Save(&payment).Error
+	})
+	if err != nil {
+		return err
+	}
+	return refundErr
+}
+
+// sendRefund asks gateway for a refund and tells which status the answer leaves it in: succeeded
+// with the gateway's refund ID, failed when the gateway rejected it, or still pending when it is
+// unknown whether the gateway paid it out
+func sendRefund(ctx context.Context, gateway PaymentGateway, payment models.Payment, refund models.PaymentRefund) (string, string, error) {
+	providerRef, err := gateway.Refund(ctx, payment.ProviderRef, refund.Amount, refund.IdempotencyKey())
+	switch {
+	case err == nil:
+		return models.RefundSucceeded, providerRef, nil
+	case errors.Is(err, ErrRejected):
+		return models.RefundFailed, "", err
+	default:
+		return models.RefundPending, "", err
+	}
+}
+
+func refundStatus(payment models.Payment) string {
+	if payment.RefundedAmount >= payment.CapturedAmount {
+		return models.PaymentRefunded
+	}
+	return models.PaymentPartiallyRefunded
+}
@@ -0,0 +1,136 @@
+package payments
+
+import (
+	"backend-go/models"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// timeoutGateway pays out refunds but loses the answer of the first failures calls, like a
+// gateway call that times out after the gateway acted on it
+type timeoutGateway struct {
+	*FakeGateway
+	failures int
+}
+
+func (g *timeoutGateway) Refund(ctx context.Context, providerRef string, amount int64, idempotencyKey string) (string, error) {
+	ref, err := g.FakeGateway.Refund(ctx, providerRef, amount, idempotencyKey)
+	if err == nil && g.failures > 0 {
+		g.failures--
+		return "", context.DeadlineExceeded
+	}
+	return ref, err
+}
+
+func capturedPayment(t *testing.T, gateway *FakeGateway, amount int64) models.Payment {
+	t.Helper()
+	ctx := context.Background()
+	intent, err := gateway.CreateIntent(ctx, amount, "IDR", "booking-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gateway.Capture(ctx, intent.ProviderRef); err != nil {
+		t.Fatal(err)
+	}
+	payment := models.Payment{Gateway: gateway.Name(), ProviderRef: intent.ProviderRef, Amount: amount, CapturedAmount: amount}
+	payment.ID = 12
+	return payment
+}
+
+func TestSendRefundRetriedAfterTimeout(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeGateway("whsec_test")
+	gateway := &timeoutGateway{FakeGateway: fake, failures: 1}
+	payment := capturedPayment(t, fake, 1000)
+	refund := models.PaymentRefund{PaymentID: payment.ID, Sequence: 1, Amount: 400, Status: models.RefundPending}
+
+	status, ref, err := sendRefund(ctx, gateway, payment, refund)
+	if status != models.RefundPending || ref != "" || err == nil {
+		t.Fatalf("first attempt = %s, %q, %v, want pending with an error", status, ref, err)
+	}
+
+	status, ref, err = sendRefund(ctx, gateway, payment, refund)
+	if status != models.RefundSucceeded || ref == "" || err != nil {
+		t.Fatalf("retry = %s, %q, %v, want succeeded", status, ref, err)
+	}
+	if intent, _ := fake.Intent(payment.ProviderRef); intent.Refunded != 400 {
+		t.Errorf("refunded %d after a timeout and a retry, want 400", intent.Refunded)
+	}
+}
+
+func TestSendRefundRejected(t *testing.T) {
+	fake := NewFakeGateway("whsec_test")
+	payment := capturedPayment(t, fake, 1000)
+	refund := models.PaymentRefund{PaymentID: payment.ID, Sequence: 1, Amount: 1500, Status: models.RefundPending}
+
+	status, _, err := sendRefund(context.Background(), fake, payment, refund)
+	if status != models.RefundFailed || !errors.Is(err, ErrRejected) {
+		t.Errorf("sendRefund = %s, %v, want failed with %v", status, err, ErrRejected)
+	}
+}
+
+func TestStripeRefundOutcomes(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		want   string
+	}{
+		{"server error", http.StatusInternalServerError, models.RefundPending},
+		{"idempotent request in progress", http.StatusConflict, models.RefundPending},
+		{"rate limited", http.StatusTooManyRequests, models.RefundPending},
+		{"invalid request", http.StatusBadRequest, models.RefundFailed},
+		{"card declined", http.StatusPaymentRequired, models.RefundFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The first attempt gets the error, the retry succeeds
+			var keys []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				keys = append(keys, r.Header.Get("Idempotency-Key"))
+				if len(keys) == 1 {
+					w.WriteHeader(tt.status)
+					fmt.Fprint(w, `{"error": {"message": "try again"}}`)
+					return
+				}
+				fmt.Fprint(w, `{"id": "re_123"}`)
+			}))
+			defer server.Close()
+
+			gateway := NewStripeGateway("sk_test", "whsec_test")
+			gateway.api = server.URL
+			payment := models.Payment{Gateway: "stripe", ProviderRef: "pi_123"}
+			payment.ID = 12
+			refund := models.PaymentRefund{PaymentID: payment.ID, Sequence: 2, Amount: 400}
+
+			if status, _, _ := sendRefund(context.Background(), gateway, payment, refund); status != tt.want {
+				t.Errorf("first attempt left the refund %s, want %s", status, tt.want)
+			}
+			status, ref, err := sendRefund(context.Background(), gateway, payment, refund)
+			if status != models.RefundSucceeded || ref != "re_123" || err != nil {
+				t.Errorf("retry = %s, %q, %v, want succeeded with re_123", status, ref, err)
+			}
+			if len(keys) != 2 || keys[0] != "refund-12-2" || keys[1] != keys[0] {
+				t.Errorf("idempotency keys = %v, want refund-12-2 twice", keys)
+			}
+		})
+	}
+}
+
+func TestStripeRefundUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	gateway := NewStripeGateway("sk_test", "whsec_test")
+	gateway.api = server.URL
+	refund := models.PaymentRefund{PaymentID: 12, Sequence: 1, Amount: 400}
+
+	status, _, err := sendRefund(context.Background(), gateway, models.Payment{ProviderRef: "pi_123"}, refund)
+	if status != models.RefundPending || err == nil || errors.Is(err, ErrRejected) {
+		t.Errorf("sendRefund = %s, %v, want pending with a transport error", status, err)
+	}
+}
@@ -0,0 +1,110 @@
+package payments
+
+import (
+	"backend-go/config"
+	"backend-go/models"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// refundRetryInterval is how often RetryRefunds looks for refunds to send again
+	refundRetryInterval = 10 * time.Minute
+
+	// refundRetryDelay leaves a refund alone for this long after its last attempt, so the request
+	// that reserved it has time to issue it
+	refundRetryDelay = 5 * time.Minute
+
+	// refundTimeout bounds one gateway call of RetryRefunds
+	refundTimeout = 30 * time.Second
+
+	// MaxRefundAttempts is how often a rejected refund is sent before it is left failed. Pending
+	// refunds are sent until the gateway answers, the money may already be on its way.
+	MaxRefundAttempts = 5
+)
+
+// errRefundChanged is returned when a failed refund was retried or its amount refunded otherwise
+var errRefundChanged = errors.New("refund changed")
+
+// StartRefundRetries runs RetryRefunds in the background every refundRetryInterval
+func StartRefundRetries() {
+	go func() {
+		ticker := time.NewTicker(refundRetryInterval)
+		defer ticker.Stop()
+
+		for ; ; <-ticker.C {
+			if err := RetryRefunds(context.Background(), time.Now()); err != nil {
+				log.Printf("Failed to retry refunds: %v", err)
+			}
+		}
+	}()
+}
+
+// RetryRefunds sends refunds again that the gateway never answered or rejected fewer than
+// MaxRefundAttempts times, with the idempotency key of their first attempt. A refund left pending by
+// a crash or a timeout is paid out at most once, and one refused for a passing reason is paid out
+// once the gateway accepts it.
+func RetryRefunds(ctx context.Context, now time.Time) error {
+	var refunds []models.PaymentRefund
+	if err := config.DB.
+		Where("updated_at < ?", now.Add(-refundRetryDelay)).
+		Where(config.DB.Where("status = ?", models.RefundPending).
+			Or("status = ? AND attempts < ?", models.RefundFailed, MaxRefundAttempts)).
+		Order("id").Find(&refunds).Error; err != nil {
+		return err
+	}
+
+	for i := range refunds {
+		refund := &refunds[i]
+		if refund.Status == models.RefundFailed {
+			err := reopenRefund(refund)
+			if errors.Is(err, ErrNotRefundable) || errors.Is(err, errRefundChanged) {
+				continue
+			}
+			if err != nil {
+				log.Printf("Failed to reopen refund %d of payment %d: %v", refund.ID, refund.PaymentID, err)
+				continue
+			}
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, refundTimeout)
+		err := IssueRefund(callCtx, refund)
+		cancel()
+		if err != nil {
+			log.Printf("Refund %d of payment %d is still %s after attempt %d: %v", refund.ID, refund.PaymentID, refund.Status, refund.Attempts, err)
+		}
+	}
+	return nil
+}
+
+// reopenRefund makes a failed refund pending again and counts its amount as refunded, as long as the
+// payment still has that much left to refund
+func reopenRefund(refund *models.PaymentRefund) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var payment models.Payment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, refund.PaymentID).Error; err != nil {
+			return err
+		}
+		if refund.Amount > payment.Refundable() {
+			return ErrNotRefundable
+		}
+
+		result := tx.Model(&models.PaymentRefund{}).Where("id = ? AND status = ?", refund.ID, models.RefundFailed).
+			Update("status", models.RefundPending)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRefundChanged
+		}
+		refund.Status = models.RefundPending
+
+		payment.RefundedAmount += refund.Amount
+		return tx.Save(&payment).Error
+	})
+}
@@ -0,0 +1,209 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	stripeAPI = "https://api.stripe.com/v1"
+
+	// stripeSignatureTolerance rejects replayed webhooks older than this
+	stripeSignatureTolerance = 5 * time.Minute
+)
+
+// StripeGateway talks to the Stripe Payment Intents API. Intents are created with
+// manual capture, so funds are only captured once the platform accepts the booking.
+type StripeGateway struct {
+	api           string
+	secretKey     string
+	webhookSecret string
+	client        *http.Client
+}
+
+// NewStripeGateway creates a Stripe adapter from an API secret key and a webhook signing secret
+func NewStripeGateway(secretKey, webhookSecret string) *StripeGateway {
+	return &StripeGateway{
+		api:           stripeAPI,
+		secretKey:     secretKey,
+		webhookSecret: webhookSecret,
+		client:        &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (g *StripeGateway) Name() string {
+	return "stripe"
+}
+
+func (g *StripeGateway) CreateIntent(ctx context.Context, amount int64, currency, reference string) (Intent, error) {
+	form := url.Values{}
+	form.Set("amount", strconv.FormatInt(amount, 10))
+	form.Set("currency", strings.ToLower(currency))
+	form.Set("capture_method", "manual")
+	form.Set("metadata[reference]", reference)
+
+	var intent struct {
+		ID           string `json:"id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	if err := g.post(ctx, "/payment_intents", form, "", &intent); err != nil {
+		return Intent{}, err
+	}
+	return Intent{ProviderRef: intent.ID, ClientSecret: intent.ClientSecret}, nil
+}
+
+func (g *StripeGateway) Capture(ctx context.Context, providerRef string) error {
+	return g.post(ctx, "/payment_intents/"+providerRef+"/capture", url.Values{}, "capture-"+providerRef, nil)
+}
+
+func (g *StripeGateway) Cancel(ctx context.Context, providerRef string) error {
+	return g.post(ctx, "/payment_intents/"+providerRef+"/cancel", url.Values{}, "cancel-"+providerRef, nil)
+}
+
+func (g *StripeGateway) Refund(ctx context.Context, providerRef string, amount int64, idempotencyKey string) (string, error) {
+	form := url.Values{}
+	form.Set("payment_intent", providerRef)
+	form.Set("amount", strconv.FormatInt(amount, 10))
+
+	var refund struct {
+		ID string `json:"id"`
+	}
+	if err := g.post(ctx, "/refunds", form, idempotencyKey, &refund); err != nil {
+		return "", err
+	}
+	return refund.ID, nil
+}
+
+// ParseWebhook verifies the Stripe-Signature header and maps the Stripe event to an Event
+func (g *StripeGateway) ParseWebhook(header http.Header, body []byte) (Event, error) {
+	if err := g.verifySignature(header.Get("Stripe-Signature"), body); err != nil {
+		return Event{}, err
+	}
+
+	var payload struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object struct {
+				ID               string `json:"id"`
+				PaymentIntent    string `json:"payment_intent"`
+				AmountCapturable int64  `json:"amount_capturable"`
+				AmountReceived   int64  `json:"amount_received"`
+				AmountRefunded   int64  `json:"amount_refunded"`
+			} `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return Event{}, fmt.Errorf("invalid Stripe event: %w", err)
+	}
+
+	object := payload.Data.Object
+	event := Event{ID: payload.ID, ProviderRef: object.ID}
+	switch payload.Type {
+	case "payment_intent.amount_capturable_updated":
+		event.Type, event.Amount = EventAuthorized, object.AmountCapturable
+	case "payment_intent.succeeded":
+		event.Type, event.Amount = EventCaptured, object.AmountReceived
+	case "payment_intent.payment_failed":
+		event.Type = EventFailed
+	case "payment_intent.canceled":
+		event.Type = EventCancelled
+	case "charge.refunded":
+		// Refunds are reported on the charge, which points back to its intent
+		event.Type, event.ProviderRef, event.Amount = EventRefunded, object.PaymentIntent, object.AmountRefunded
+	default:
+		event.Type = EventIgnored
+	}
+	return event, nil
+}
+
+// verifySignature checks a "t=<timestamp>,v1=<hmac>" header against HMAC-SHA256("<timestamp>.<body>")
+func (g *StripeGateway) verifySignature(header string, body []byte) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 || g.webhookSecret == "" {
+		return ErrInvalidSignature
+	}
+	if age := time.Since(time.Unix(seconds, 0)); age > stripeSignatureTolerance || age < -stripeSignatureTolerance {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(g.webhookSecret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+
+	for _, signature := range signatures {
+		if decoded, err := hex.DecodeString(signature); err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// post sends a form encoded request, idempotencyKey lets Stripe deduplicate retried calls. Errors
+// wrap ErrRejected when Stripe answered that it will not carry out the request.
+func (g *StripeGateway) post(ctx context.Context, path string, form url.Values, idempotencyKey string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.api+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(g.secretKey, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("stripe request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		var apiError struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(body, &apiError)
+		err := fmt.Errorf("stripe returned %d: %s", resp.StatusCode, apiError.Error.Message)
+		// Other client errors are final. A 409 is a concurrent request with the same idempotency key
+		// still running and a 429 asks to slow down, neither says the request will not be carried out.
+		if resp.StatusCode < 500 && resp.StatusCode != http.StatusConflict && resp.StatusCode != http.StatusTooManyRequests {
+			err = fmt.Errorf("%w: %w", ErrRejected, err)
+		}
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
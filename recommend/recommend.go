@@ -0,0 +1,89 @@
+package recommend
+
+import (
+	"backend-go/currency"
+	"backend-go/models"
+	"sort"
+	"sync"
+)
+
+// Location is a point the user wants trips near
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Profile is what is known about the user a recommendation is made for
+type Profile struct {
+	Preferences map[uint]string // Preference IDs the user picked, with their names
+	Location    *Location       // Optional, from the request
+	Budget      int64           // Per participant budget in minor units of currency.Base, 0 when unknown
+	Rates       currency.Rates  // Used to compare trip prices in different currencies with Budget
+}
+
+// Result is the score of one trip, Signals holds the normalized inputs and Reasons
+// the human readable explanation shown to the user
+type Result struct {
+	Score   float64            `json:"score"`
+	Signals map[string]float64 `json:"signals"`
+	Reasons []string           `json:"why_recommended"`
+}
+
+// Recommendation is a scored trip
+type Recommendation struct {
+	Trip models.Trip `json:"trip"`
+	Result
+}
+
+// Scorer ranks trips for a profile, higher scores are recommended first
+type Scorer interface {
+	Score(profile Profile, trip models.Trip) Result
+}
+
+var (
+	scorersMu sync.RWMutex
+	scorers   = map[string]Scorer{
+		DefaultScorer: NewWeightedScorer(DefaultSignals...),
+	}
+)
+
+// DefaultScorer is the name of the scorer used when the request does not pick one
+const DefaultScorer = "weighted"
+
+// Register makes a scorer available under name, replacing any scorer with that name
+func Register(name string, scorer Scorer) {
+	scorersMu.Lock()
+	defer scorersMu.Unlock()
+	scorers[name] = scorer
+}
+
+// Lookup returns the scorer registered under name
+func Lookup(name string) (Scorer, bool) {
+	scorersMu.RLock()
+	defer scorersMu.RUnlock()
+	scorer, exists := scorers[name]
+	return scorer, exists
+}
+
+// Rank scores every trip and returns the best limit of them, best first
+func Rank(scorer Scorer, profile Profile, trips []models.Trip, limit int) []Recommendation {
+	recommendations := make([]Recommendation, 0, len(trips))
+	for _, trip := range trips {
+		recommendations = append(recommendations, Recommendation{
+			Trip:   trip,
+			Result: scorer.Score(profile, trip),
+		})
+	}
+
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		if recommendations[i].Score != recommendations[j].Score {
+			return recommendations[i].Score > recommendations[j].Score
+		}
+		return recommendations[i].Trip.ID < recommendations[j].Trip.ID
+	})
+
+	if limit > 0 && len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+	return recommendations
+}
@@ -0,0 +1,145 @@
+package recommend
+
+import (
+	"backend-go/currency"
+	"backend-go/models"
+	"backend-go/utils"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Signal measures one aspect of how well a trip fits a profile. It returns a value
+// between 0 and 1 and a reason, ok is false when the signal has nothing to go on
+// (no location given, no budget known) and is then left out of the score.
+type Signal func(profile Profile, trip models.Trip) (value float64, reason string, ok bool)
+
+// WeightedSignal is a named signal and its weight in a WeightedScorer
+type WeightedSignal struct {
+	Name   string
+	Weight float64
+	Signal Signal
+}
+
+// DefaultSignals are the signals of the default scorer
+var DefaultSignals = []WeightedSignal{
+	{Name: "preferences", Weight: 0.45, Signal: PreferenceSignal},
+	{Name: "proximity", Weight: 0.2, Signal: ProximitySignal},
+	{Name: "price", Weight: 0.15, Signal: PriceSignal},
+	{Name: "rating", Weight: 0.2, Signal: RatingSignal},
+}
+
+// WeightedScorer scores trips with the weighted average of the signals that apply
+type WeightedScorer struct {
+	signals []WeightedSignal
+}
+
+// NewWeightedScorer creates a scorer from signals
+func NewWeightedScorer(signals ...WeightedSignal) *WeightedScorer {
+	return &WeightedScorer{signals: signals}
+}
+
+// Score implements Scorer
+func (s *WeightedScorer) Score(profile Profile, trip models.Trip) Result {
+	result := Result{Signals: make(map[string]float64)}
+
+	var total, weights float64
+	for _, signal := range s.signals {
+		value, reason, ok := signal.Signal(profile, trip)
+		if !ok {
+			continue
+		}
+		result.Signals[signal.Name] = round(value)
+		total += value * signal.Weight
+		weights += signal.Weight
+		if reason != "" {
+			result.Reasons = append(result.Reasons, reason)
+		}
+	}
+
+	if weights > 0 {
+		result.Score = round(total / weights)
+	}
+	return result
+}
+
+// proximityScaleKm is the distance at which the proximity signal drops to one half
+const proximityScaleKm = 10.0
+
+// priorRating and priorWeight pull the ratings of trips with few reviews towards the middle
+const (
+	priorRating = 3.5
+	priorWeight = 5.0
+)
+
+// PreferenceSignal is the share of the user's preferences the trip is tagged with
+func PreferenceSignal(profile Profile, trip models.Trip) (float64, string, bool) {
+	if len(profile.Preferences) == 0 {
+		return 0, "", false
+	}
+
+	var matched []string
+	for _, preference := range trip.Preferences {
+		if name, exists := profile.Preferences[preference.ID]; exists {
+			matched = append(matched, name)
+		}
+	}
+	if len(matched) == 0 {
+		return 0, "", true
+	}
+
+	sort.Strings(matched)
+	value := float64(len(matched)) / float64(len(profile.Preferences))
+	return value, "Matches your interests: " + strings.Join(matched, ", "), true
+}
+
+// ProximitySignal favours trips that start close to the requested location
+func ProximitySignal(profile Profile, trip models.Trip) (float64, string, bool) {
+	if profile.Location == nil {
+		return 0, "", false
+	}
+
+	distance := utils.DistanceKm(profile.Location.Latitude, profile.Location.Longitude, trip.StartLatitude, trip.StartLongitude)
+	value := 1 / (1 + distance/proximityScaleKm)
+	reason := ""
+	if value >= 0.5 {
+		reason = fmt.Sprintf("Starts %.1f km from you", distance)
+	}
+	return value, reason, true
+}
+
+// PriceSignal is 1 for trips within budget and falls off linearly to 0 at twice the budget
+func PriceSignal(profile Profile, trip models.Trip) (float64, string, bool) {
+	if profile.Budget <= 0 {
+		return 0, "", false
+	}
+
+	price, err := profile.Rates.Convert(trip.PriceAmount, trip.Currency, currency.Base)
+	if err != nil {
+		return 0, "", false
+	}
+	if price <= profile.Budget {
+		return 1, "Fits your budget", true
+	}
+
+	over := float64(price-profile.Budget) / float64(profile.Budget)
+	return math.Max(0, 1-over), "", true
+}
+
+// RatingSignal is the review average, shrunk towards the middle for trips with few reviews
+func RatingSignal(profile Profile, trip models.Trip) (float64, string, bool) {
+	count := float64(trip.ReviewCount)
+	rating := (trip.RatingAverage*count + priorRating*priorWeight) / (count + priorWeight)
+
+	reason := ""
+	if trip.ReviewCount > 0 && trip.RatingAverage >= 4 {
+		reason = fmt.Sprintf("Rated %.1f from %d reviews", trip.RatingAverage, trip.ReviewCount)
+	}
+	return (rating - 1) / 4, reason, true
+}
+
+// round keeps scores readable in responses
+func round(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
@@ -0,0 +1,351 @@
+package reconcile
+
+import (
+	"backend-go/config"
+	"backend-go/media"
+	"backend-go/models"
+	"backend-go/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Storage folders the job looks at, anything else in the store is left alone
+const (
+	imagesPrefix   = "images/"
+	coversPrefix   = "covers/"
+	blobsPrefix    = models.BlobFolder + "/"
+	variantsPrefix = media.VariantFolder + "/"
+	uploadsPrefix  = "tus/"
+)
+
+// Defaults for the job, IMAGE_GC_TTL and IMAGE_GC_GRACE override them
+const (
+	DefaultUnattachedTTL = 30 * 24 * time.Hour
+	DefaultGrace         = time.Hour
+)
+
+// unattachedCondition selects images never attached or detached from a trip, images of a deleted trip
+// and images whose trip row is gone altogether, for longer than a cutoff given three times. Clean
+// deletes with it again so an image attached since the scan stays.
+const unattachedCondition = `(images.trip_id IS NULL AND images.updated_at < ?)
+	OR EXISTS (SELECT 1 FROM trips WHERE trips.id = images.trip_id AND trips.deleted_at < ?)
+	OR (images.trip_id IS NOT NULL AND images.updated_at < ? AND NOT EXISTS (SELECT 1 FROM trips WHERE trips.id = images.trip_id))`
+
+// errChanged rolls back the cleanup of an image that changed since the scan
+var errChanged = errors.New("changed since the scan")
+
+// Options tune what counts as garbage
+type Options struct {
+	// UnattachedTTL is how long an image may stay outside a live trip before it is removed
+	UnattachedTTL time.Duration
+
+	// Grace skips files and rows younger than this, they may belong to an upload in progress
+	Grace time.Duration
+}
+
+// Report lists where storage and the images table disagree
+type Report struct {
+	OrphanFiles  []storage.Object // Stored files no blob, live image, variant source or upload owns
+	MissingFiles []models.Image   // Image rows whose file is gone
+	Unattached   []models.Image   // Images without a trip, or of a deleted trip, for longer than the TTL
+	UnusedBlobs  []models.Blob    // Blobs no live image refers to
+
+	cutoff time.Time // Images outside a live trip since before this are unattached
+}
+
+// Empty reports whether there is nothing to clean up
+func (r Report) Empty() bool {
+	return len(r.OrphanFiles) == 0 && len(r.MissingFiles) == 0 && len(r.Unattached) == 0 && len(r.UnusedBlobs) == 0
+}
+
+// Log writes the report to the application log, one line per finding
+func (r Report) Log() {
+	var orphanBytes int64
+	for _, object := range r.OrphanFiles {
+		orphanBytes += object.Size
+		log.Printf("Orphan file: %s (%d bytes, modified %s)", object.Key, object.Size, object.ModTime.Format(time.RFC3339))
+	}
+	for _, image := range r.MissingFiles {
+		log.Printf("Missing file: image %d (%s) has lost its file", image.ID, image.StorageKey)
+	}
+	for _, image := range r.Unattached {
+		log.Printf("Unattached image: image %d (%s), trip %s, last changed %s", image.ID, image.StorageKey, tripLabel(image.TripID), image.UpdatedAt.Format(time.RFC3339))
+	}
+	for _, blob := range r.UnusedBlobs {
+		log.Printf("Unused blob: blob %d (%s) has no images, %d counted", blob.ID, blob.StorageKey, blob.RefCount)
+	}
+	log.Printf("Image GC found %d orphan files (%d bytes), %d rows without files, %d unattached images, %d unused blobs",
+		len(r.OrphanFiles), orphanBytes, len(r.MissingFiles), len(r.Unattached), len(r.UnusedBlobs))
+}
+
+// OptionsFromEnv reads IMAGE_GC_TTL and IMAGE_GC_GRACE as Go durations, e.g. 720h
+func OptionsFromEnv() Options {
+	return Options{
+		UnattachedTTL: durationFromEnv("IMAGE_GC_TTL", DefaultUnattachedTTL),
+		Grace:         durationFromEnv("IMAGE_GC_GRACE", DefaultGrace),
+	}
+}
+
+// Scan compares the store with the images and upload sessions tables without changing anything
+func Scan(ctx context.Context, db *gorm.DB, store storage.Storage, opts Options) (Report, error) {
+	var report Report
+	now := time.Now()
+	settled := now.Add(-opts.Grace)
+
+	// Rows are loaded before files are listed: files are stored before their row is created, so a
+	// row seen here always has its file listed unless the file is really gone
+	var images []models.Image
+	if err := db.Select("id", "storage_key", "blob_id", "trip_id", "created_at", "updated_at").Find(&images).Error; err != nil {
+		return report, fmt.Errorf("load images: %w", err)
+	}
+	var blobs []models.Blob
+	if err := db.Find(&blobs).Error; err != nil {
+		return report, fmt.Errorf("load blobs: %w", err)
+	}
+	var tokens []string
+	if err := db.Model(&models.UploadSession{}).Pluck("token", &tokens).Error; err != nil {
+		return report, fmt.Errorf("load upload sessions: %w", err)
+	}
+	// Covers set before gallery covers existed may have no image row, the trip still shows them
+	var covers []string
+	if err := db.Model(&models.Trip{}).Where("cover_image <> ''").Pluck("cover_image", &covers).Error; err != nil {
+		return report, fmt.Errorf("load trip covers: %w", err)
+	}
+
+	live := make(map[string]bool, len(blobs)+len(covers))
+	liveSources := make(map[string]bool, len(blobs)+len(covers))
+	keep := func(key string) {
+		live[key] = true
+		liveSources[strings.TrimSuffix(key, path.Ext(key))] = true
+	}
+	blobKeys := make(map[uint]string, len(blobs))
+	for _, blob := range blobs {
+		blobKeys[blob.ID] = blob.StorageKey
+		keep(blob.StorageKey)
+	}
+	// The file of each image is its blob's, images from before deduplication own theirs
+	fileKeys := make(map[uint]string, len(images))
+	references := make(map[uint]int, len(blobs))
+	for _, image := range images {
+		fileKeys[image.ID] = image.StorageKey
+		if image.BlobID != nil {
+			fileKeys[image.ID] = blobKeys[*image.BlobID]
+			references[*image.BlobID]++
+		}
+		keep(fileKeys[image.ID])
+	}
+	for _, cover := range covers {
+		if key, ok := media.KeyFromPath(cover); ok {
+			keep(key)
+		}
+	}
+	sessions := make(map[string]bool, len(tokens))
+	for _, token := range tokens {
+		sessions[token] = true
+	}
+
+	stored := make(map[string]bool)
+	for _, prefix := range []string{imagesPrefix, coversPrefix, blobsPrefix, variantsPrefix, uploadsPrefix} {
+		err := store.List(ctx, prefix, func(object storage.Object) error {
+			stored[object.Key] = true
+			if object.ModTime.After(settled) {
+				return nil
+			}
+
+			var owned bool
+			switch prefix {
+			case imagesPrefix, coversPrefix, blobsPrefix:
+				owned = live[object.Key]
+			case variantsPrefix:
+				source, ok := media.VariantSource(object.Key)
+				owned = ok && liveSources[source]
+			case uploadsPrefix:
+				token, _, _ := strings.Cut(strings.TrimPrefix(object.Key, uploadsPrefix), "/")
+				owned = sessions[token]
+			}
+			if !owned {
+				report.OrphanFiles = append(report.OrphanFiles, object)
+			}
+			return nil
+		})
+		if err != nil {
+			return report, fmt.Errorf("list %s: %w", prefix, err)
+		}
+	}
+
+	missing := make(map[uint]bool)
+	for _, image := range images {
+		if !stored[fileKeys[image.ID]] && image.CreatedAt.Before(settled) {
+			report.MissingFiles = append(report.MissingFiles, image)
+			missing[image.ID] = true
+		}
+	}
+
+	report.cutoff = now.Add(-opts.UnattachedTTL)
+	var unattached []models.Image
+	if err := db.Where(unattachedCondition, report.cutoff, report.cutoff, report.cutoff).Find(&unattached).Error; err != nil {
+		return report, fmt.Errorf("load unattached images: %w", err)
+	}
+	for _, image := range unattached {
+		if !missing[image.ID] {
+			report.Unattached = append(report.Unattached, image)
+		}
+	}
+
+	for _, blob := range blobs {
+		if references[blob.ID] == 0 && blob.UpdatedAt.Before(settled) {
+			report.UnusedBlobs = append(report.UnusedBlobs, blob)
+		}
+	}
+
+	return report, nil
+}
+
+// Clean removes what a report found: orphan files are deleted, rows without files and unattached
+// images are deleted along with their last reference to a blob, and unused blobs are deleted with
+// their files. Each is checked again as it is deleted, anything that changed since the scan is
+// left alone. It carries on past failures and returns them together.
+func Clean(ctx context.Context, db *gorm.DB, store storage.Storage, report Report) error {
+	var errs []error
+	for _, object := range report.OrphanFiles {
+		// An upload of the same content may have brought a blob back since the scan
+		if strings.HasPrefix(object.Key, blobsPrefix) {
+			var blobs int64
+			if err := db.Model(&models.Blob{}).Where("storage_key = ?", object.Key).Count(&blobs).Error; err != nil || blobs > 0 {
+				continue
+			}
+		}
+		if err := store.Delete(ctx, object.Key); err != nil {
+			errs = append(errs, fmt.Errorf("delete %s: %w", object.Key, err))
+		}
+	}
+
+	for _, image := range report.MissingFiles {
+		// The file may have been stored again, and the row must still be the one found without it
+		key, err := models.FileKey(db, image)
+		if err != nil {
+			continue
+		}
+		if _, err := store.Stat(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		errs = append(errs, deleteImage(ctx, db, store, image, db.Where("updated_at = ?", image.UpdatedAt))...)
+	}
+	for _, image := range report.Unattached {
+		// The image may have been attached to a live trip since the scan
+		errs = append(errs, deleteImage(ctx, db, store, image, db.Where(unattachedCondition, report.cutoff, report.cutoff, report.cutoff))...)
+	}
+
+	for _, blob := range report.UnusedBlobs {
+		// An image may have taken a reference since the scan, the blob only goes while there is none
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Where("id = ? AND NOT EXISTS (SELECT 1 FROM images WHERE images.blob_id = blobs.id AND images.deleted_at IS NULL)", blob.ID).
+				Delete(&models.Blob{})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			errs = append(errs, deleteKeys(ctx, store, append([]string{blob.StorageKey}, media.AllVariantKeys(blob.StorageKey)...))...)
+			return nil
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("delete blob %d: %w", blob.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Start runs the job in the background every IMAGE_GC_INTERVAL (e.g. 24h), logging the report
+// before cleaning up. It does nothing when IMAGE_GC_INTERVAL is unset.
+func Start() {
+	raw := os.Getenv("IMAGE_GC_INTERVAL")
+	if raw == "" {
+		return
+	}
+	interval, err := time.ParseDuration(raw)
+	if err != nil || interval <= 0 {
+		log.Printf("Warning: ignoring invalid IMAGE_GC_INTERVAL=%q, image GC is off", raw)
+		return
+	}
+
+	opts := OptionsFromEnv()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx := context.Background()
+			report, err := Scan(ctx, config.DB, storage.Default(), opts)
+			if err != nil {
+				log.Printf("Image GC failed: %v", err)
+				continue
+			}
+			report.Log()
+			if err := Clean(ctx, config.DB, storage.Default(), report); err != nil {
+				log.Printf("Image GC could not clean everything: %v", err)
+			}
+		}
+	}()
+}
+
+// deleteImage deletes an image when it still matches condition and releases its blob. Like
+// DeleteImage, the file and its variants go with the last image showing them.
+func deleteImage(ctx context.Context, db *gorm.DB, store storage.Storage, image models.Image, condition *gorm.DB) []error {
+	var errs []error
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := models.ClearTripCover(tx, image.ID); err != nil {
+			return err
+		}
+		result := tx.Where("id = ?", image.ID).Where(condition).Delete(&models.Image{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errChanged
+		}
+		keys, err := models.ReleaseBlob(tx, image)
+		if err != nil {
+			return err
+		}
+		errs = deleteKeys(ctx, store, keys)
+		return nil
+	})
+	if err != nil && !errors.Is(err, errChanged) {
+		errs = append(errs, fmt.Errorf("delete image %d: %w", image.ID, err))
+	}
+	return errs
+}
+
+func deleteKeys(ctx context.Context, store storage.Storage, keys []string) []error {
+	var errs []error
+	for _, key := range keys {
+		if err := store.Delete(ctx, key); err != nil {
+			errs = append(errs, fmt.Errorf("delete %s: %w", key, err))
+		}
+	}
+	return errs
+}
+
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil && duration >= 0 {
+			return duration
+		}
+		log.Printf("Warning: ignoring invalid %s=%q", key, value)
+	}
+	return fallback
+}
+
+func tripLabel(tripID *uint) string {
+	if tripID == nil {
+		return "none"
+	}
+	return fmt.Sprintf("%d (deleted)", *tripID)
+}
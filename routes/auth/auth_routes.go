@@ -17,4 +17,5 @@ func SetupAuthRoutes(router *gin.RouterGroup) {
 	// Protected routes with middleware chaining
 	router.GET("/auth/profile", middleware.AuthMiddleware(), auth.GetProfile)
 	router.PUT("/auth/profile", middleware.AuthMiddleware(), auth.UpdateProfile)
+	router.PUT("/auth/password", middleware.AuthMiddleware(), auth.ChangePassword)
 }
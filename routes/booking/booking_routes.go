@@ -0,0 +1,27 @@
+package booking
+
+import (
+	"backend-go/controllers/booking"
+	"backend-go/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupBookingRoutes sets up booking routes
+func SetupBookingRoutes(router *gin.RouterGroup) {
+	// Visitor only routes
+	router.POST("/bookings", middleware.AuthMiddleware(), middleware.RequireRole("visitor"), booking.Create)
+	router.GET("/bookings/my-bookings", middleware.AuthMiddleware(), middleware.RequireRole("visitor"), booking.GetMyBookings)
+
+	// Trip owner only routes
+	router.GET("/bookings/my-trips", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), booking.GetTripBookings)
+
+	// Visitor who booked or owner of the trip
+	router.GET("/bookings/:id", middleware.AuthMiddleware(), booking.GetByID)
+	router.GET("/bookings/:id/cancellation", middleware.AuthMiddleware(), booking.CancellationQuote)
+	router.POST("/bookings/:id/cancel", middleware.AuthMiddleware(), booking.Cancel)
+	router.GET("/bookings/:id/refund-decisions", middleware.AuthMiddleware(), booking.GetRefundDecisions)
+
+	// Trip owner only routes
+	router.PUT("/bookings/:id/status", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), booking.UpdateStatus)
+}
@@ -0,0 +1,19 @@
+package calendar
+
+import (
+	"backend-go/controllers/calendar"
+	"backend-go/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupCalendarRoutes sets up iCalendar feed and download routes
+func SetupCalendarRoutes(router *gin.RouterGroup) {
+	// Public route, the secret token authenticates calendar apps
+	router.GET("/calendar/feeds/:token", calendar.Feed)
+
+	// Protected routes
+	router.GET("/calendar/feed", middleware.AuthMiddleware(), calendar.GetFeedURL)
+	router.POST("/calendar/feed/rotate", middleware.AuthMiddleware(), calendar.RotateFeedURL)
+	router.GET("/bookings/:id/ics", middleware.AuthMiddleware(), calendar.BookingICS)
+}
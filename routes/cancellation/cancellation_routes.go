@@ -0,0 +1,20 @@
+package cancellation
+
+import (
+	"backend-go/controllers/cancellation"
+	"backend-go/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupCancellationRoutes sets up cancellation policy routes
+func SetupCancellationRoutes(router *gin.RouterGroup) {
+	// Public route so visitors can read a trip's policy before booking
+	router.GET("/cancellation-policies/:id", cancellation.GetByID)
+
+	// Trip owner only routes
+	router.GET("/cancellation-policies", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), cancellation.GetMine)
+	router.POST("/cancellation-policies", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), cancellation.Create)
+	router.PUT("/cancellation-policies/:id", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), cancellation.Update)
+	router.DELETE("/cancellation-policies/:id", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), cancellation.Delete)
+}
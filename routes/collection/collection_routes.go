@@ -0,0 +1,25 @@
+package collection
+
+import (
+	"backend-go/controllers/collection"
+	"backend-go/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupCollectionRoutes sets up trip collection and share link routes
+func SetupCollectionRoutes(router *gin.RouterGroup) {
+	// Public routes (public collections and share links)
+	router.GET("/collections/:id", middleware.OptionalAuth(), collection.GetByID)
+	router.GET("/shared/collections/:token", collection.GetShared)
+
+	// Collection owner routes
+	router.GET("/collections", middleware.AuthMiddleware(), collection.GetMine)
+	router.POST("/collections", middleware.AuthMiddleware(), collection.Create)
+	router.PUT("/collections/:id", middleware.AuthMiddleware(), collection.Update)
+	router.DELETE("/collections/:id", middleware.AuthMiddleware(), collection.Delete)
+	router.POST("/collections/:id/share-link", middleware.AuthMiddleware(), collection.RegenerateShareLink)
+	router.POST("/collections/:id/items", middleware.AuthMiddleware(), collection.AddItem)
+	router.PUT("/collections/:id/items/order", middleware.AuthMiddleware(), collection.Reorder)
+	router.DELETE("/collections/:id/items/:tripId", middleware.AuthMiddleware(), collection.RemoveItem)
+}
@@ -0,0 +1,21 @@
+package conversation
+
+import (
+	"backend-go/controllers/conversation"
+	"backend-go/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupConversationRoutes sets up messaging routes between visitors and trip owners
+func SetupConversationRoutes(router *gin.RouterGroup) {
+	// Protected routes, only the two participants can read a conversation
+	router.GET("/conversations", middleware.AuthMiddleware(), conversation.GetMine)
+	router.POST("/conversations", middleware.AuthMiddleware(), conversation.Start)
+	router.GET("/conversations/:id", middleware.AuthMiddleware(), conversation.GetByID)
+	router.POST("/conversations/:id/messages", middleware.AuthMiddleware(), conversation.Send)
+	router.POST("/conversations/:id/read", middleware.AuthMiddleware(), conversation.MarkRead)
+
+	// Trip owner routes
+	router.GET("/inbox", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), conversation.GetInbox)
+}
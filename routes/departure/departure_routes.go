@@ -0,0 +1,20 @@
+package departure
+
+import (
+	"backend-go/controllers/departure"
+	"backend-go/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupDepartureRoutes sets up departure and availability routes
+func SetupDepartureRoutes(router *gin.RouterGroup) {
+	// Public routes (anyone can see when a trip runs)
+	router.GET("/trips/:id/departures", departure.GetByTrip)
+	router.GET("/trips/:id/availability", departure.Availability)
+
+	// Trip owner only routes
+	router.POST("/trips/:id/departures", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), departure.Create)
+	router.PUT("/departures/:id", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), departure.Update)
+	router.DELETE("/departures/:id", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), departure.Delete)
+}
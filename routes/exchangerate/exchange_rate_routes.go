@@ -0,0 +1,18 @@
+package exchangerate
+
+import (
+	"backend-go/controllers/exchangerate"
+	"backend-go/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupExchangeRateRoutes sets up exchange rate listing and admin import routes
+func SetupExchangeRateRoutes(router *gin.RouterGroup) {
+	// Public route
+	router.GET("/exchange-rates", exchangerate.GetAll)
+
+	// Admin routes
+	router.PUT("/exchange-rates", middleware.AuthMiddleware(), middleware.RequireRole("admin"), exchangerate.Import)           // Import a rate table
+	router.PUT("/exchange-rates/:currency", middleware.AuthMiddleware(), middleware.RequireRole("admin"), exchangerate.Update) // Set one rate
+}
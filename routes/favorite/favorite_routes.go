@@ -0,0 +1,15 @@
+package favorite
+
+import (
+	"backend-go/controllers/favorite"
+	"backend-go/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupFavoriteRoutes sets up routes for saving trips
+func SetupFavoriteRoutes(router *gin.RouterGroup) {
+	router.GET("/favorites", middleware.AuthMiddleware(), favorite.GetMine)
+	router.POST("/trips/:id/favorite", middleware.AuthMiddleware(), favorite.Add)
+	router.DELETE("/trips/:id/favorite", middleware.AuthMiddleware(), favorite.Remove)
+}
@@ -17,6 +17,22 @@ func SetupImageRoutes(router *gin.RouterGroup) {
 	router.POST("/images/upload", middleware.AuthMiddleware(), image.Upload)
 	router.POST("/images/upload-cover", middleware.AuthMiddleware(), image.UploadCoverImage)
 	router.GET("/images/my-images", middleware.AuthMiddleware(), image.GetMyImages)
+	router.GET("/images/usage", middleware.AuthMiddleware(), image.GetUsage)
 	router.GET("/images/trip/:trip_id", middleware.OptionalAuth(), image.GetImagesByTrip)
+	router.PUT("/images/:id", middleware.AuthMiddleware(), image.UpdateImage)
+	router.PUT("/images/:id/trip", middleware.AuthMiddleware(), image.MoveImage)
 	router.DELETE("/images/:id", middleware.AuthMiddleware(), image.DeleteImage)
+
+	// Trip gallery management, trip owner only
+	router.PUT("/trips/:id/images/order", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), image.ReorderGallery)
+	router.PUT("/trips/:id/cover", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), image.SetCover)
+	router.GET("/trips/:id/images/duplicates", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), image.GetDuplicates)
+
+	// Resumable uploads (tus 1.0)
+	router.OPTIONS("/uploads", image.TusOptions)
+	router.POST("/uploads", middleware.AuthMiddleware(), image.CreateUpload)
+	router.HEAD("/uploads/:token", middleware.AuthMiddleware(), image.HeadUpload)
+	router.PATCH("/uploads/:token", middleware.AuthMiddleware(), image.PatchUpload)
+	router.GET("/uploads/:token", middleware.AuthMiddleware(), image.GetUpload)
+	router.DELETE("/uploads/:token", middleware.AuthMiddleware(), image.DeleteUpload)
 }
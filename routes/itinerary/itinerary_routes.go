@@ -0,0 +1,21 @@
+package itinerary
+
+import (
+	"backend-go/controllers/itinerary"
+	"backend-go/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupItineraryRoutes sets up the visitor trip planner routes
+func SetupItineraryRoutes(router *gin.RouterGroup) {
+	// Visitor only routes
+	router.GET("/itineraries", middleware.AuthMiddleware(), middleware.RequireRole("visitor"), itinerary.GetMine)
+	router.POST("/itineraries", middleware.AuthMiddleware(), middleware.RequireRole("visitor"), itinerary.Create)
+	router.GET("/itineraries/:id", middleware.AuthMiddleware(), middleware.RequireRole("visitor"), itinerary.GetByID)
+	router.PUT("/itineraries/:id", middleware.AuthMiddleware(), middleware.RequireRole("visitor"), itinerary.Update)
+	router.DELETE("/itineraries/:id", middleware.AuthMiddleware(), middleware.RequireRole("visitor"), itinerary.Delete)
+	router.GET("/itineraries/:id/ics", middleware.AuthMiddleware(), middleware.RequireRole("visitor"), itinerary.ExportICS)
+	router.POST("/itineraries/:id/items", middleware.AuthMiddleware(), middleware.RequireRole("visitor"), itinerary.AddItem)
+	router.DELETE("/itineraries/:id/items/:itemId", middleware.AuthMiddleware(), middleware.RequireRole("visitor"), itinerary.RemoveItem)
+}
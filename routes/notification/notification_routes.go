@@ -0,0 +1,23 @@
+package notification
+
+import (
+	"backend-go/controllers/notification"
+	"backend-go/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupNotificationRoutes sets up notification inbox, preference and push subscription routes
+func SetupNotificationRoutes(router *gin.RouterGroup) {
+	// Public routes
+	router.GET("/notifications/push-key", notification.GetPushKey)
+
+	// Protected routes
+	router.GET("/notifications", middleware.AuthMiddleware(), notification.GetMine)
+	router.POST("/notifications/read-all", middleware.AuthMiddleware(), notification.MarkAllRead)
+	router.POST("/notifications/:id/read", middleware.AuthMiddleware(), notification.MarkRead)
+	router.GET("/notifications/preferences", middleware.AuthMiddleware(), notification.GetPreferences)
+	router.PUT("/notifications/preferences", middleware.AuthMiddleware(), notification.UpdatePreferences)
+	router.POST("/notifications/push-subscriptions", middleware.AuthMiddleware(), notification.Subscribe)
+	router.DELETE("/notifications/push-subscriptions/:id", middleware.AuthMiddleware(), notification.Unsubscribe)
+}
@@ -0,0 +1,19 @@
+package payment
+
+import (
+	"backend-go/controllers/payment"
+	"backend-go/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupPaymentRoutes sets up payment routes
+func SetupPaymentRoutes(router *gin.RouterGroup) {
+	// Public route for gateway webhooks, authenticated by signature
+	router.POST("/payments/webhooks/:gateway", payment.Webhook)
+
+	// Protected routes with middleware chaining
+	router.POST("/bookings/:id/payments", middleware.AuthMiddleware(), middleware.RequireRole("visitor"), payment.Create)
+	router.GET("/bookings/:id/payments", middleware.AuthMiddleware(), payment.GetByBooking)
+	router.POST("/payments/:id/refunds", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), payment.Refund)
+}
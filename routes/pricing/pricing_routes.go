@@ -0,0 +1,18 @@
+package pricing
+
+import (
+	"backend-go/controllers/pricing"
+	"backend-go/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupPricingRoutes sets up pricing rule and quote routes
+func SetupPricingRoutes(router *gin.RouterGroup) {
+	// Public routes (anyone can see what a trip costs)
+	router.GET("/trips/:id/pricing-rules", pricing.GetRules)
+	router.POST("/trips/:id/quote", middleware.OptionalAuth(), pricing.Quote)
+
+	// Trip owner only routes
+	router.PUT("/trips/:id/pricing-rules", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), pricing.ReplaceRules)
+}
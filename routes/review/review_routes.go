@@ -0,0 +1,26 @@
+package review
+
+import (
+	"backend-go/controllers/review"
+	"backend-go/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupReviewRoutes sets up review, reply and helpful vote routes
+func SetupReviewRoutes(router *gin.RouterGroup) {
+	// Public route
+	router.GET("/trips/:id/reviews", review.GetByTrip)
+
+	// Visitor only routes
+	router.POST("/trips/:id/reviews", middleware.AuthMiddleware(), middleware.RequireRole("visitor"), review.Create)
+	router.PUT("/reviews/:id", middleware.AuthMiddleware(), middleware.RequireRole("visitor"), review.Update)
+	router.DELETE("/reviews/:id", middleware.AuthMiddleware(), middleware.RequireRole("visitor"), review.Delete)
+
+	// Trip owner only route
+	router.PUT("/reviews/:id/reply", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), review.Reply)
+
+	// Any logged in user can vote
+	router.POST("/reviews/:id/helpful", middleware.AuthMiddleware(), review.MarkHelpful)
+	router.DELETE("/reviews/:id/helpful", middleware.AuthMiddleware(), review.UnmarkHelpful)
+}
@@ -2,10 +2,24 @@ package routes
 
 import (
 	"backend-go/routes/auth"
+	"backend-go/routes/booking"
+	"backend-go/routes/calendar"
+	"backend-go/routes/cancellation"
+	"backend-go/routes/collection"
+	"backend-go/routes/conversation"
+	"backend-go/routes/departure"
+	"backend-go/routes/exchangerate"
+	"backend-go/routes/favorite"
 	"backend-go/routes/image"
+	"backend-go/routes/itinerary"
+	"backend-go/routes/notification"
+	"backend-go/routes/payment"
 	"backend-go/routes/preference"
+	"backend-go/routes/pricing"
+	"backend-go/routes/review"
 	"backend-go/routes/trip"
 	"backend-go/routes/user"
+	"backend-go/routes/voucher"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,6 +38,46 @@ func SetupRoutes(router *gin.Engine) {
 		// Trip routes (protected)
 		trip.SetupTripRoutes(v1)
 
+		// Departure and availability routes (public & protected)
+		departure.SetupDepartureRoutes(v1)
+
+		// Pricing rule and quote routes (public & protected)
+		pricing.SetupPricingRoutes(v1)
+
+		// Itinerary routes (protected)
+		itinerary.SetupItineraryRoutes(v1)
+
+		// Review routes (public & protected)
+		review.SetupReviewRoutes(v1)
+
+		// Favorite and collection routes (public & protected)
+		favorite.SetupFavoriteRoutes(v1)
+		collection.SetupCollectionRoutes(v1)
+
+		// Voucher routes (protected)
+		voucher.SetupVoucherRoutes(v1)
+
+		// Booking routes (protected)
+		booking.SetupBookingRoutes(v1)
+
+		// Calendar feed routes (public by token & protected)
+		calendar.SetupCalendarRoutes(v1)
+
+		// Conversation and inbox routes (protected)
+		conversation.SetupConversationRoutes(v1)
+
+		// Notification routes (protected, the push key is public)
+		notification.SetupNotificationRoutes(v1)
+
+		// Cancellation policy routes (public & protected)
+		cancellation.SetupCancellationRoutes(v1)
+
+		// Payment routes (protected, webhooks are public)
+		payment.SetupPaymentRoutes(v1)
+
+		// Exchange rate routes (public & admin)
+		exchangerate.SetupExchangeRateRoutes(v1)
+
 		// Preference routes (public & protected)
 		preference.SetupPreferenceRoutes(v1)
 
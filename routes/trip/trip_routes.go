@@ -13,13 +13,13 @@ func SetupTripRoutes(router *gin.RouterGroup) {
 	router.GET("/trips", middleware.OptionalAuth(), trip.GetAll)
 	router.GET("/trips/:id", middleware.OptionalAuth(), trip.GetByID)
 
+	// Personalized routes - require authentication
+	router.GET("/trips/recommended", middleware.AuthMiddleware(), trip.GetRecommended)
+
 	// Protected routes with middleware chaining
 	// Trip owner only routes - require authentication + trip_owner role
 	router.POST("/trips", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), trip.Create)
 	router.PUT("/trips/:id", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), trip.Update)
 	router.DELETE("/trips/:id", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), trip.Delete)
 	router.GET("/trips/my-trips", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), trip.GetMyTrips)
-
-	// ! Just for seeding, comment it out after using
-	router.POST("/trips/seed", middleware.AuthMiddleware(), middleware.RequireRole("trip_owner"), trip.SeedTrips)
 }
@@ -0,0 +1,21 @@
+package voucher
+
+import (
+	"backend-go/controllers/voucher"
+	"backend-go/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupVoucherRoutes sets up voucher management and validation routes
+func SetupVoucherRoutes(router *gin.RouterGroup) {
+	// Visitors check a code against a quote before booking
+	router.POST("/vouchers/validate", middleware.AuthMiddleware(), middleware.RequireRole("visitor"), voucher.Validate)
+
+	// Admins manage platform-wide codes, trip owners codes for their own trips
+	router.GET("/vouchers", middleware.AuthMiddleware(), middleware.RequireRole("admin", "trip_owner"), voucher.GetAll)
+	router.GET("/vouchers/:id", middleware.AuthMiddleware(), middleware.RequireRole("admin", "trip_owner"), voucher.GetByID)
+	router.POST("/vouchers", middleware.AuthMiddleware(), middleware.RequireRole("admin", "trip_owner"), voucher.Create)
+	router.PUT("/vouchers/:id", middleware.AuthMiddleware(), middleware.RequireRole("admin", "trip_owner"), voucher.Update)
+	router.DELETE("/vouchers/:id", middleware.AuthMiddleware(), middleware.RequireRole("admin", "trip_owner"), voucher.Delete)
+}
@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory keeps objects in memory. It is meant for tests and local experiments, nothing survives a restart.
+type Memory struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+type memoryObject struct {
+	data []byte
+	info Object
+}
+
+// NewMemory creates an empty in-memory backend
+func NewMemory() *Memory {
+	return &Memory{objects: make(map[string]memoryObject)}
+}
+
+func (m *Memory) Name() string {
+	return "memory"
+}
+
+func (m *Memory) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if contentType == "" {
+		contentType = contentTypeOf(key)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = memoryObject{
+		data: data,
+		info: Object{Key: key, Size: int64(len(data)), ContentType: contentType, ModTime: time.Now()},
+	}
+	return nil
+}
+
+func (m *Memory) Get(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	object, err := m.lookup(key)
+	if err != nil {
+		return nil, Object{}, err
+	}
+	return io.NopCloser(bytes.NewReader(object.data)), object.info, nil
+}
+
+func (m *Memory) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	object, err := m.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	data := object.data[min(offset, int64(len(object.data))):]
+	if length >= 0 && length < int64(len(data)) {
+		data = data[:length]
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *Memory) Stat(ctx context.Context, key string) (Object, error) {
+	object, err := m.lookup(key)
+	return object.info, err
+}
+
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+// SignedURL is not supported, objects are served through the API
+func (m *Memory) SignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return "", ErrNotSupported
+}
+
+func (m *Memory) List(ctx context.Context, prefix string, fn func(Object) error) error {
+	m.mu.RLock()
+	var found []Object
+	for key, object := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			found = append(found, object.info)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(found, func(i, j int) bool { return found[i].Key < found[j].Key })
+	for _, object := range found {
+		if err := fn(object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Memory) lookup(key string) (memoryObject, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return memoryObject{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	object, exists := m.objects[key]
+	if !exists {
+		return memoryObject{}, ErrNotFound
+	}
+	return object, nil
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for keys that hold no object
+	ErrNotFound = errors.New("object not found")
+
+	// ErrInvalidKey is returned for keys that are empty, absolute or climb out of the store
+	ErrInvalidKey = errors.New("invalid object key")
+
+	// ErrNotSupported is returned by backends that cannot sign URLs
+	ErrNotSupported = errors.New("not supported by this storage backend")
+)
+
+// Object describes a stored object
+type Object struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Storage is implemented by every place uploaded files can live. Keys are slash separated
+// paths such as images/image_1.jpg.
+type Storage interface {
+	Name() string
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, Object, error)
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) // length < 0 reads to the end
+	Stat(ctx context.Context, key string) (Object, error)
+	Delete(ctx context.Context, key string) error // Deleting a missing key is not an error
+	SignedURL(ctx context.Context, key string, expires time.Duration) (string, error)
+	List(ctx context.Context, prefix string, fn func(Object) error) error
+}
+
+var (
+	setupOnce sync.Once
+	active    Storage
+)
+
+// Default returns the backend named by STORAGE_BACKEND, local disk when unset
+func Default() Storage {
+	setupOnce.Do(func() {
+		name := os.Getenv("STORAGE_BACKEND")
+		if name == "" {
+			name = "local"
+		}
+		backend, err := New(name)
+		if err != nil {
+			log.Fatalf("Storage backend %q is not configured: %v", name, err)
+		}
+		if name == "memory" {
+			log.Println("Warning: using in-memory storage, uploads are lost when the server stops")
+		}
+		active = backend
+	})
+	return active
+}
+
+// New builds a backend from the environment: local (STORAGE_LOCAL_ROOT, default uploads),
+// s3 (S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY, S3_PATH_STYLE)
+// or memory
+func New(name string) (Storage, error) {
+	switch name {
+	case "local":
+		root := os.Getenv("STORAGE_LOCAL_ROOT")
+		if root == "" {
+			root = "uploads"
+		}
+		return NewLocal(root), nil
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PathStyle:       os.Getenv("S3_PATH_STYLE") == "true",
+		})
+	case "memory":
+		return NewMemory(), nil
+	}
+	return nil, fmt.Errorf("unknown storage backend %q, use local, s3 or memory", name)
+}
+
+// cleanKey validates a key and returns it in canonical form
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") || cleaned != key {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
+
+// contentTypeOf guesses the content type of a key from its extension
+func contentTypeOf(key string) string {
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
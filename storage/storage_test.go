@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// backends returns a fresh instance of every backend that needs no server
+func backends(t *testing.T) map[string]Storage {
+	return map[string]Storage{
+		"local":  NewLocal(t.TempDir()),
+		"memory": NewMemory(),
+	}
+}
+
+func put(t *testing.T, store Storage, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		if err := store.Put(context.Background(), key, strings.NewReader(key), int64(len(key)), ""); err != nil {
+			t.Fatalf("Put(%q): %v", key, err)
+		}
+	}
+}
+
+func list(t *testing.T, store Storage, prefix string) []string {
+	t.Helper()
+	keys := []string{}
+	err := store.List(context.Background(), prefix, func(object Object) error {
+		if object.Size != int64(len(object.Key)) {
+			t.Errorf("List(%q): %s has size %d, want %d", prefix, object.Key, object.Size, len(object.Key))
+		}
+		keys = append(keys, object.Key)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("List(%q): %v", prefix, err)
+	}
+	return keys
+}
+
+func TestList(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"", []string{"blobs/3f/3fa2.jpg", "images/a.jpg", "images/ab.jpg", "images/b.png", "imagesx/c.jpg", "tus/tok1/00", "tus/tok1/05", "tus/tok2/00"}},
+		{"images/", []string{"images/a.jpg", "images/ab.jpg", "images/b.png"}},
+		{"images", []string{"images/a.jpg", "images/ab.jpg", "images/b.png", "imagesx/c.jpg"}},
+		{"images/a", []string{"images/a.jpg", "images/ab.jpg"}},
+		{"tus/tok1/", []string{"tus/tok1/00", "tus/tok1/05"}},
+		{"blobs/3f/", []string{"blobs/3f/3fa2.jpg"}},
+		{"covers/", []string{}},
+		{"tus/tok3/", []string{}},
+	}
+
+	for name, store := range backends(t) {
+		put(t, store, "images/b.png", "images/a.jpg", "images/ab.jpg", "imagesx/c.jpg", "blobs/3f/3fa2.jpg", "tus/tok1/05", "tus/tok1/00", "tus/tok2/00")
+		for _, tt := range tests {
+			if got := list(t, store, tt.prefix); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: List(%q) = %v, want %v", name, tt.prefix, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestListStopsOnError(t *testing.T) {
+	stop := errors.New("stop")
+	for name, store := range backends(t) {
+		put(t, store, "images/a.jpg", "images/b.jpg")
+		calls := 0
+		err := store.List(context.Background(), "images/", func(Object) error {
+			calls++
+			return stop
+		})
+		if !errors.Is(err, stop) || calls != 1 {
+			t.Errorf("%s: List returned %v after %d calls, want %v after 1", name, err, calls, stop)
+		}
+	}
+}
+
+func TestLocalListSkipsTemporaryFiles(t *testing.T) {
+	root := t.TempDir()
+	store := NewLocal(root)
+	put(t, store, "images/a.jpg")
+	if err := os.WriteFile(filepath.Join(root, "images", ".upload-123"), []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := list(t, store, "images/"), []string{"images/a.jpg"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List = %v, want %v", got, want)
+	}
+}
+
+func TestLocalListEmptyRoot(t *testing.T) {
+	store := NewLocal(filepath.Join(t.TempDir(), "missing"))
+	if got := list(t, store, "images/"); len(got) != 0 {
+		t.Errorf("List = %v, want nothing", got)
+	}
+}
+
+func TestLocalListRejectsEscapingPrefix(t *testing.T) {
+	store := NewLocal(t.TempDir())
+	err := store.List(context.Background(), "../etc/", func(Object) error { return nil })
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("List(../etc/) = %v, want %v", err, ErrInvalidKey)
+	}
+}
+
+func TestGetPutDelete(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range backends(t) {
+		put(t, store, "images/a.jpg")
+
+		object, err := store.Stat(ctx, "images/a.jpg")
+		if err != nil || object.Size != int64(len("images/a.jpg")) || object.ContentType != "image/jpeg" {
+			t.Errorf("%s: Stat = %+v, %v", name, object, err)
+		}
+
+		if err := store.Delete(ctx, "images/a.jpg"); err != nil {
+			t.Errorf("%s: Delete: %v", name, err)
+		}
+		if err := store.Delete(ctx, "images/a.jpg"); err != nil {
+			t.Errorf("%s: Delete of a missing key: %v", name, err)
+		}
+		if _, err := store.Stat(ctx, "images/a.jpg"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: Stat after Delete = %v, want %v", name, err, ErrNotFound)
+		}
+		if err := store.Put(ctx, "../a.jpg", strings.NewReader("x"), 1, ""); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%s: Put(../a.jpg) = %v, want %v", name, err, ErrInvalidKey)
+		}
+	}
+}
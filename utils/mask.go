@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MaskedContact replaces contact details hidden by MaskContactDetails
+const MaskedContact = "[contact hidden]"
+
+var (
+	emailPattern = regexp.MustCompile(`(?i)[a-z0-9._%+\-]+\s*(@|\(at\)|\[at\])\s*[a-z0-9.\-]+\s*(\.|\(dot\)|\[dot\])\s*[a-z]{2,}`)
+
+	// Seven or more digits, optionally with a leading + and separated by spaces, dots, dashes or brackets
+	phonePattern = regexp.MustCompile(`\+?\(?\d[\d\s().\-]{5,}\d`)
+
+	// Dates such as 2026-11-01 match phonePattern but are safe to show
+	datePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+	// Amounts grouped in thousands, such as 1.500.000 or 1,500,000.00, are prices rather than phone numbers
+	amountPattern = regexp.MustCompile(`^[1-9]\d{0,2}((\.\d{3})+(,\d{1,2})?|(,\d{3})+(\.\d{1,2})?)$`)
+
+	// A currency in front of the digits, as in Rp 1500000 or IDR 250000, makes them an amount too
+	currencyPattern = regexp.MustCompile(`(?i)(^|[^a-z])(rp\.?|idr|usd|sgd|eur|aud|myr|\$|€|£)\s*$`)
+)
+
+// MaskContactDetails hides email addresses and phone numbers in text, it reports whether anything was hidden
+func MaskContactDetails(text string) (string, bool) {
+	masked := emailPattern.ReplaceAllString(text, MaskedContact)
+
+	var out strings.Builder
+	last := 0
+	for _, match := range phonePattern.FindAllStringIndex(masked, -1) {
+		number := masked[match[0]:match[1]]
+		if isPhoneNumber(number, masked[:match[0]]) {
+			out.WriteString(masked[last:match[0]])
+			out.WriteString(MaskedContact)
+			last = match[1]
+		}
+	}
+	out.WriteString(masked[last:])
+
+	masked = out.String()
+	return masked, masked != text
+}
+
+// isPhoneNumber tells whether a phonePattern match, preceded by before, is a phone number
+func isPhoneNumber(match, before string) bool {
+	digits := 0
+	for _, r := range match {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	if digits < 7 || datePattern.MatchString(match) {
+		return false
+	}
+	return !amountPattern.MatchString(match) && !currencyPattern.MatchString(before)
+}
@@ -0,0 +1,39 @@
+package utils
+
+import "testing"
+
+func TestMaskContactDetails(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		want   string
+		masked bool
+	}{
+		{"plain text", "See you at the harbour at 7", "See you at the harbour at 7", false},
+		{"email", "Write to budi@example.com please", "Write to " + MaskedContact + " please", true},
+		{"obfuscated email", "budi (at) example (dot) com", MaskedContact, true},
+		{"mobile number", "Call me on 081234567890", "Call me on " + MaskedContact, true},
+		{"international number", "WhatsApp +62 812-3456-7890 anytime", "WhatsApp " + MaskedContact + " anytime", true},
+		{"number with brackets", "Office (021) 555 1234", "Office " + MaskedContact, true},
+		{"number grouped with dots", "Call 0812.3456.7890", "Call " + MaskedContact, true},
+		{"short number", "Room 1234, pier 56", "Room 1234, pier 56", false},
+		{"date", "Departing 2026-11-01", "Departing 2026-11-01", false},
+		{"rupiah with dots", "It costs Rp 1.500.000 per person", "It costs Rp 1.500.000 per person", false},
+		{"rupiah without space", "Rp1.500.000", "Rp1.500.000", false},
+		{"amount with commas", "Total 1,500,000 for two", "Total 1,500,000 for two", false},
+		{"amount with decimals", "That is 12,500,000.00 in total", "That is 12,500,000.00 in total", false},
+		{"currency code", "IDR 2500000 deposit", "IDR 2500000 deposit", false},
+		{"dollar sign", "about $1250000", "about $1250000", false},
+		{"number after a word ending like a currency", "Sharp 081234567890", "Sharp " + MaskedContact, true},
+		{"amount and number", "Rp 1.500.000, call 081234567890", "Rp 1.500.000, call " + MaskedContact, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, masked := MaskContactDetails(tt.text)
+			if got != tt.want || masked != tt.masked {
+				t.Errorf("MaskContactDetails(%q) = %q, %v, want %q, %v", tt.text, got, masked, tt.want, tt.masked)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Page limits for paginated listings
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Page is the page and page size read from the page and per_page query parameters
+type Page struct {
+	Number int `json:"page"`
+	Size   int `json:"per_page"`
+}
+
+// GetPage reads the pagination parameters, falling back to the defaults for missing or invalid values
+func GetPage(c *gin.Context) Page {
+	page := Page{Number: 1, Size: DefaultPageSize}
+	if number, err := strconv.Atoi(c.Query("page")); err == nil && number > 0 {
+		page.Number = number
+	}
+	if size, err := strconv.Atoi(c.Query("per_page")); err == nil && size > 0 {
+		page.Size = min(size, MaxPageSize)
+	}
+	return page
+}
+
+// Apply limits a query to the page
+func (p Page) Apply(query *gorm.DB) *gorm.DB {
+	return query.Offset((p.Number - 1) * p.Size).Limit(p.Size)
+}
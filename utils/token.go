@@ -0,0 +1,15 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// RandomToken returns an unguessable hex token for links that work without logging in
+func RandomToken() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		panic(err) // crypto/rand only fails when the OS has no entropy source
+	}
+	return hex.EncodeToString(bytes)
+}